@@ -51,7 +51,16 @@ func main() {
 			Message: "Basic Commands:",
 			Commands: []*cobra.Command{
 				options.BuildGenerateCmd(),
+				options.BuildImportCmd(),
 				options.BuildApplyCmd(),
+				options.BuildDiffCmd(),
+				options.BuildDeleteCmd(),
+				options.BuildResetCmd(),
+				options.BuildWaitCmd(),
+				options.BuildKubeletCmd(),
+				options.BuildSnapshotCmd(),
+				options.BuildSimulateCmd(),
+				options.BuildAnonymizeCmd(),
 				options.VersionCommand(),
 			},
 		},
@@ -22,8 +22,13 @@ import (
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/kubectl/pkg/cmd"
 
+	"github.com/D0m021ng/scheduler-simulator/pkg/simctl/anonymize"
 	"github.com/D0m021ng/scheduler-simulator/pkg/simctl/apply"
 	"github.com/D0m021ng/scheduler-simulator/pkg/simctl/generate"
+	"github.com/D0m021ng/scheduler-simulator/pkg/simctl/importer"
+	"github.com/D0m021ng/scheduler-simulator/pkg/simctl/kubelet"
+	"github.com/D0m021ng/scheduler-simulator/pkg/simctl/simulate"
+	"github.com/D0m021ng/scheduler-simulator/pkg/simctl/snapshot"
 	"github.com/D0m021ng/scheduler-simulator/pkg/version"
 )
 
@@ -70,10 +75,88 @@ func BuildGenerateCmd() *cobra.Command {
 	return generateCmd
 }
 
+func BuildImportCmd() *cobra.Command {
+	importCmd := &cobra.Command{
+		Use:   "import",
+		Short: "Import jobs of HPC workload traces as test data",
+	}
+
+	importSWFCmd := &cobra.Command{
+		Use:   "swf -f FILENAME",
+		Short: "Import jobs of a Standard Workload Format trace",
+		Run: func(cmd *cobra.Command, args []string) {
+			checkError(cmd, importer.ImportSWF())
+		},
+	}
+	importer.InitImportFlags(importSWFCmd)
+	importer.InitImportSWFFlags(importSWFCmd)
+	importCmd.AddCommand(importSWFCmd)
+
+	importSacctCmd := &cobra.Command{
+		Use:   "sacct -f FILENAME",
+		Short: "Import jobs of slurm accounting logs, as printed by sacct -P",
+		Run: func(cmd *cobra.Command, args []string) {
+			checkError(cmd, importer.ImportSacct())
+		},
+	}
+	importer.InitImportFlags(importSacctCmd)
+	importCmd.AddCommand(importSacctCmd)
+
+	return importCmd
+}
+
 func BuildApplyCmd() *cobra.Command {
 	return apply.NewCmdApply(genericclioptions.IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr})
 }
 
+func BuildDiffCmd() *cobra.Command {
+	return apply.NewCmdDiff(genericclioptions.IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr})
+}
+
+func BuildDeleteCmd() *cobra.Command {
+	return apply.NewCmdDelete(genericclioptions.IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr})
+}
+
+func BuildResetCmd() *cobra.Command {
+	return apply.NewCmdReset(genericclioptions.IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr})
+}
+
+func BuildWaitCmd() *cobra.Command {
+	return apply.NewCmdWait(genericclioptions.IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr})
+}
+
+func BuildKubeletCmd() *cobra.Command {
+	return kubelet.NewCmdKubelet(genericclioptions.IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr})
+}
+
+func BuildSnapshotCmd() *cobra.Command {
+	return snapshot.NewCmdSnapshot(genericclioptions.IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr})
+}
+
+func BuildSimulateCmd() *cobra.Command {
+	simulateCmd := &cobra.Command{
+		Use:   "simulate --nodes FILENAME --pods FILENAME",
+		Short: "Simulate scheduling of test data without a cluster",
+		Run: func(cmd *cobra.Command, args []string) {
+			checkError(cmd, simulate.Simulate())
+		},
+	}
+	simulate.InitSimulateFlags(simulateCmd)
+	return simulateCmd
+}
+
+func BuildAnonymizeCmd() *cobra.Command {
+	anonymizeCmd := &cobra.Command{
+		Use:   "anonymize -f FILENAME",
+		Short: "Anonymize the identifiers of test data, snapshots and traces",
+		Run: func(cmd *cobra.Command, args []string) {
+			checkError(cmd, anonymize.AnonymizeFiles())
+		},
+	}
+	anonymize.InitAnonymizeFlags(anonymizeCmd)
+	return anonymizeCmd
+}
+
 func BuildKubectlCmd() *cobra.Command {
 	return cmd.NewDefaultKubectlCommand()
 }
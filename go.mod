@@ -3,43 +3,69 @@ module github.com/D0m021ng/scheduler-simulator
 go 1.19
 
 require (
+	github.com/evanphx/json-patch v4.12.0+incompatible
 	github.com/google/uuid v1.3.0
+	github.com/jonboulle/clockwork v0.2.2
 	github.com/spf13/cobra v1.6.1
 	github.com/spf13/pflag v1.0.5
 	k8s.io/api v0.26.1
 	k8s.io/apimachinery v0.26.1
 	k8s.io/cli-runtime v0.26.1
 	k8s.io/client-go v0.26.1
+	k8s.io/component-helpers v0.26.1
 	k8s.io/klog/v2 v2.80.1
 	k8s.io/kubectl v0.26.1
+	k8s.io/kubernetes v1.20.1
 	k8s.io/utils v0.0.0-20221107191617-1a15be271d1d
 	sigs.k8s.io/yaml v1.3.0
 )
 
 replace (
 	github.com/chai2010/gettext-go => github.com/chai2010/gettext-go v0.1.0
+	google.golang.org/grpc => google.golang.org/grpc v1.27.1
 	k8s.io/api => k8s.io/api v0.20.1
+	k8s.io/apiextensions-apiserver => k8s.io/apiextensions-apiserver v0.20.1
 	k8s.io/apimachinery => k8s.io/apimachinery v0.20.1
+	k8s.io/apiserver => k8s.io/apiserver v0.20.1
 	k8s.io/cli-runtime => k8s.io/cli-runtime v0.20.1
 	k8s.io/client-go => k8s.io/client-go v0.20.1
+	k8s.io/cloud-provider => k8s.io/cloud-provider v0.20.1
+	k8s.io/cluster-bootstrap => k8s.io/cluster-bootstrap v0.20.1
 	k8s.io/code-generator => k8s.io/code-generator v0.20.1
 	k8s.io/component-base => k8s.io/component-base v0.20.1
 	k8s.io/component-helpers => k8s.io/component-helpers v0.20.1
+	k8s.io/controller-manager => k8s.io/controller-manager v0.20.1
+	k8s.io/cri-api => k8s.io/cri-api v0.20.1
+	k8s.io/csi-translation-lib => k8s.io/csi-translation-lib v0.20.1
+	k8s.io/kube-aggregator => k8s.io/kube-aggregator v0.20.1
+	k8s.io/kube-controller-manager => k8s.io/kube-controller-manager v0.20.1
 	k8s.io/kube-openapi => k8s.io/kube-openapi v0.0.0-20201113171705-d219536bb9fd
+	k8s.io/kube-proxy => k8s.io/kube-proxy v0.20.1
+	k8s.io/kube-scheduler => k8s.io/kube-scheduler v0.20.1
 	k8s.io/kubectl => k8s.io/kubectl v0.20.1
+	k8s.io/kubelet => k8s.io/kubelet v0.20.1
+	k8s.io/kubernetes => k8s.io/kubernetes v1.20.1
+	k8s.io/legacy-cloud-providers => k8s.io/legacy-cloud-providers v0.20.1
 	k8s.io/metrics => k8s.io/metrics v0.20.1
+	k8s.io/mount-utils => k8s.io/mount-utils v0.20.1
+	k8s.io/sample-apiserver => k8s.io/sample-apiserver v0.20.1
 )
 
 require (
 	github.com/Azure/go-ansiterm v0.0.0-20210617225240-d185dfc1b5a1 // indirect
 	github.com/MakeNowJust/heredoc v1.0.0 // indirect
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/blang/semver v3.5.1+incompatible // indirect
+	github.com/cespare/xxhash/v2 v2.1.1 // indirect
 	github.com/chai2010/gettext-go v1.0.2 // indirect
+	github.com/coreos/go-semver v0.3.0 // indirect
+	github.com/coreos/go-systemd v0.0.0-20190321100706-95778dfbb74e // indirect
+	github.com/coreos/pkg v0.0.0-20180928190104-399ea9e2e55f // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/daviddengcn/go-colortext v1.0.0 // indirect
 	github.com/docker/distribution v2.8.1+incompatible // indirect
 	github.com/docker/spdystream v0.0.0-20160310174837-449fdfce4d96 // indirect
 	github.com/emicklei/go-restful v2.9.5+incompatible // indirect
-	github.com/evanphx/json-patch v4.12.0+incompatible // indirect
 	github.com/exponent-io/jsonpath v0.0.0-20151013193312-d6023ce2651d // indirect
 	github.com/fatih/camelcase v1.0.0 // indirect
 	github.com/fvbommel/sortorder v1.0.1 // indirect
@@ -50,6 +76,7 @@ require (
 	github.com/go-openapi/spec v0.19.3 // indirect
 	github.com/go-openapi/swag v0.19.14 // indirect
 	github.com/gogo/protobuf v1.3.2 // indirect
+	github.com/golang/groupcache v0.0.0-20200121045136-8c9f03a8e57e // indirect
 	github.com/golang/protobuf v1.5.2 // indirect
 	github.com/google/btree v1.0.1 // indirect
 	github.com/google/go-cmp v0.5.9 // indirect
@@ -59,12 +86,12 @@ require (
 	github.com/hashicorp/golang-lru v0.5.1 // indirect
 	github.com/imdario/mergo v0.3.6 // indirect
 	github.com/inconshreveable/mousetrap v1.0.1 // indirect
-	github.com/jonboulle/clockwork v0.2.2 // indirect
 	github.com/josharian/intern v1.0.0 // indirect
 	github.com/json-iterator/go v1.1.12 // indirect
 	github.com/liggitt/tabwriter v0.0.0-20181228230101-89fcab3d43de // indirect
 	github.com/lithammer/dedent v1.1.0 // indirect
 	github.com/mailru/easyjson v0.7.6 // indirect
+	github.com/matttproud/golang_protobuf_extensions v1.0.2-0.20181231171920-c182affec369 // indirect
 	github.com/mitchellh/go-wordwrap v1.0.0 // indirect
 	github.com/moby/term v0.0.0-20220808134915-39b0c02b01ae // indirect
 	github.com/modern-go/concurrent v0.0.0-20180306012644-bacd9c7ef1dd // indirect
@@ -73,8 +100,16 @@ require (
 	github.com/opencontainers/go-digest v1.0.0 // indirect
 	github.com/peterbourgon/diskv v2.0.1+incompatible // indirect
 	github.com/pkg/errors v0.9.1 // indirect
+	github.com/prometheus/client_golang v1.7.1 // indirect
+	github.com/prometheus/client_model v0.2.0 // indirect
+	github.com/prometheus/common v0.10.0 // indirect
+	github.com/prometheus/procfs v0.2.0 // indirect
 	github.com/russross/blackfriday v1.5.2 // indirect
 	github.com/stretchr/testify v1.7.1 // indirect
+	go.etcd.io/etcd v0.5.0-alpha.5.0.20200910180754-dd1b699fc489 // indirect
+	go.uber.org/atomic v1.4.0 // indirect
+	go.uber.org/multierr v1.1.0 // indirect
+	go.uber.org/zap v1.10.0 // indirect
 	golang.org/x/crypto v0.0.0-20201002170205-7f63de1d35b0 // indirect
 	golang.org/x/net v0.3.1-0.20221206200815-1e63c2f08a10 // indirect
 	golang.org/x/oauth2 v0.0.0-20220223155221-ee480838109b // indirect
@@ -82,13 +117,19 @@ require (
 	golang.org/x/text v0.5.0 // indirect
 	golang.org/x/time v0.0.0-20220210224613-90d013bbcef8 // indirect
 	google.golang.org/appengine v1.6.7 // indirect
+	google.golang.org/genproto v0.0.0-20201110150050-8816d57aaa9a // indirect
+	google.golang.org/grpc v1.31.0 // indirect
 	google.golang.org/protobuf v1.28.1 // indirect
 	gopkg.in/inf.v0 v0.9.1 // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
+	k8s.io/apiserver v0.20.1 // indirect
+	k8s.io/cloud-provider v0.20.1 // indirect
 	k8s.io/component-base v0.26.1 // indirect
-	k8s.io/component-helpers v0.26.1 // indirect
+	k8s.io/csi-translation-lib v0.20.1 // indirect
 	k8s.io/kube-openapi v0.0.0-20221012153701-172d655c2280 // indirect
+	k8s.io/kube-scheduler v0.0.0 // indirect
 	k8s.io/metrics v0.26.1 // indirect
+	k8s.io/mount-utils v0.0.0 // indirect
 	sigs.k8s.io/kustomize v2.0.3+incompatible // indirect
 	sigs.k8s.io/structured-merge-diff/v4 v4.2.3 // indirect
 )
@@ -0,0 +1,348 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package anonymize
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+const (
+	anonymousPrefix = "anon-"
+	hashLength      = 12
+)
+
+// Anonymizer replaces the identifiers of objects by hashes. The same identifier is always replaced
+// by the same hash, wherever it appears, so the references between objects, such as the node name
+// of a pod and the hostname label of the node, and the cardinality of labels are preserved.
+// Resource quantities are never changed.
+type Anonymizer struct {
+	rules *Rules
+	keep  sets.String
+}
+
+func NewAnonymizer(rules *Rules) *Anonymizer {
+	if rules == nil {
+		rules = DefaultRules()
+	}
+	return &Anonymizer{
+		rules: rules,
+		keep:  sets.NewString(rules.Keep...),
+	}
+}
+
+// Anonymize replaces the identifiers, labels and annotations of obj in place.
+func (a *Anonymizer) Anonymize(obj *unstructured.Unstructured) {
+	a.anonymizeMeta(obj.Object)
+
+	switch obj.GetKind() {
+	case "Node":
+		a.anonymizeNode(obj.Object)
+	case "Pod":
+		if spec, ok := obj.Object["spec"].(map[string]interface{}); ok {
+			a.anonymizePodSpec(spec)
+		}
+		a.anonymizePodStatus(obj.Object)
+	case "PodGroup":
+		a.replaceString(obj.Object, "spec", "queue")
+		a.replaceString(obj.Object, "spec", "priorityClassName")
+	case "Queue":
+		a.replaceString(obj.Object, "spec", "parent")
+	}
+}
+
+// Value returns the anonymous name of an identifier or label value.
+func (a *Anonymizer) Value(value string) string {
+	if value == "" || a.keep.Has(value) {
+		return value
+	}
+	if renamed, ok := a.rules.Rename[value]; ok {
+		return renamed
+	}
+	sum := sha256.Sum256([]byte(a.rules.Salt + "\x00" + value))
+	return anonymousPrefix + hex.EncodeToString(sum[:])[:hashLength]
+}
+
+// Key returns the anonymous key of a label or annotation, and false if it is denied.
+func (a *Anonymizer) Key(key string) (string, bool) {
+	if matchKey(a.rules.Deny, key) {
+		return "", false
+	}
+	if !a.rules.HashKeys || matchKey(a.rules.Allow, key) {
+		return key, true
+	}
+	return a.Value(key), true
+}
+
+// Label returns the anonymous key and value of a label or annotation, and false if it is denied.
+func (a *Anonymizer) Label(key, value string) (string, string, bool) {
+	newKey, ok := a.Key(key)
+	if !ok {
+		return "", "", false
+	}
+	if matchKey(a.rules.Allow, key) {
+		return newKey, value, true
+	}
+	return newKey, a.Value(value), true
+}
+
+func (a *Anonymizer) anonymizeMeta(obj map[string]interface{}) {
+	a.replaceString(obj, "metadata", "name")
+	a.replaceString(obj, "metadata", "namespace")
+	if generateName, found, _ := unstructured.NestedString(obj, "metadata", "generateName"); found {
+		_ = unstructured.SetNestedField(obj, a.Value(strings.TrimSuffix(generateName, "-"))+"-", "metadata", "generateName")
+	}
+	a.replaceLabels(obj, "metadata", "labels")
+	a.replaceLabels(obj, "metadata", "annotations")
+}
+
+func (a *Anonymizer) anonymizeNode(obj map[string]interface{}) {
+	a.replaceString(obj, "spec", "providerID")
+	a.replaceString(obj, "spec", "externalID")
+	if taints, found, _ := unstructured.NestedSlice(obj, "spec", "taints"); found {
+		for _, taint := range taints {
+			a.replaceKeyValue(taint.(map[string]interface{}))
+		}
+		_ = unstructured.SetNestedSlice(obj, taints, "spec", "taints")
+	}
+
+	// addresses are hostnames and ips, which are meaningless for scheduling
+	unstructured.RemoveNestedField(obj, "status", "addresses")
+	unstructured.RemoveNestedField(obj, "status", "images")
+	unstructured.RemoveNestedField(obj, "status", "volumesInUse")
+	unstructured.RemoveNestedField(obj, "status", "volumesAttached")
+	for _, field := range []string{"machineID", "systemUUID", "bootID"} {
+		a.replaceString(obj, "status", "nodeInfo", field)
+	}
+}
+
+func (a *Anonymizer) anonymizePodSpec(spec map[string]interface{}) {
+	for _, field := range []string{"nodeName", "hostname", "subdomain", "serviceAccountName", "serviceAccount", "priorityClassName"} {
+		a.replaceString(spec, field)
+	}
+	// volumes and pull secrets refer to objects which are not exported
+	delete(spec, "volumes")
+	delete(spec, "imagePullSecrets")
+
+	a.replaceLabels(spec, "nodeSelector")
+	for _, field := range []string{"initContainers", "containers"} {
+		containers, found, _ := unstructured.NestedSlice(spec, field)
+		if !found {
+			continue
+		}
+		for i, c := range containers {
+			containers[i] = a.anonymizeContainer(c.(map[string]interface{}))
+		}
+		_ = unstructured.SetNestedSlice(spec, containers, field)
+	}
+	if tolerations, found, _ := unstructured.NestedSlice(spec, "tolerations"); found {
+		for _, toleration := range tolerations {
+			a.replaceKeyValue(toleration.(map[string]interface{}))
+		}
+		_ = unstructured.SetNestedSlice(spec, tolerations, "tolerations")
+	}
+	if affinity, ok := spec["affinity"].(map[string]interface{}); ok {
+		a.anonymizeAffinity(affinity)
+	}
+	if constraints, found, _ := unstructured.NestedSlice(spec, "topologySpreadConstraints"); found {
+		for _, c := range constraints {
+			constraint := c.(map[string]interface{})
+			a.replaceTopologyKey(constraint)
+			a.anonymizeLabelSelector(constraint, "labelSelector")
+		}
+		_ = unstructured.SetNestedSlice(spec, constraints, "topologySpreadConstraints")
+	}
+}
+
+// anonymizeContainer keeps only the fields of a container which matter for scheduling,
+// command lines and environments easily leak secrets.
+func (a *Anonymizer) anonymizeContainer(container map[string]interface{}) map[string]interface{} {
+	result := map[string]interface{}{}
+	if name, ok := container["name"].(string); ok {
+		result["name"] = a.Value(name)
+	}
+	if image, ok := container["image"].(string); ok {
+		result["image"] = a.Value(image)
+	}
+	for _, field := range []string{"resources", "ports"} {
+		if value, ok := container[field]; ok {
+			result[field] = value
+		}
+	}
+	return result
+}
+
+func (a *Anonymizer) anonymizePodStatus(obj map[string]interface{}) {
+	a.replaceString(obj, "status", "nominatedNodeName")
+	for _, field := range []string{"hostIP", "podIP", "podIPs", "containerStatuses", "initContainerStatuses", "message"} {
+		unstructured.RemoveNestedField(obj, "status", field)
+	}
+}
+
+func (a *Anonymizer) anonymizeAffinity(affinity map[string]interface{}) {
+	if nodeAffinity, ok := affinity["nodeAffinity"].(map[string]interface{}); ok {
+		if terms, found, _ := unstructured.NestedSlice(nodeAffinity, "requiredDuringSchedulingIgnoredDuringExecution", "nodeSelectorTerms"); found {
+			for _, term := range terms {
+				a.anonymizeNodeSelectorTerm(term.(map[string]interface{}))
+			}
+			_ = unstructured.SetNestedSlice(nodeAffinity, terms, "requiredDuringSchedulingIgnoredDuringExecution", "nodeSelectorTerms")
+		}
+		if terms, found, _ := unstructured.NestedSlice(nodeAffinity, "preferredDuringSchedulingIgnoredDuringExecution"); found {
+			for _, term := range terms {
+				if preference, ok := term.(map[string]interface{})["preference"].(map[string]interface{}); ok {
+					a.anonymizeNodeSelectorTerm(preference)
+				}
+			}
+			_ = unstructured.SetNestedSlice(nodeAffinity, terms, "preferredDuringSchedulingIgnoredDuringExecution")
+		}
+	}
+
+	for _, field := range []string{"podAffinity", "podAntiAffinity"} {
+		podAffinity, ok := affinity[field].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if terms, found, _ := unstructured.NestedSlice(podAffinity, "requiredDuringSchedulingIgnoredDuringExecution"); found {
+			for _, term := range terms {
+				a.anonymizePodAffinityTerm(term.(map[string]interface{}))
+			}
+			_ = unstructured.SetNestedSlice(podAffinity, terms, "requiredDuringSchedulingIgnoredDuringExecution")
+		}
+		if terms, found, _ := unstructured.NestedSlice(podAffinity, "preferredDuringSchedulingIgnoredDuringExecution"); found {
+			for _, term := range terms {
+				if podAffinityTerm, ok := term.(map[string]interface{})["podAffinityTerm"].(map[string]interface{}); ok {
+					a.anonymizePodAffinityTerm(podAffinityTerm)
+				}
+			}
+			_ = unstructured.SetNestedSlice(podAffinity, terms, "preferredDuringSchedulingIgnoredDuringExecution")
+		}
+	}
+}
+
+func (a *Anonymizer) anonymizeNodeSelectorTerm(term map[string]interface{}) {
+	a.replaceExpressions(term, "matchExpressions")
+	// the only supported field is metadata.name, whose values are node names
+	if fields, found, _ := unstructured.NestedSlice(term, "matchFields"); found {
+		for _, f := range fields {
+			field := f.(map[string]interface{})
+			if values, found, _ := unstructured.NestedStringSlice(field, "values"); found {
+				for i := range values {
+					values[i] = a.Value(values[i])
+				}
+				_ = unstructured.SetNestedStringSlice(field, values, "values")
+			}
+		}
+		_ = unstructured.SetNestedSlice(term, fields, "matchFields")
+	}
+}
+
+func (a *Anonymizer) anonymizePodAffinityTerm(term map[string]interface{}) {
+	a.anonymizeLabelSelector(term, "labelSelector")
+	a.replaceTopologyKey(term)
+	if namespaces, found, _ := unstructured.NestedStringSlice(term, "namespaces"); found {
+		for i := range namespaces {
+			namespaces[i] = a.Value(namespaces[i])
+		}
+		_ = unstructured.SetNestedStringSlice(term, namespaces, "namespaces")
+	}
+}
+
+func (a *Anonymizer) anonymizeLabelSelector(obj map[string]interface{}, field string) {
+	selector, ok := obj[field].(map[string]interface{})
+	if !ok {
+		return
+	}
+	a.replaceLabels(selector, "matchLabels")
+	a.replaceExpressions(selector, "matchExpressions")
+}
+
+// replaceExpressions anonymizes the keys and values of label selector requirements,
+// the same way as the labels they select.
+func (a *Anonymizer) replaceExpressions(obj map[string]interface{}, field string) {
+	expressions, found, _ := unstructured.NestedSlice(obj, field)
+	if !found {
+		return
+	}
+	for _, e := range expressions {
+		expression := e.(map[string]interface{})
+		key, _ := expression["key"].(string)
+		if values, found, _ := unstructured.NestedStringSlice(expression, "values"); found {
+			for i := range values {
+				_, values[i], _ = a.Label(key, values[i])
+			}
+			_ = unstructured.SetNestedStringSlice(expression, values, "values")
+		}
+		if newKey, ok := a.Key(key); ok {
+			expression["key"] = newKey
+		}
+	}
+	_ = unstructured.SetNestedSlice(obj, expressions, field)
+}
+
+// replaceKeyValue anonymizes taints and tolerations, which match labels by key and value.
+func (a *Anonymizer) replaceKeyValue(obj map[string]interface{}) {
+	key, _ := obj["key"].(string)
+	if key == "" {
+		return
+	}
+	value, _ := obj["value"].(string)
+	newKey, newValue, ok := a.Label(key, value)
+	if !ok {
+		// denied keys are only removed from labels, matching them must keep working
+		return
+	}
+	obj["key"] = newKey
+	if value != "" {
+		obj["value"] = newValue
+	}
+}
+
+func (a *Anonymizer) replaceTopologyKey(obj map[string]interface{}) {
+	if key, ok := obj["topologyKey"].(string); ok {
+		if newKey, ok := a.Key(key); ok {
+			obj["topologyKey"] = newKey
+		}
+	}
+}
+
+func (a *Anonymizer) replaceString(obj map[string]interface{}, fields ...string) {
+	value, found, err := unstructured.NestedString(obj, fields...)
+	if !found || err != nil {
+		return
+	}
+	_ = unstructured.SetNestedField(obj, a.Value(value), fields...)
+}
+
+func (a *Anonymizer) replaceLabels(obj map[string]interface{}, fields ...string) {
+	labels, found, err := unstructured.NestedStringMap(obj, fields...)
+	if !found || err != nil {
+		return
+	}
+	result := make(map[string]string, len(labels))
+	for key, value := range labels {
+		if newKey, newValue, ok := a.Label(key, value); ok {
+			result[newKey] = newValue
+		}
+	}
+	if len(result) == 0 {
+		unstructured.RemoveNestedField(obj, fields...)
+		return
+	}
+	_ = unstructured.SetNestedStringMap(obj, result, fields...)
+}
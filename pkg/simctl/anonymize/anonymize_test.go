@@ -0,0 +1,79 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package anonymize
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/D0m021ng/scheduler-simulator/pkg/simctl/generate"
+)
+
+func toUnstructured(t *testing.T, obj runtime.Object) *unstructured.Unstructured {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		t.Fatalf("convert %T failed: %v", obj, err)
+	}
+	return &unstructured.Unstructured{Object: content}
+}
+
+func TestAnonymize(t *testing.T) {
+	res := generate.BuildResources(map[string]string{"cpu": "4", "memory": "8Gi"})
+	node := generate.BuildFakeNode("instance-0001", false, res, res, nil, map[string]string{
+		"kubernetes.io/hostname": "instance-0001",
+		"kubernetes.io/os":       "linux",
+		"team":                   "payments",
+	})
+	pod := generate.BuildFakePod("billing-api", "payments", "volcano", "payments", map[string]string{"team": "payments"},
+		v1.PodRunning, res)
+	pod.Spec.NodeName = "instance-0001"
+	pod.Annotations["kubectl.kubernetes.io/last-applied-configuration"] = "{}"
+
+	rules := DefaultRules()
+	rules.Salt = "test"
+	anonymizer := NewAnonymizer(rules)
+	anonNode, anonPod := toUnstructured(t, node), toUnstructured(t, pod)
+	anonymizer.Anonymize(anonNode)
+	anonymizer.Anonymize(anonPod)
+
+	if anonNode.GetName() == node.Name || anonNode.GetLabels()["kubernetes.io/hostname"] != anonNode.GetName() {
+		t.Errorf("expected hostname label to match anonymous node name, got %s %v", anonNode.GetName(), anonNode.GetLabels())
+	}
+	if anonNode.GetLabels()["kubernetes.io/os"] != "linux" {
+		t.Errorf("expected allowed label to be kept, got %v", anonNode.GetLabels())
+	}
+	if nodeName, _, _ := unstructured.NestedString(anonPod.Object, "spec", "nodeName"); nodeName != anonNode.GetName() {
+		t.Errorf("expected pod bound to %s, got %s", anonNode.GetName(), nodeName)
+	}
+	if anonPod.GetNamespace() != anonPod.GetLabels()["team"] || anonPod.GetNamespace() == "payments" {
+		t.Errorf("expected same values to be hashed the same way, got %s %v", anonPod.GetNamespace(), anonPod.GetLabels())
+	}
+	if _, ok := anonPod.GetAnnotations()["kubectl.kubernetes.io/last-applied-configuration"]; ok {
+		t.Errorf("expected denied annotation to be removed")
+	}
+	containers, _, _ := unstructured.NestedSlice(anonPod.Object, "spec", "containers")
+	cpu, _, _ := unstructured.NestedString(containers[0].(map[string]interface{}), "resources", "requests", "cpu")
+	if cpu != "4" {
+		t.Errorf("expected resource requests to be kept, got %v", containers[0])
+	}
+
+	rules.Salt = "other"
+	if NewAnonymizer(rules).Value("payments") == anonPod.GetNamespace() {
+		t.Errorf("expected hashes to depend on salt")
+	}
+}
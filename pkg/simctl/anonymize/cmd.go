@@ -0,0 +1,65 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package anonymize
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/D0m021ng/scheduler-simulator/pkg/simctl/generate"
+)
+
+type anonymizeFlags struct {
+	Filenames []string
+	Output    string
+	RulesFile string
+}
+
+var anonFlags = &anonymizeFlags{}
+
+// InitAnonymizeFlags is used to init all flags during anonymize data.
+func InitAnonymizeFlags(cmd *cobra.Command) {
+
+	cmd.Flags().StringSliceVarP(&anonFlags.Filenames, "filename", "f", nil, "the files of objects to anonymize")
+	cmd.Flags().StringVarP(&anonFlags.Output, "output", "o", "anonymized.yaml", "the name of anonymized data file")
+	cmd.Flags().StringVarP(&anonFlags.RulesFile, "rules", "", "", "the yaml file of allow/deny rules, the default rules are used if empty")
+}
+
+// AnonymizeFiles anonymizes all objects of the given files into a single output file.
+func AnonymizeFiles() error {
+	if len(anonFlags.Filenames) == 0 {
+		return fmt.Errorf("must specify one of -f")
+	}
+	rules, err := LoadRules(anonFlags.RulesFile)
+	if err != nil {
+		return err
+	}
+	anonymizer := NewAnonymizer(rules)
+
+	var objs []interface{}
+	for _, filename := range anonFlags.Filenames {
+		fileObjs, err := generate.ReadYamlFile(filename)
+		if err != nil {
+			return err
+		}
+		for _, obj := range fileObjs {
+			anonymizer.Anonymize(obj)
+			objs = append(objs, obj)
+		}
+	}
+	fmt.Printf("Anonymize %d object(s) to %s\n", len(objs), anonFlags.Output)
+	return generate.WriteYamlFile(anonFlags.Output, objs)
+}
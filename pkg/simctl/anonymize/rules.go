@@ -0,0 +1,113 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package anonymize
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Rules decide which identifiers, labels and annotations are kept as is. For example,
+//
+//	salt: 5b0c7e
+//	allow: ["kubernetes.io/os", "node-role.kubernetes.io/*"]
+//	deny: ["kubectl.kubernetes.io/*"]
+//	keep: ["default", "volcano"]
+//	rename: {"team-a": "tenant-1"}
+//
+// Keys of allow and deny ending with "*" match all keys with that prefix.
+type Rules struct {
+	// Salt is mixed into every hash, so that names can not be recovered by hashing guesses.
+	Salt string `json:"salt,omitempty"`
+	// Allow are the keys of labels and annotations whose values are kept.
+	Allow []string `json:"allow,omitempty"`
+	// Deny are the keys of labels and annotations which are removed.
+	Deny []string `json:"deny,omitempty"`
+	// HashKeys hashes the keys of labels and annotations which are not allowed, besides their values.
+	HashKeys bool `json:"hashKeys,omitempty"`
+	// Keep are the names and values which are never hashed, such as well-known namespaces.
+	Keep []string `json:"keep,omitempty"`
+	// Rename maps names and values to the given ones instead of hashing them.
+	Rename map[string]string `json:"rename,omitempty"`
+}
+
+// DefaultRules keeps the well-known labels and names which carry no information about the owner
+// of a cluster, and the annotations which scheduling depends on.
+func DefaultRules() *Rules {
+	return &Rules{
+		Allow: []string{
+			"kubernetes.io/arch",
+			"kubernetes.io/os",
+			"beta.kubernetes.io/arch",
+			"beta.kubernetes.io/os",
+			"node.kubernetes.io/instance-type",
+			"beta.kubernetes.io/instance-type",
+			"node-role.kubernetes.io/*",
+			"node.kubernetes.io/*",
+			"scheduler-simulator",
+			"scheduler-simulator/*",
+		},
+		Deny: []string{
+			"kubectl.kubernetes.io/*",
+			"deployment.kubernetes.io/*",
+			"control-plane.alpha.kubernetes.io/*",
+			"volumes.kubernetes.io/*",
+			"node.alpha.kubernetes.io/*",
+			"csi.volume.kubernetes.io/*",
+		},
+		Keep: []string{
+			"default",
+			"kube-system",
+			"kube-public",
+			"kube-node-lease",
+			"volcano",
+			"default-scheduler",
+			"system-cluster-critical",
+			"system-node-critical",
+		},
+	}
+}
+
+// LoadRules reads rules from a yaml file, the default rules are used if path is empty.
+func LoadRules(path string) (*Rules, error) {
+	if path == "" {
+		return DefaultRules(), nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	rules := &Rules{}
+	if err := yaml.UnmarshalStrict(data, rules); err != nil {
+		return nil, fmt.Errorf("invalid anonymize rules %s: %v", path, err)
+	}
+	return rules, nil
+}
+
+func matchKey(patterns []string, key string) bool {
+	for _, pattern := range patterns {
+		if strings.HasSuffix(pattern, "*") {
+			if strings.HasPrefix(key, strings.TrimSuffix(pattern, "*")) {
+				return true
+			}
+		} else if pattern == key {
+			return true
+		}
+	}
+	return false
+}
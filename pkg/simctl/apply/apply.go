@@ -15,19 +15,45 @@ limitations under the License.
 package apply
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"time"
 
+	"github.com/jonboulle/clockwork"
 	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/klog/v2"
+	kubectlapply "k8s.io/kubectl/pkg/cmd/apply"
 	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util"
 	"k8s.io/kubectl/pkg/util/i18n"
 	"k8s.io/kubectl/pkg/validation"
 	utilpointer "k8s.io/utils/pointer"
+
+	"github.com/D0m021ng/scheduler-simulator/pkg/simctl/generate"
+)
+
+const (
+	// maxPatchRetry is the maximum number of conflicts retry for during a patch operation before returning failure
+	maxPatchRetry = 5
+
+	warningNoLastAppliedConfigAnnotation = "Warning: resource %[1]s is missing the %[2]s annotation which is required by simctl apply. " +
+		"The missing annotation will be patched automatically.\n"
+
+	defaultFieldManager = "simctl"
 )
 
 // ApplyFlags directly reflect the information that CLI is gathering via flags.
@@ -38,13 +64,46 @@ type ApplyFlags struct {
 
 	Overwrite bool
 
+	ServerSideApply bool
+	ForceConflicts  bool
+	FieldManager    string
+
+	ApplyStatus bool
+
+	Concurrency int
+	QPS         float32
+	Burst       int
+
+	Prune    bool
+	Selector string
+
+	Validate string
+
+	Output string
+
+	Retries      int
+	RetryBackoff time.Duration
+	Checkpoint   string
+	Resume       bool
+
+	Replay bool
+	Speed  float64
+
+	Wait    bool
+	Timeout time.Duration
+
+	Replicas int
+
 	genericclioptions.IOStreams
 }
 
 // ApplyOptions defines flags and other configuration parameters for the `apply` command
 type ApplyOptions struct {
-	Validator validation.Schema
-	Builder   *resource.Builder
+	// Validator checks the documents of files before they are applied,
+	// as directed by ValidationDirective.
+	Validator           validation.Schema
+	ValidationDirective string
+	Builder             *resource.Builder
 
 	Recorder        genericclioptions.Recorder
 	FilenameOptions resource.FilenameOptions
@@ -52,6 +111,66 @@ type ApplyOptions struct {
 	Namespace        string
 	EnforceNamespace bool
 
+	// Overwrite automatically resolves conflicts between the modified and live configuration
+	// by using values from the modified configuration.
+	Overwrite bool
+
+	// ServerSideApply sends apply patches, so that the api server merges the objects
+	// and tracks the ownership of their fields by FieldManager.
+	ServerSideApply bool
+	ForceConflicts  bool
+	FieldManager    string
+
+	// ApplyStatus writes the status of objects through the status subresource after they are applied,
+	// for the kinds which Discovery reports with one.
+	ApplyStatus bool
+	Discovery   discovery.DiscoveryInterface
+
+	// Concurrency is the count of workers which apply objects in parallel.
+	Concurrency int
+
+	DryRunStrategy cmdutil.DryRunStrategy
+
+	// Prune deletes the live objects which match Selector, but are missing in the files.
+	Prune         bool
+	Selector      string
+	Mapper        meta.RESTMapper
+	DynamicClient dynamic.Interface
+
+	// Output is the format of the results: "" prints the operation done to every object,
+	// name prints only the names, json and yaml print the applied objects as a list.
+	Output  string
+	results *applyResults
+
+	// Retries is the count of times a failed object is applied again, if the error is
+	// likely transient. The first retry waits RetryBackoff, which doubles for every next one.
+	Retries      int
+	RetryBackoff time.Duration
+
+	// Checkpoint is the file recording the applied objects, Resume skips
+	// the objects recorded by a previous run which did not complete.
+	Checkpoint string
+	Resume     bool
+	checkpoint *checkpoint
+
+	// Replay applies the objects with an arrival time on the schedule of the trace,
+	// Speed times faster than real time.
+	Replay bool
+	Speed  float64
+
+	// Wait waits for the applied pods to be bound or unschedulable within Timeout,
+	// and reports the outcome.
+	Wait    bool
+	Timeout time.Duration
+	Client  kubernetes.Interface
+
+	// Replicas is the count of objects created from every document which has only a
+	// generateName. ordinals tells these objects apart until their names are generated.
+	Replicas int
+	ordinals map[*resource.Info]int
+
+	genericclioptions.IOStreams
+
 	// Objects (and some denormalized data) which are to be
 	// applied. The standard way to fill in this structure
 	// is by calling "GetObjects()", which will use the
@@ -76,8 +195,19 @@ func NewApplyFlags(ioStreams genericclioptions.IOStreams) *ApplyFlags {
 			KubeConfig: utilpointer.String(""),
 			APIServer:  utilpointer.String(""),
 		},
-		Overwrite: true,
-		IOStreams: ioStreams,
+		Overwrite:    true,
+		FieldManager: defaultFieldManager,
+		ApplyStatus:  true,
+		Concurrency:  1,
+		QPS:          50,
+		Burst:        100,
+		Validate:     ValidationStrict,
+		Retries:      5,
+		RetryBackoff: time.Second,
+		Speed:        1,
+		Timeout:      defaultWaitTimeout,
+		Replicas:     1,
+		IOStreams:    ioStreams,
 	}
 	return flags
 }
@@ -93,7 +223,7 @@ func NewCmdApply(ioStreams genericclioptions.IOStreams) *cobra.Command {
 		Run: func(cmd *cobra.Command, args []string) {
 			o, err := flags.ToOptions(cmd, args)
 			cmdutil.CheckErr(err)
-			//cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Validate())
 			cmdutil.CheckErr(o.Run())
 		},
 	}
@@ -103,10 +233,41 @@ func NewCmdApply(ioStreams genericclioptions.IOStreams) *cobra.Command {
 
 // AddFlags registers flags for a cli
 func (flags *ApplyFlags) AddFlags(cmd *cobra.Command) {
+	flags.addObjectFlags(cmd)
+	cmdutil.AddDryRunFlag(cmd)
+
+	cmd.Flags().IntVar(&flags.Concurrency, "concurrency", flags.Concurrency, "The count of objects which are applied in parallel.")
+	cmd.Flags().Float32Var(&flags.QPS, "qps", flags.QPS, "The maximum requests per second sent to the api server by all workers, 0 or less means no limit.")
+	cmd.Flags().IntVar(&flags.Burst, "burst", flags.Burst, "The maximum burst of requests above --qps.")
+	cmd.Flags().BoolVar(&flags.Prune, "prune", flags.Prune, "Automatically delete resource objects, that do not appear in the configs and are created by apply. Should be used with -l.")
+	cmd.Flags().StringVarP(&flags.Output, "output", "o", flags.Output, "Output format. One of: name|json|yaml.")
+	cmd.Flags().IntVar(&flags.Retries, "retries", flags.Retries, "The count of times an object is applied again after a throttling, server, conflict or network error.")
+	cmd.Flags().DurationVar(&flags.RetryBackoff, "retry-backoff", flags.RetryBackoff, "The wait before the first retry, which doubles for every next retry.")
+	cmd.Flags().StringVar(&flags.Checkpoint, "checkpoint", flags.Checkpoint, "The file recording the applied objects, defaults to a file in the temporary directory unique for the cluster and the applied files.")
+	cmd.Flags().BoolVar(&flags.Replay, "replay", flags.Replay, "If true, apply the objects with an "+generate.ArrivalTimeAnnotationKey+" annotation, or workloads with a creationTimestamp, when their arrival time is reached. Send SIGUSR1 to pause or resume.")
+	cmd.Flags().Float64Var(&flags.Speed, "speed", flags.Speed, "The speed of --replay relative to real time, e.g. 60 replays an hour of the trace in a minute.")
+	cmd.Flags().BoolVar(&flags.Wait, "wait", flags.Wait, "If true, wait for the applied pods to be bound or unschedulable, and report the outcome.")
+	cmd.Flags().DurationVar(&flags.Timeout, "timeout", flags.Timeout, "The length of time to wait for the applied pods with --wait.")
+	cmd.Flags().IntVar(&flags.Replicas, "replicas", flags.Replicas, "The count of objects created from every document with a metadata.generateName and no name.")
+	cmd.Flags().BoolVar(&flags.Resume, "resume", flags.Resume, "If true, skip the objects which were applied by a previous run of the same files that failed.")
+}
+
+// addObjectFlags registers the flags which select the objects and how they are merged with the live
+// objects, which `diff` shares with `apply`.
+func (flags *ApplyFlags) addObjectFlags(cmd *cobra.Command) {
 	// bind flag structs
 	flags.RecordFlags.AddFlags(cmd)
 	flags.FileNameFlags.AddFlags(cmd.Flags())
 	flags.KubeConfigFlags.AddFlags(cmd.Flags())
+
+	cmd.Flags().BoolVar(&flags.Overwrite, "overwrite", flags.Overwrite, "Automatically resolve conflicts between the modified and live configuration by using values from the modified configuration")
+	cmd.Flags().BoolVar(&flags.ServerSideApply, "server-side", flags.ServerSideApply, "If true, apply runs in the server instead of the client.")
+	cmd.Flags().BoolVar(&flags.ForceConflicts, "force-conflicts", flags.ForceConflicts, "If true, server-side apply will force the changes against conflicts.")
+	cmd.Flags().StringVar(&flags.FieldManager, "field-manager", flags.FieldManager, "Name of the manager used to track field ownership.")
+	cmd.Flags().BoolVar(&flags.ApplyStatus, "status", flags.ApplyStatus, "If true, also apply the status of objects, such as the allocatable resources of nodes, through the status subresource.")
+	cmd.Flags().StringVarP(&flags.Selector, "selector", "l", flags.Selector, "Selector (label query) to filter on, supports '=', '==', and '!='.(e.g. -l key1=value1,key2=value2)")
+	cmd.Flags().StringVar(&flags.Validate, "validate", flags.Validate, "Validate the objects before applying them: strict rejects all objects if any is invalid, warn only reports the problems, ignore skips validation.")
+	cmd.Flags().Lookup("validate").NoOptDefVal = ValidationStrict
 }
 
 // ToOptions converts from CLI inputs to runtime inputs
@@ -124,36 +285,139 @@ func (flags *ApplyFlags) ToOptions(cmd *cobra.Command, args []string) (*ApplyOpt
 		return nil, err
 	}
 
-	f := cmdutil.NewFactory(flags.KubeConfigFlags)
+	f := cmdutil.NewFactory(newRateLimitedClientGetter(flags.KubeConfigFlags, flags.QPS, flags.Burst))
 	namespace, enforceNamespace, err := f.ToRawKubeConfigLoader().Namespace()
 	if err != nil {
 		return nil, err
 	}
 	builder := f.NewBuilder()
 
-	//validationDirective, err := cmdutil.GetValidationDirective(cmd)
-	//if err != nil {
-	//	return nil, err
-	//}
-	//validator, err := f.Validator(validationDirective, nil)
-	//if err != nil {
-	//	return nil, err
-	//}
+	validationDirective, err := parseValidationDirective(flags.Validate)
+	if err != nil {
+		return nil, err
+	}
+	var validator validation.Schema
+	if validationDirective != ValidationIgnore {
+		openapiSchema, err := f.Validator(true)
+		if err != nil {
+			// validate offline only, e.g. when the openapi schema is not served
+			fmt.Fprintf(flags.ErrOut, "Warning: skip openapi validation: %v\n", err)
+		}
+		validator = &objectValidator{openapi: openapiSchema}
+	}
+
+	// diff has no --dry-run, it always merges the objects on the server without persisting them
+	dryRunStrategy := cmdutil.DryRunServer
+	if cmd.Flags().Lookup("dry-run") != nil {
+		if dryRunStrategy, err = cmdutil.GetDryRunStrategy(cmd); err != nil {
+			return nil, err
+		}
+	}
 
 	o := &ApplyOptions{
-		Recorder:         recorder,
-		Builder:          builder,
-		Namespace:        namespace,
-		EnforceNamespace: enforceNamespace,
-		FilenameOptions:  fileNameOpt,
-		//Validator:        validator,
-		objects:       []*resource.Info{},
-		objectsCached: false,
+		Recorder:            recorder,
+		Builder:             builder,
+		Namespace:           namespace,
+		EnforceNamespace:    enforceNamespace,
+		FilenameOptions:     fileNameOpt,
+		Overwrite:           flags.Overwrite,
+		ServerSideApply:     flags.ServerSideApply,
+		ForceConflicts:      flags.ForceConflicts,
+		FieldManager:        flags.FieldManager,
+		ApplyStatus:         flags.ApplyStatus,
+		Concurrency:         flags.Concurrency,
+		DryRunStrategy:      dryRunStrategy,
+		Prune:               flags.Prune,
+		Selector:            flags.Selector,
+		Output:              flags.Output,
+		Retries:             flags.Retries,
+		RetryBackoff:        flags.RetryBackoff,
+		Checkpoint:          flags.Checkpoint,
+		Resume:              flags.Resume,
+		Replay:              flags.Replay,
+		Speed:               flags.Speed,
+		Wait:                flags.Wait,
+		Timeout:             flags.Timeout,
+		Replicas:            flags.Replicas,
+		IOStreams:           flags.IOStreams,
+		Validator:           validator,
+		ValidationDirective: validationDirective,
+		objects:             []*resource.Info{},
+		objectsCached:       false,
+	}
+	if len(o.Checkpoint) == 0 {
+		config, err := f.ToRESTConfig()
+		if err != nil {
+			return nil, err
+		}
+		o.Checkpoint = defaultCheckpointPath(config.Host, fileNameOpt.Filenames, namespace)
+	}
+	if o.ApplyStatus {
+		if o.Discovery, err = f.ToDiscoveryClient(); err != nil {
+			return nil, err
+		}
+	}
+	if o.Wait {
+		if o.Client, err = f.KubernetesClientSet(); err != nil {
+			return nil, err
+		}
+	}
+	if o.Prune {
+		if o.Mapper, err = f.ToRESTMapper(); err != nil {
+			return nil, err
+		}
+		if o.DynamicClient, err = f.DynamicClient(); err != nil {
+			return nil, err
+		}
 	}
 	return o, nil
 }
 
+// Validate verifies if ApplyOptions are valid and without conflicts.
+func (o *ApplyOptions) Validate() error {
+	if o.ForceConflicts && !o.ServerSideApply {
+		return fmt.Errorf("--force-conflicts only works with --server-side")
+	}
+	if len(o.FieldManager) == 0 {
+		return fmt.Errorf("--field-manager must not be empty")
+	}
+	if o.ServerSideApply && o.DryRunStrategy == cmdutil.DryRunClient {
+		return fmt.Errorf("--dry-run=client doesn't work with --server-side (did you mean --dry-run=server instead?)")
+	}
+	if o.Concurrency < 1 {
+		return fmt.Errorf("--concurrency must be at least 1")
+	}
+	if o.Prune && len(o.Selector) == 0 {
+		return fmt.Errorf("--prune requires a selector, specified with -l")
+	}
+	switch o.Output {
+	case "", "name", "json", "yaml":
+	default:
+		return fmt.Errorf("unsupported output format %q, allowed formats are: name, json, yaml", o.Output)
+	}
+	if o.Replicas < 1 {
+		return fmt.Errorf("--replicas must be at least 1")
+	}
+	if o.Retries < 0 {
+		return fmt.Errorf("--retries must not be negative")
+	}
+	if o.Replay && o.Speed <= 0 {
+		return fmt.Errorf("--speed must be greater than 0")
+	}
+	if o.Wait && o.Timeout <= 0 {
+		return fmt.Errorf("--timeout must be greater than 0")
+	}
+	if o.Resume && o.DryRunStrategy != cmdutil.DryRunNone {
+		return fmt.Errorf("--resume doesn't work with --dry-run")
+	}
+	return nil
+}
+
 func (o *ApplyOptions) Run() error {
+	if err := o.validateFiles(); err != nil {
+		return err
+	}
+
 	// Generates the objects using the resource builder if they have not
 	// already been stored by calling "SetObjects()" in the pre-processor.
 	errs := []error{}
@@ -164,11 +428,48 @@ func (o *ApplyOptions) Run() error {
 	if len(infos) == 0 && len(errs) == 0 {
 		return fmt.Errorf("no objects passed to apply")
 	}
-	fmt.Printf("infos length: %d", len(infos))
-	// Iterate through all objects, applying each one.
-	for _, info := range infos {
-		if err := o.applyObject(info); err != nil {
+
+	if o.DryRunStrategy == cmdutil.DryRunNone {
+		if o.checkpoint, err = openCheckpoint(o.Checkpoint, o.Resume); err != nil {
+			return err
+		}
+	}
+	o.results = newApplyResults(o.Output, o.DryRunStrategy, o.Out, o.ErrOut)
+	o.results.startProgress(len(infos))
+	upfront, arrivals := infos, []arrival(nil)
+	if o.Replay {
+		if upfront, arrivals, err = splitArrivals(infos); err != nil {
+			return err
+		}
+	}
+	// Apply the objects tier by tier, so that namespaces, CRDs and priority
+	// classes exist before the objects which depend on them.
+	for _, tier := range tierInfos(upfront) {
+		errs = append(errs, parallelize(o.Concurrency, tier, o.applyObject)...)
+	}
+	errs = append(errs, o.replay(arrivals)...)
+	// Only prune when every object was applied, a failed object must not be
+	// mistaken for one which was removed from the files.
+	if o.Prune && len(errs) == 0 {
+		errs = append(errs, o.prune(infos)...)
+	}
+	if err := o.results.Flush(); err != nil {
+		errs = append(errs, err)
+	}
+	if o.checkpoint != nil {
+		if err := o.checkpoint.Close(len(errs) == 0); err != nil {
 			errs = append(errs, err)
+		} else if len(errs) > 0 {
+			fmt.Fprintf(o.ErrOut, "Applied objects are recorded in %s, rerun with --resume to skip them.\n", o.Checkpoint)
+		}
+	}
+	if o.Wait && o.DryRunStrategy == cmdutil.DryRunNone {
+		if pods := podNames(infos); len(pods) > 0 {
+			wait := &WaitOptions{Client: o.Client, Pods: pods, Timeout: o.Timeout, IOStreams: o.IOStreams}
+			fmt.Fprintf(o.ErrOut, "Waiting up to %v for %d pods to be scheduled...\n", o.Timeout, len(pods))
+			if err := wait.WaitAndReport(); err != nil {
+				errs = append(errs, err)
+			}
 		}
 	}
 	// If any errors occurred during apply, then return error (or
@@ -188,45 +489,230 @@ func (o *ApplyOptions) GetObjects() ([]*resource.Info, error) {
 	if !o.objectsCached {
 		r := o.Builder.
 			Unstructured().
-			//Schema(o.Validator).
+			Schema(o.builderSchema()).
 			ContinueOnError().
 			NamespaceParam(o.Namespace).DefaultNamespace().
 			FilenameParam(o.EnforceNamespace, &o.FilenameOptions).
+			LabelSelectorParam(o.Selector).
 			Flatten().
 			Do()
 		o.objects, err = r.Infos()
+		o.objects = o.expandGenerated(o.objects)
 		o.objectsCached = true
 	}
 	return o.objects, err
 }
 
+// applyObject applies info and records the result of it.
 func (o *ApplyOptions) applyObject(info *resource.Info) error {
+	var key, hash string
+	if o.checkpoint != nil {
+		var err error
+		key = o.checkpointKey(info)
+		if hash, err = objectHash(info.Object); err != nil {
+			return err
+		}
+		if o.checkpoint.Done(key, hash) {
+			o.results.RecordInfo(info, OperationSkipped)
+			return nil
+		}
+	}
+
+	operation, err := o.applyWithRetries(info)
+	if err == nil && o.checkpoint != nil {
+		err = o.checkpoint.Add(key, hash)
+	}
+	if err != nil {
+		operation = OperationFailed
+	}
+	o.results.RecordInfo(info, operation)
+	return err
+}
 
+func (o *ApplyOptions) applyInfo(info *resource.Info) (string, error) {
 	if err := o.Recorder.Record(info.Object); err != nil {
 		klog.V(4).Infof("error recording current command: %v", err)
 	}
 
-	if len(info.Name) == 0 {
-		metadata, _ := meta.Accessor(info.Object)
-		generatedName := metadata.GetGenerateName()
-		if len(generatedName) > 0 {
-			return fmt.Errorf("from %s: cannot use generate name with apply", generatedName)
+	// The api server drops the status on create and update, so keep the
+	// status in the file to write it through the status subresource.
+	status := objectStatus(info.Object)
+	operation, err := o.applyConfiguration(info)
+	if err != nil {
+		return "", err
+	}
+	if o.ApplyStatus && len(status) > 0 && o.DryRunStrategy != cmdutil.DryRunClient {
+		if err := o.applyStatus(info, status); err != nil {
+			return "", err
 		}
 	}
+	return operation, nil
+}
 
-	helper := resource.NewHelper(info.Client, info.Mapping)
+// applyConfiguration creates the object if it is missing, and patches it otherwise.
+// It returns the operation done to the object, as printed by kubectl.
+func (o *ApplyOptions) applyConfiguration(info *resource.Info) (string, error) {
+	helper := resource.NewHelper(info.Client, info.Mapping).
+		WithFieldManager(o.FieldManager).
+		DryRun(o.DryRunStrategy == cmdutil.DryRunServer)
+
+	if isGenerated(info) {
+		return o.createGenerated(info, helper)
+	}
+	if o.ServerSideApply {
+		return o.serverSideApply(info, helper)
+	}
+
+	// Get the modified configuration of the object. Embed the result
+	// as an annotation in the modified configuration, so that it will appear
+	// in the patch sent to the server.
+	modified, err := util.GetModifiedConfiguration(info.Object, true, unstructured.UnstructuredJSONScheme)
+	if err != nil {
+		return "", addSourceToErr(fmt.Sprintf("retrieving modified configuration from:\n%s\nfor:", info.String()), info.Source, err)
+	}
 
 	if err := info.Get(); err != nil {
 		if !errors.IsNotFound(err) {
-			return cmdutil.AddSourceToErr(fmt.Sprintf("retrieving current configuration of:\n%s\nfrom server for:", info.String()), info.Source, err)
+			return "", addSourceToErr(fmt.Sprintf("retrieving current configuration of:\n%s\nfrom server for:", info.String()), info.Source, err)
+		}
+
+		// Create the resource if it doesn't exist
+		// First, update the annotation used by apply
+		if err := util.CreateApplyAnnotation(info.Object, unstructured.UnstructuredJSONScheme); err != nil {
+			return "", addSourceToErr("creating", info.Source, err)
+		}
+		if o.DryRunStrategy == cmdutil.DryRunClient {
+			return OperationCreated, nil
 		}
 
 		// Then create the resource and skip the three-way merge
 		obj, err := helper.Create(info.Namespace, true, info.Object)
 		if err != nil {
-			return cmdutil.AddSourceToErr("creating", info.Source, err)
+			return "", addSourceToErr("creating", info.Source, err)
 		}
 		info.Refresh(obj, true)
+		return OperationCreated, nil
+	}
+	if o.DryRunStrategy == cmdutil.DryRunClient {
+		return OperationConfigured, nil
+	}
+
+	metadata, _ := meta.Accessor(info.Object)
+	if _, ok := metadata.GetAnnotations()[corev1.LastAppliedConfigAnnotation]; !ok {
+		fmt.Fprintf(o.ErrOut, warningNoLastAppliedConfigAnnotation, info.ObjectName(), corev1.LastAppliedConfigAnnotation)
+	}
+
+	// Compute a three way merge patch from the last applied, modified and live
+	// configuration, and send it to the server.
+	patcher := &kubectlapply.Patcher{
+		Mapping:   info.Mapping,
+		Helper:    helper,
+		Overwrite: o.Overwrite,
+		BackOff:   clockwork.NewRealClock(),
+		Retries:   maxPatchRetry,
 	}
+	patchBytes, patchedObject, err := patcher.Patch(info.Object, modified, info.Source, info.Namespace, info.Name, o.ErrOut)
+	if err != nil {
+		return "", addSourceToErr(fmt.Sprintf("applying patch:\n%s\nto:\n%v\nfor:", patchBytes, info), info.Source, err)
+	}
+	info.Refresh(patchedObject, true)
+	if string(patchBytes) == "{}" {
+		return OperationUnchanged, nil
+	}
+	return OperationConfigured, nil
+}
+
+// serverSideApply sends the whole object as an apply patch, the api server creates it
+// if it is missing and reports the fields which are owned by other managers as conflicts.
+func (o *ApplyOptions) serverSideApply(info *resource.Info, helper *resource.Helper) (string, error) {
+	data, err := runtime.Encode(unstructured.UnstructuredJSONScheme, info.Object)
+	if err != nil {
+		return "", addSourceToErr("serverside-apply", info.Source, err)
+	}
+
+	options := metav1.PatchOptions{
+		Force: &o.ForceConflicts,
+	}
+	obj, err := helper.Patch(info.Namespace, info.Name, types.ApplyPatchType, data, &options)
+	if err != nil {
+		if errors.IsConflict(err) {
+			return "", fmt.Errorf("conflicts applying %s with field manager %q, rerun with --force-conflicts "+
+				"to take ownership of the conflicting fields: %v", info.ObjectName(), o.FieldManager, err)
+		}
+		return "", addSourceToErr("serverside-apply", info.Source, err)
+	}
+	info.Refresh(obj, true)
+	return OperationServerSideApplied, nil
+}
+
+// objectStatus returns a copy of the status of obj, or nil if it has none.
+func objectStatus(obj runtime.Object) map[string]interface{} {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil
+	}
+	status, found, err := unstructured.NestedMap(u.Object, "status")
+	if !found || err != nil {
+		return nil
+	}
+	return status
+}
+
+// applyStatus merges status into the status subresource of the applied object, so that the
+// cluster shows the state in the file, e.g. the allocatable resources of fake nodes.
+func (o *ApplyOptions) applyStatus(info *resource.Info, status map[string]interface{}) error {
+	hasStatus, err := o.hasStatusSubresource(info.Mapping)
+	if err != nil {
+		return addSourceToErr("discovering the status subresource", info.Source, err)
+	}
+	if !hasStatus {
+		// the status of the kind is stored with the object
+		return nil
+	}
+	data, err := json.Marshal(map[string]interface{}{"status": status})
+	if err != nil {
+		return addSourceToErr("serializing status", info.Source, err)
+	}
+	options := &metav1.PatchOptions{FieldManager: o.FieldManager}
+	if o.DryRunStrategy == cmdutil.DryRunServer {
+		options.DryRun = []string{metav1.DryRunAll}
+	}
+	obj, err := info.Client.Patch(types.MergePatchType).
+		NamespaceIfScoped(info.Namespace, info.Namespaced()).
+		Resource(info.Mapping.Resource.Resource).
+		Name(info.Name).
+		SubResource("status").
+		VersionedParams(options, metav1.ParameterCodec).
+		Body(data).
+		Do(context.TODO()).
+		Get()
+	if err != nil {
+		return addSourceToErr("updating status", info.Source, err)
+	}
+	info.Refresh(obj, true)
 	return nil
 }
+
+// hasStatusSubresource reports whether the resource of mapping has a status subresource.
+func (o *ApplyOptions) hasStatusSubresource(mapping *meta.RESTMapping) (bool, error) {
+	resources, err := o.Discovery.ServerResourcesForGroupVersion(mapping.Resource.GroupVersion().String())
+	if err != nil {
+		return false, err
+	}
+	for _, apiResource := range resources.APIResources {
+		if apiResource.Name == mapping.Resource.Resource+"/status" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func dryRunSuffix(strategy cmdutil.DryRunStrategy) string {
+	switch strategy {
+	case cmdutil.DryRunClient:
+		return " (dry run)"
+	case cmdutil.DryRunServer:
+		return " (server dry run)"
+	}
+	return ""
+}
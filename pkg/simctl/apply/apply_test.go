@@ -0,0 +1,44 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apply
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestHasStatusSubresource(t *testing.T) {
+	discovery := &fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{Resources: []*metav1.APIResourceList{{
+		GroupVersion: "v1",
+		APIResources: []metav1.APIResource{{Name: "nodes"}, {Name: "nodes/status"}, {Name: "configmaps"}},
+	}}}}
+	o := &ApplyOptions{Discovery: discovery}
+	for resource, want := range map[string]bool{"nodes": true, "configmaps": false} {
+		mapping := &meta.RESTMapping{Resource: schema.GroupVersionResource{Version: "v1", Resource: resource}}
+		if got, err := o.hasStatusSubresource(mapping); err != nil || got != want {
+			t.Errorf("%s has a status subresource: %v, %v, want %v", resource, got, err, want)
+		}
+	}
+	// an unknown group version is an error, not a kind without status subresource
+	mapping := &meta.RESTMapping{Resource: schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}}
+	if _, err := o.hasStatusSubresource(mapping); err == nil {
+		t.Errorf("an unknown group version has no error")
+	}
+}
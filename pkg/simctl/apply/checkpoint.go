@@ -0,0 +1,155 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apply
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/resource"
+)
+
+// checkpoint records the objects which have been applied, one "key hash" line per object,
+// so that a failed apply can be resumed without applying them again. The hash is taken
+// from the object in the file, an object which was changed since is applied again.
+type checkpoint struct {
+	lock sync.Mutex
+	path string
+	file *os.File
+	done map[string]string
+}
+
+// openCheckpoint opens the checkpoint at path for appending. If resume is true, the objects
+// recorded by the previous run are kept, otherwise the checkpoint is truncated.
+func openCheckpoint(path string, resume bool) (*checkpoint, error) {
+	c := &checkpoint{path: path, done: map[string]string{}}
+	flag := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if resume {
+		if err := c.load(); err != nil {
+			return nil, err
+		}
+	} else {
+		flag |= os.O_TRUNC
+	}
+	file, err := os.OpenFile(path, flag, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("opening checkpoint: %v", err)
+	}
+	c.file = file
+	return c, nil
+}
+
+func (c *checkpoint) load() error {
+	file, err := os.Open(c.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading checkpoint: %v", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		// a line may be cut short by a crash, the object is applied again then
+		if len(fields) != 2 {
+			continue
+		}
+		c.done[fields[0]] = fields[1]
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("reading checkpoint %s: %v", c.path, err)
+	}
+	return nil
+}
+
+// Done returns true if the object with key and hash has been applied.
+func (c *checkpoint) Done(key, hash string) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.done[key] == hash
+}
+
+// Add records that the object with key and hash has been applied. It is written
+// right away, so that it survives the process being killed.
+func (c *checkpoint) Add(key, hash string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.done[key] = hash
+	if _, err := fmt.Fprintf(c.file, "%s %s\n", key, hash); err != nil {
+		return fmt.Errorf("writing checkpoint: %v", err)
+	}
+	return nil
+}
+
+// Close closes the checkpoint, and removes it if the apply completed, since
+// there is nothing left to resume.
+func (c *checkpoint) Close(completed bool) error {
+	if err := c.file.Close(); err != nil {
+		return err
+	}
+	if completed {
+		return os.Remove(c.path)
+	}
+	return nil
+}
+
+// defaultCheckpointPath returns a checkpoint path in the temporary directory which is unique
+// for the api server, the files and the namespace, so that rerunning the same command against
+// the same cluster resumes it.
+func defaultCheckpointPath(server string, filenames []string, namespace string) string {
+	abs := make([]string, 0, len(filenames))
+	for _, filename := range filenames {
+		if path, err := filepath.Abs(filename); err == nil {
+			filename = path
+		}
+		abs = append(abs, filename)
+	}
+	sort.Strings(abs)
+	sum := sha256.Sum256([]byte(server + "\x00" + namespace + "\x00" + strings.Join(abs, "\x00")))
+	return filepath.Join(os.TempDir(), fmt.Sprintf("simctl-apply-%s.checkpoint", hex.EncodeToString(sum[:8])))
+}
+
+// checkpointKey identifies the object of info in a checkpoint. An object whose name is
+// generated is identified by its generateName and its ordinal among the objects sharing it.
+func (o *ApplyOptions) checkpointKey(info *resource.Info) string {
+	name := info.Name
+	if isGenerated(info) {
+		metadata, _ := meta.Accessor(info.Object)
+		name = fmt.Sprintf("%s#%d", metadata.GetGenerateName(), o.ordinals[info])
+	}
+	return pruneKey(info.Mapping.GroupVersionKind.GroupKind(), info.Namespace, name)
+}
+
+// objectHash returns a short hash of the content of obj.
+func objectHash(obj runtime.Object) (string, error) {
+	data, err := runtime.Encode(unstructured.UnstructuredJSONScheme, obj)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:16]), nil
+}
@@ -0,0 +1,29 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apply
+
+import "testing"
+
+func TestDefaultCheckpointPath(t *testing.T) {
+	files := []string{"b.yaml", "a.yaml"}
+	path := defaultCheckpointPath("https://cluster-1:6443", files, "default")
+	if got := defaultCheckpointPath("https://cluster-1:6443", []string{"a.yaml", "b.yaml"}, "default"); got != path {
+		t.Errorf("the order of the files changed the checkpoint %s to %s", path, got)
+	}
+	// the same files applied to another cluster do not share the checkpoint
+	if got := defaultCheckpointPath("https://cluster-2:6443", files, "default"); got == path {
+		t.Errorf("two clusters share the checkpoint %s", path)
+	}
+}
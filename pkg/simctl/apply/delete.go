@@ -0,0 +1,313 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apply
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	utilpointer "k8s.io/utils/pointer"
+
+	"github.com/D0m021ng/scheduler-simulator/pkg/simctl/generate"
+)
+
+var (
+	// resetKinds are the kinds of objects which simctl creates.
+	resetKinds = []schema.GroupVersionKind{
+		{Version: "v1", Kind: "Namespace"},
+		{Group: "scheduling.k8s.io", Version: "v1", Kind: "PriorityClass"},
+		{Group: "scheduling.volcano.sh", Version: "v1beta1", Kind: "Queue"},
+		{Version: "v1", Kind: "Node"},
+		{Version: "v1", Kind: "ResourceQuota"},
+		{Group: "scheduling.volcano.sh", Version: "v1beta1", Kind: "PodGroup"},
+		{Version: "v1", Kind: "Pod"},
+	}
+
+	// protectedNamespaces are never deleted, even if they are labeled by simctl.
+	protectedNamespaces = sets.NewString("default", "kube-system", "kube-public", "kube-node-lease")
+)
+
+// DeleteFlags directly reflect the information that CLI is gathering via flags.
+type DeleteFlags struct {
+	FileNameFlags   *genericclioptions.FileNameFlags
+	KubeConfigFlags *genericclioptions.ConfigFlags
+
+	LabelSelector string
+
+	Concurrency int
+	QPS         float32
+	Burst       int
+
+	GracePeriod int
+	Wait        bool
+	Timeout     time.Duration
+
+	genericclioptions.IOStreams
+}
+
+// DeleteOptions defines flags and other configuration parameters for the `delete` and `reset` commands
+type DeleteOptions struct {
+	Builder *resource.Builder
+	Mapper  meta.RESTMapper
+
+	FilenameOptions  resource.FilenameOptions
+	Namespace        string
+	EnforceNamespace bool
+
+	// Reset deletes all objects which are labeled by LabelSelector, instead of the objects in files.
+	// Objects which simctl only patched are kept, e.g. real nodes which were relabeled.
+	Reset         bool
+	LabelSelector string
+
+	Concurrency     int
+	GracePeriod     int
+	WaitForDeletion bool
+	Timeout         time.Duration
+
+	genericclioptions.IOStreams
+}
+
+func NewDeleteFlags(ioStreams genericclioptions.IOStreams) *DeleteFlags {
+	filenames := []string{}
+	kustomize := ""
+	recursive := false
+	usage := "The files that contain the configurations to delete."
+	return &DeleteFlags{
+		FileNameFlags: &genericclioptions.FileNameFlags{Usage: usage, Filenames: &filenames, Kustomize: &kustomize, Recursive: &recursive},
+		KubeConfigFlags: &genericclioptions.ConfigFlags{
+			Timeout:    utilpointer.String("0"),
+			KubeConfig: utilpointer.String(""),
+			APIServer:  utilpointer.String(""),
+			Namespace:  utilpointer.String(""),
+		},
+		LabelSelector: generate.SimulatorLabelKey + "=true",
+		Concurrency:   10,
+		QPS:           50,
+		Burst:         100,
+		Wait:          true,
+		Timeout:       5 * time.Minute,
+		IOStreams:     ioStreams,
+	}
+}
+
+// NewCmdDelete creates the `delete` command
+func NewCmdDelete(ioStreams genericclioptions.IOStreams) *cobra.Command {
+	flags := NewDeleteFlags(ioStreams)
+
+	cmd := &cobra.Command{
+		Use:                   "delete -f FILENAME",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Delete the resources in files, in reverse dependency order"),
+		Run: func(cmd *cobra.Command, args []string) {
+			o, err := flags.ToOptions(cmd, false)
+			cmdutil.CheckErr(err)
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+	flags.FileNameFlags.AddFlags(cmd.Flags())
+	flags.AddFlags(cmd)
+	return cmd
+}
+
+// NewCmdReset creates the `reset` command
+func NewCmdReset(ioStreams genericclioptions.IOStreams) *cobra.Command {
+	flags := NewDeleteFlags(ioStreams)
+
+	cmd := &cobra.Command{
+		Use:                   "reset",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Delete all nodes, pods, queues, podgroups and namespaces created by simctl"),
+		Run: func(cmd *cobra.Command, args []string) {
+			o, err := flags.ToOptions(cmd, true)
+			cmdutil.CheckErr(err)
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+	flags.AddFlags(cmd)
+	cmd.Flags().StringVarP(&flags.LabelSelector, "selector", "l", flags.LabelSelector, "Delete the objects matching this label selector.")
+	return cmd
+}
+
+// AddFlags registers flags for a cli
+func (flags *DeleteFlags) AddFlags(cmd *cobra.Command) {
+	flags.KubeConfigFlags.AddFlags(cmd.Flags())
+
+	cmd.Flags().IntVar(&flags.Concurrency, "concurrency", flags.Concurrency, "The count of objects which are deleted in parallel.")
+	cmd.Flags().Float32Var(&flags.QPS, "qps", flags.QPS, "The maximum requests per second sent to the api server by all workers, 0 or less means no limit.")
+	cmd.Flags().IntVar(&flags.Burst, "burst", flags.Burst, "The maximum burst of requests above --qps.")
+	cmd.Flags().IntVar(&flags.GracePeriod, "grace-period", flags.GracePeriod, "Period of time in seconds given to the resource to terminate gracefully. Simulated pods have no kubelet, so the default 0 deletes them immediately.")
+	cmd.Flags().BoolVar(&flags.Wait, "wait", flags.Wait, "If true, wait for the objects of a kind to be gone before deleting the objects they depend on.")
+	cmd.Flags().DurationVar(&flags.Timeout, "timeout", flags.Timeout, "The length of time to wait for the deletion of every object.")
+}
+
+// ToOptions converts from CLI inputs to runtime inputs
+func (flags *DeleteFlags) ToOptions(cmd *cobra.Command, reset bool) (*DeleteOptions, error) {
+	fileNameOpt := flags.FileNameFlags.ToOptions()
+	if !reset {
+		if err := fileNameOpt.RequireFilenameOrKustomize(); err != nil {
+			return nil, err
+		}
+	}
+	if flags.Concurrency < 1 {
+		return nil, fmt.Errorf("--concurrency must be at least 1")
+	}
+	if _, err := labels.Parse(flags.LabelSelector); err != nil {
+		return nil, err
+	}
+
+	f := cmdutil.NewFactory(newRateLimitedClientGetter(flags.KubeConfigFlags, flags.QPS, flags.Burst))
+	namespace, enforceNamespace, err := f.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return nil, err
+	}
+	mapper, err := f.ToRESTMapper()
+	if err != nil {
+		return nil, err
+	}
+
+	return &DeleteOptions{
+		Builder:          f.NewBuilder(),
+		Mapper:           mapper,
+		FilenameOptions:  fileNameOpt,
+		Namespace:        namespace,
+		EnforceNamespace: enforceNamespace,
+		Reset:            reset,
+		LabelSelector:    flags.LabelSelector,
+		Concurrency:      flags.Concurrency,
+		GracePeriod:      flags.GracePeriod,
+		WaitForDeletion:  flags.Wait,
+		Timeout:          flags.Timeout,
+		IOStreams:        flags.IOStreams,
+	}, nil
+}
+
+func (o *DeleteOptions) Run() error {
+	errs := []error{}
+	infos, err := o.GetObjects()
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if len(infos) == 0 && len(errs) == 0 {
+		fmt.Fprintln(o.Out, "No resources found")
+		return nil
+	}
+	errs = append(errs, o.DeleteInfos(infos)...)
+	return utilerrors.NewAggregate(errs)
+}
+
+// GetObjects returns the objects in files, or all objects labeled as created by simctl for reset.
+func (o *DeleteOptions) GetObjects() ([]*resource.Info, error) {
+	if !o.Reset {
+		return o.Builder.
+			Unstructured().
+			ContinueOnError().
+			NamespaceParam(o.Namespace).DefaultNamespace().
+			FilenameParam(o.EnforceNamespace, &o.FilenameOptions).
+			Flatten().
+			Do().
+			Infos()
+	}
+
+	var resourceTypes []string
+	for _, gvk := range resetKinds {
+		mapping, err := o.Mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			if meta.IsNoMatchError(err) {
+				continue
+			}
+			return nil, err
+		}
+		resourceTypes = append(resourceTypes, mapping.Resource.GroupResource().String())
+	}
+	infos, err := o.Builder.
+		Unstructured().
+		ContinueOnError().
+		AllNamespaces(true).
+		ResourceTypes(resourceTypes...).
+		LabelSelectorParam(o.LabelSelector).
+		Flatten().
+		Do().
+		Infos()
+
+	var created []*resource.Info
+	for _, info := range infos {
+		if info.Mapping.GroupVersionKind.Kind == "Namespace" && protectedNamespaces.Has(info.Name) {
+			continue
+		}
+		created = append(created, info)
+	}
+	return created, err
+}
+
+// DeleteInfos deletes infos tier by tier in reverse dependency order, e.g. the pods before their namespaces.
+func (o *DeleteOptions) DeleteInfos(infos []*resource.Info) []error {
+	var errs []error
+	tiers := tierInfos(infos)
+	for i := len(tiers) - 1; i >= 0; i-- {
+		tierErrs := parallelize(o.Concurrency, tiers[i], o.deleteObject)
+		if len(tierErrs) == 0 && o.WaitForDeletion {
+			tierErrs = parallelize(o.Concurrency, tiers[i], o.waitForDeletion)
+		}
+		errs = append(errs, tierErrs...)
+	}
+	return errs
+}
+
+func (o *DeleteOptions) deleteObject(info *resource.Info) error {
+	policy := metav1.DeletePropagationBackground
+	options := &metav1.DeleteOptions{
+		GracePeriodSeconds: utilpointer.Int64(int64(o.GracePeriod)),
+		PropagationPolicy:  &policy,
+	}
+	_, err := resource.NewHelper(info.Client, info.Mapping).DeleteWithOptions(info.Namespace, info.Name, options)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return cmdutil.AddSourceToErr("deleting", info.Source, err)
+	}
+	fmt.Fprintf(o.Out, "%s deleted\n", info.ObjectName())
+	return nil
+}
+
+func (o *DeleteOptions) waitForDeletion(info *resource.Info) error {
+	helper := resource.NewHelper(info.Client, info.Mapping)
+	err := wait.PollImmediate(time.Second, o.Timeout, func() (bool, error) {
+		if _, err := helper.Get(info.Namespace, info.Name); err != nil {
+			if errors.IsNotFound(err) {
+				return true, nil
+			}
+			return false, err
+		}
+		return false, nil
+	})
+	if err != nil {
+		return fmt.Errorf("waiting for the deletion of %s: %v", info.ObjectName(), err)
+	}
+	return nil
+}
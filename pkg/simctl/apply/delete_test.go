@@ -0,0 +1,60 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apply
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"github.com/D0m021ng/scheduler-simulator/pkg/simctl/generate"
+	"github.com/D0m021ng/scheduler-simulator/pkg/simctl/importer"
+)
+
+func TestResetSelectsImportedObjects(t *testing.T) {
+	jobs := []importer.Job{{ID: "1", Queue: "q", Runtime: time.Minute, Tasks: 2}}
+	// the pods carry the default labels of `simctl import`
+	objs := importer.BuildJobObjects(jobs, "batch", "volcano", "default", map[string]string{generate.SimulatorLabelKey: "true"})
+
+	selector, err := labels.Parse(NewDeleteFlags(genericclioptions.IOStreams{}).LabelSelector)
+	if err != nil {
+		t.Fatal(err)
+	}
+	selected := map[string]int{}
+	for _, obj := range objs {
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		u := &unstructured.Unstructured{Object: content}
+		isResetKind := false
+		for _, gvk := range resetKinds {
+			isResetKind = isResetKind || gvk == u.GroupVersionKind()
+		}
+		if !isResetKind || !selector.Matches(labels.Set(u.GetLabels())) ||
+			(u.GetKind() == "Namespace" && protectedNamespaces.Has(u.GetName())) {
+			t.Errorf("reset does not select %s %s", u.GetKind(), u.GetName())
+			continue
+		}
+		selected[u.GetKind()]++
+	}
+	if selected["Namespace"] != 1 || selected["Queue"] != 1 || selected["PodGroup"] != 1 || selected["Pod"] != 2 {
+		t.Errorf("got selected kinds %v", selected)
+	}
+}
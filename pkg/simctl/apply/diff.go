@@ -0,0 +1,292 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apply
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"sigs.k8s.io/yaml"
+)
+
+// diffContext is the count of unchanged lines around the changes of a diff.
+const diffContext = 3
+
+// NewCmdDiff creates the `diff` command, which shows what `apply` would do with the same flags.
+func NewCmdDiff(ioStreams genericclioptions.IOStreams) *cobra.Command {
+	flags := NewApplyFlags(ioStreams)
+
+	cmd := &cobra.Command{
+		Use:                   "diff -f FILENAME",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Diff the configurations in files against the live objects"),
+		Run: func(cmd *cobra.Command, args []string) {
+			o, err := flags.ToOptions(cmd, args)
+			cmdutil.CheckErr(err)
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.RunDiff())
+		},
+	}
+	// only the flags which select and merge the objects, diff never changes the live objects
+	flags.addObjectFlags(cmd)
+	return cmd
+}
+
+// RunDiff prints whether every object would be created, configured or left unchanged by apply,
+// with a unified diff between the live and the merged object.
+func (o *ApplyOptions) RunDiff() error {
+	if err := o.validateFiles(); err != nil {
+		return err
+	}
+
+	errs := []error{}
+	infos, err := o.GetObjects()
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if len(infos) == 0 && len(errs) == 0 {
+		return fmt.Errorf("no objects passed to diff")
+	}
+	for _, info := range infos {
+		if err := o.diffObject(info); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+func (o *ApplyOptions) diffObject(info *resource.Info) error {
+	var live runtime.Object
+	if len(info.Name) > 0 {
+		obj, err := resource.NewHelper(info.Client, info.Mapping).Get(info.Namespace, info.Name)
+		if err != nil && !errors.IsNotFound(err) {
+			return cmdutil.AddSourceToErr(fmt.Sprintf("retrieving current configuration of:\n%s\nfrom server for:", info.String()), info.Source, err)
+		}
+		if err == nil {
+			live = obj
+		}
+	}
+
+	status := objectStatus(info.Object)
+	if _, err := o.applyConfiguration(info); err != nil {
+		return err
+	}
+	merged, ok := info.Object.(*unstructured.Unstructured)
+	if !ok {
+		return fmt.Errorf("unexpected object %T of %s", info.Object, info.ObjectName())
+	}
+	if o.ApplyStatus && len(status) > 0 {
+		if err := mergeStatus(merged, status); err != nil {
+			return cmdutil.AddSourceToErr("merging status", info.Source, err)
+		}
+	}
+
+	liveYaml := ""
+	if live != nil {
+		var err error
+		if liveYaml, err = diffYaml(live); err != nil {
+			return err
+		}
+	}
+	mergedYaml, err := diffYaml(merged)
+	if err != nil {
+		return err
+	}
+
+	name := info.ObjectName()
+	switch {
+	case live == nil:
+		fmt.Fprintf(o.Out, "%s would be created\n", name)
+	case liveYaml == mergedYaml:
+		fmt.Fprintf(o.Out, "%s unchanged\n", name)
+		return nil
+	default:
+		fmt.Fprintf(o.Out, "%s would be configured\n", name)
+	}
+	fmt.Fprint(o.Out, unifiedDiff("live/"+name, "merged/"+name, liveYaml, mergedYaml))
+	return nil
+}
+
+// mergeStatus merges status into the status of obj, the same way as applyStatus.
+func mergeStatus(obj *unstructured.Unstructured, status map[string]interface{}) error {
+	current := []byte("{}")
+	if liveStatus, found, _ := unstructured.NestedMap(obj.Object, "status"); found {
+		data, err := json.Marshal(liveStatus)
+		if err != nil {
+			return err
+		}
+		current = data
+	}
+	patch, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+	merged, err := jsonpatch.MergePatch(current, patch)
+	if err != nil {
+		return err
+	}
+	mergedStatus := map[string]interface{}{}
+	if err := json.Unmarshal(merged, &mergedStatus); err != nil {
+		return err
+	}
+	obj.Object["status"] = mergedStatus
+	return nil
+}
+
+// diffYaml serializes obj without the fields which differ on every write.
+func diffYaml(obj runtime.Object) (string, error) {
+	u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return "", err
+	}
+	u = runtime.DeepCopyJSON(u)
+	unstructured.RemoveNestedField(u, "metadata", "managedFields")
+	unstructured.RemoveNestedField(u, "metadata", "resourceVersion")
+	data, err := yaml.Marshal(u)
+	return string(data), err
+}
+
+type diffLine struct {
+	// op is ' ' for unchanged, '-' for removed and '+' for added lines.
+	op   byte
+	text string
+}
+
+// unifiedDiff returns the changes from a to b in the unified format, or "" if they are equal.
+func unifiedDiff(fromName, toName, a, b string) string {
+	lines := diffLines(splitLines(a), splitLines(b))
+
+	var changes []int
+	for idx, line := range lines {
+		if line.op != ' ' {
+			changes = append(changes, idx)
+		}
+	}
+	if len(changes) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+	for i := 0; i < len(changes); {
+		// merge the changes whose contexts overlap into one hunk
+		j := i
+		for j+1 < len(changes) && changes[j+1]-changes[j] <= 2*diffContext {
+			j++
+		}
+		start := changes[i] - diffContext
+		if start < 0 {
+			start = 0
+		}
+		end := changes[j] + diffContext + 1
+		if end > len(lines) {
+			end = len(lines)
+		}
+
+		aStart, bStart := 0, 0
+		for _, line := range lines[:start] {
+			if line.op != '+' {
+				aStart++
+			}
+			if line.op != '-' {
+				bStart++
+			}
+		}
+		aCount, bCount := 0, 0
+		for _, line := range lines[start:end] {
+			if line.op != '+' {
+				aCount++
+			}
+			if line.op != '-' {
+				bCount++
+			}
+		}
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(aStart, aCount), hunkRange(bStart, bCount))
+		for _, line := range lines[start:end] {
+			fmt.Fprintf(&sb, "%c%s\n", line.op, line.text)
+		}
+		i = j + 1
+	}
+	return sb.String()
+}
+
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// diffLines computes the edit script from a to b by their longest common subsequence.
+func diffLines(a, b []string) []diffLine {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var lines []diffLine
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, diffLine{op: ' ', text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, diffLine{op: '-', text: a[i]})
+			i++
+		default:
+			lines = append(lines, diffLine{op: '+', text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, diffLine{op: '-', text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, diffLine{op: '+', text: b[j]})
+	}
+	return lines
+}
@@ -0,0 +1,73 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apply
+
+import (
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/cli-runtime/pkg/resource"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+// isGenerated returns true if the object of info has a generateName and no name,
+// so that its name is generated by the api server when it is created.
+func isGenerated(info *resource.Info) bool {
+	if len(info.Name) > 0 {
+		return false
+	}
+	metadata, err := meta.Accessor(info.Object)
+	return err == nil && len(metadata.GetGenerateName()) > 0
+}
+
+// expandGenerated returns infos with o.Replicas copies of every object whose name is generated,
+// and numbers the objects sharing a generateName, so that the checkpoint tells them apart.
+func (o *ApplyOptions) expandGenerated(infos []*resource.Info) []*resource.Info {
+	o.ordinals = map[*resource.Info]int{}
+	counts := map[string]int{}
+	expanded := make([]*resource.Info, 0, len(infos))
+	for _, info := range infos {
+		if !isGenerated(info) {
+			expanded = append(expanded, info)
+			continue
+		}
+		metadata, _ := meta.Accessor(info.Object)
+		prefix := pruneKey(info.Mapping.GroupVersionKind.GroupKind(), info.Namespace, metadata.GetGenerateName())
+		for i := 0; i < o.Replicas; i++ {
+			replica := info
+			if i > 0 {
+				copied := *info
+				copied.Object = info.Object.DeepCopyObject()
+				replica = &copied
+			}
+			o.ordinals[replica] = counts[prefix]
+			counts[prefix]++
+			expanded = append(expanded, replica)
+		}
+	}
+	return expanded
+}
+
+// createGenerated creates an object whose name is generated, such an object can not be
+// applied, since it can not be found again. The generated name is set to info.
+func (o *ApplyOptions) createGenerated(info *resource.Info, helper *resource.Helper) (string, error) {
+	if o.DryRunStrategy == cmdutil.DryRunClient {
+		return OperationCreated, nil
+	}
+	obj, err := helper.Create(info.Namespace, true, info.Object)
+	if err != nil {
+		return "", addSourceToErr("creating", info.Source, err)
+	}
+	info.Refresh(obj, true)
+	return OperationCreated, nil
+}
@@ -0,0 +1,50 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apply
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/cli-runtime/pkg/resource"
+)
+
+func TestExpandGenerated(t *testing.T) {
+	generated := newTestInfo("Pod", "", "", time.Time{})
+	metadata, _ := meta.Accessor(generated.Object)
+	metadata.SetGenerateName("job-")
+	named := newTestInfo("Pod", "fixed", "", time.Time{})
+
+	o := &ApplyOptions{Replicas: 3}
+	infos := o.expandGenerated([]*resource.Info{named, generated})
+	if len(infos) != 4 || infos[0] != named {
+		t.Fatalf("expected the named pod and 3 replicas, got %d objects", len(infos))
+	}
+
+	keys := map[string]bool{}
+	for _, info := range infos[1:] {
+		if !isGenerated(info) {
+			t.Errorf("replica %s lost its generateName", info.ObjectName())
+		}
+		keys[o.checkpointKey(info)] = true
+	}
+	if len(keys) != 3 {
+		t.Errorf("replicas must have distinct checkpoint keys, got %v", keys)
+	}
+	if infos[1].Object == infos[2].Object {
+		t.Errorf("replicas must not share their object")
+	}
+}
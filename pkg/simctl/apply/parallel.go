@@ -0,0 +1,128 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apply
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+// applyTiers are applied one after another, the objects of later tiers depend on the
+// objects of earlier ones. Kinds which are not listed are applied in the last tier.
+var applyTiers = map[schema.GroupKind]int{
+	{Group: "", Kind: "Namespace"}:                                    0,
+	{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition"}: 0,
+
+	{Group: "scheduling.k8s.io", Kind: "PriorityClass"}: 1,
+	{Group: "scheduling.volcano.sh", Kind: "Queue"}:     1,
+	{Group: "", Kind: "ServiceAccount"}:                 1,
+	{Group: "", Kind: "ResourceQuota"}:                  1,
+	{Group: "", Kind: "LimitRange"}:                     1,
+	{Group: "", Kind: "Node"}:                           1,
+
+	{Group: "scheduling.volcano.sh", Kind: "PodGroup"}: 2,
+}
+
+const lastApplyTier = 3
+
+// tierInfos groups infos by their apply tier, keeping the order of the files within a tier.
+func tierInfos(infos []*resource.Info) [][]*resource.Info {
+	tiers := make([][]*resource.Info, lastApplyTier+1)
+	for _, info := range infos {
+		tier := applyTier(info)
+		tiers[tier] = append(tiers[tier], info)
+	}
+	return tiers
+}
+
+// applyTier returns the tier of info, see applyTiers.
+func applyTier(info *resource.Info) int {
+	if info.Mapping != nil {
+		if tier, ok := applyTiers[info.Mapping.GroupVersionKind.GroupKind()]; ok {
+			return tier
+		}
+	}
+	return lastApplyTier
+}
+
+// parallelize runs fn on infos with a pool of concurrency workers, and returns the errors of all objects.
+func parallelize(concurrency int, infos []*resource.Info, fn func(info *resource.Info) error) []error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if concurrency > len(infos) {
+		concurrency = len(infos)
+	}
+
+	var errs []error
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	queue := make(chan *resource.Info)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for info := range queue {
+				if err := fn(info); err != nil {
+					lock.Lock()
+					errs = append(errs, err)
+					lock.Unlock()
+				}
+			}
+		}()
+	}
+	for _, info := range infos {
+		queue <- info
+	}
+	close(queue)
+	wg.Wait()
+	return errs
+}
+
+// rateLimitedClientGetter shares a single client-side rate limiter between all clients of
+// the wrapped getter, so that the workers together send at most qps requests per second.
+type rateLimitedClientGetter struct {
+	*genericclioptions.ConfigFlags
+
+	rateLimiter flowcontrol.RateLimiter
+}
+
+func newRateLimitedClientGetter(flags *genericclioptions.ConfigFlags, qps float32, burst int) *rateLimitedClientGetter {
+	getter := &rateLimitedClientGetter{ConfigFlags: flags}
+	if qps > 0 {
+		getter.rateLimiter = flowcontrol.NewTokenBucketRateLimiter(qps, burst)
+	}
+	return getter
+}
+
+// ToRESTConfig implements RESTClientGetter.
+func (g *rateLimitedClientGetter) ToRESTConfig() (*rest.Config, error) {
+	config, err := g.ConfigFlags.ToRESTConfig()
+	if err != nil {
+		return nil, err
+	}
+	if g.rateLimiter != nil {
+		config.RateLimiter = g.rateLimiter
+	} else {
+		// a negative qps disables the default rate limiter of clients
+		config.QPS = -1
+	}
+	return config, nil
+}
@@ -0,0 +1,106 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apply
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/cli-runtime/pkg/resource"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+// prune deletes the live objects which match o.Selector but are not in infos. Namespaced
+// objects are only pruned in the namespaces of infos, and namespaces are never pruned.
+func (o *ApplyOptions) prune(infos []*resource.Info) []error {
+	visited := sets.NewString()
+	namespaces := sets.NewString()
+	for _, info := range infos {
+		visited.Insert(pruneKey(info.Mapping.GroupVersionKind.GroupKind(), info.Namespace, info.Name))
+		if info.Namespaced() {
+			namespaces.Insert(info.Namespace)
+		}
+	}
+
+	var errs []error
+	for _, gvk := range resetKinds {
+		if gvk.Kind == "Namespace" {
+			continue
+		}
+		mapping, err := o.Mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			if meta.IsNoMatchError(err) {
+				continue
+			}
+			errs = append(errs, err)
+			continue
+		}
+
+		scopes := []string{metav1.NamespaceNone}
+		if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+			scopes = namespaces.List()
+		}
+		for _, namespace := range scopes {
+			list, err := o.DynamicClient.Resource(mapping.Resource).Namespace(namespace).
+				List(context.TODO(), metav1.ListOptions{LabelSelector: o.Selector})
+			if err != nil {
+				errs = append(errs, fmt.Errorf("listing %s to prune: %v", mapping.Resource.Resource, err))
+				continue
+			}
+			for i := range list.Items {
+				obj := &list.Items[i]
+				if visited.Has(pruneKey(gvk.GroupKind(), obj.GetNamespace(), obj.GetName())) {
+					continue
+				}
+				// only prune the objects created by apply, unless fields are owned on the server
+				if _, ok := obj.GetAnnotations()[corev1.LastAppliedConfigAnnotation]; !ok && !o.ServerSideApply {
+					continue
+				}
+				if err := o.pruneObject(mapping, obj); err != nil {
+					errs = append(errs, err)
+				}
+			}
+		}
+	}
+	return errs
+}
+
+func (o *ApplyOptions) pruneObject(mapping *meta.RESTMapping, obj *unstructured.Unstructured) error {
+	if o.DryRunStrategy != cmdutil.DryRunClient {
+		policy := metav1.DeletePropagationBackground
+		options := metav1.DeleteOptions{PropagationPolicy: &policy}
+		if o.DryRunStrategy == cmdutil.DryRunServer {
+			options.DryRun = []string{metav1.DryRunAll}
+		}
+		err := o.DynamicClient.Resource(mapping.Resource).Namespace(obj.GetNamespace()).
+			Delete(context.TODO(), obj.GetName(), options)
+		if err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("pruning %s/%s: %v", mapping.Resource.GroupResource().String(), obj.GetName(), err)
+		}
+	}
+	o.results.Record(mapping.GroupVersionKind.Kind, obj, OperationPruned)
+	return nil
+}
+
+func pruneKey(gk schema.GroupKind, namespace, name string) string {
+	return fmt.Sprintf("%s/%s/%s", gk.String(), namespace, name)
+}
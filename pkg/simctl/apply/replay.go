@@ -0,0 +1,218 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apply
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/cli-runtime/pkg/resource"
+
+	"github.com/D0m021ng/scheduler-simulator/pkg/simctl/generate"
+)
+
+// maxReplayWait is the longest sleep of the replay loop, so that a
+// long wait notices a change of the pause state in time.
+const maxReplayWait = time.Second
+
+// arrival is an object which is applied at an offset from the start of the trace.
+type arrival struct {
+	info   *resource.Info
+	offset time.Duration
+}
+
+// splitArrivals splits infos into the objects which are applied right away, such as nodes and
+// queues, and the arrivals which are replayed, ordered by their offset. An object arrives at the
+// offset in its arrival annotation, or else workloads at their creationTimestamp relative to the
+// earliest one.
+func splitArrivals(infos []*resource.Info) ([]*resource.Info, []arrival, error) {
+	var upfront []*resource.Info
+	var arrivals []arrival
+	var stamped []*resource.Info
+	var first time.Time
+	for _, info := range infos {
+		accessor, err := meta.Accessor(info.Object)
+		if err != nil {
+			return nil, nil, err
+		}
+		if value, ok := accessor.GetAnnotations()[generate.ArrivalTimeAnnotationKey]; ok {
+			offset, err := time.ParseDuration(value)
+			if err != nil || offset < 0 {
+				return nil, nil, fmt.Errorf("%s: invalid %s annotation %q", info.ObjectName(), generate.ArrivalTimeAnnotationKey, value)
+			}
+			arrivals = append(arrivals, arrival{info: info, offset: offset})
+			continue
+		}
+		created := accessor.GetCreationTimestamp()
+		if created.IsZero() || applyTier(info) != lastApplyTier {
+			upfront = append(upfront, info)
+			continue
+		}
+		if first.IsZero() || created.Time.Before(first) {
+			first = created.Time
+		}
+		stamped = append(stamped, info)
+	}
+	for _, info := range stamped {
+		accessor, _ := meta.Accessor(info.Object)
+		arrivals = append(arrivals, arrival{info: info, offset: accessor.GetCreationTimestamp().Sub(first)})
+	}
+	// objects arriving together are applied in their dependency order, e.g. a podgroup before its pods
+	sort.SliceStable(arrivals, func(i, j int) bool {
+		if arrivals[i].offset != arrivals[j].offset {
+			return arrivals[i].offset < arrivals[j].offset
+		}
+		return applyTier(arrivals[i].info) < applyTier(arrivals[j].info)
+	})
+	return upfront, arrivals, nil
+}
+
+// replayClock is the time of the trace, which passes speed times faster than
+// the wall clock, and stands still while the replay is paused.
+type replayClock struct {
+	lock      sync.Mutex
+	speed     float64
+	start     time.Time
+	base      time.Duration
+	paused    bool
+	pausedAt  time.Time
+	pausedFor time.Duration
+}
+
+// newReplayClock starts the trace time at base.
+func newReplayClock(base time.Duration, speed float64) *replayClock {
+	return &replayClock{speed: speed, start: time.Now(), base: base}
+}
+
+// Now returns the current offset from the start of the trace.
+func (c *replayClock) Now() time.Duration {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.now()
+}
+
+func (c *replayClock) now() time.Duration {
+	end := time.Now()
+	if c.paused {
+		end = c.pausedAt
+	}
+	elapsed := end.Sub(c.start) - c.pausedFor
+	return c.base + time.Duration(float64(elapsed)*c.speed)
+}
+
+// Toggle pauses a running clock and resumes a paused one, it returns true if the clock is paused.
+func (c *replayClock) Toggle() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.paused {
+		c.pausedFor += time.Since(c.pausedAt)
+	} else {
+		c.pausedAt = time.Now()
+	}
+	c.paused = !c.paused
+	return c.paused
+}
+
+// Until returns the wall time until the trace reaches offset, or false if the clock is paused.
+func (c *replayClock) Until(offset time.Duration) (time.Duration, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.paused {
+		return 0, false
+	}
+	return time.Duration(float64(offset-c.now()) / c.speed), true
+}
+
+// replay applies arrivals when the trace time reaches their offsets, with up to
+// o.Concurrency objects in flight, and returns the errors of all objects. Sending
+// a pause signal (SIGUSR1) pauses the replay, sending it again resumes it.
+func (o *ApplyOptions) replay(arrivals []arrival) []error {
+	if len(arrivals) == 0 {
+		return nil
+	}
+
+	// when resuming, the arrivals before the first one which is left have been applied
+	base := time.Duration(0)
+	for _, a := range arrivals {
+		if !o.applied(a.info) {
+			base = a.offset
+			break
+		}
+	}
+	clock := newReplayClock(base, o.Speed)
+
+	toggles := make(chan os.Signal, 1)
+	if len(pauseSignals) > 0 {
+		signal.Notify(toggles, pauseSignals...)
+		defer signal.Stop(toggles)
+		o.results.Printf("Replaying %d objects at %gx speed, send %v to pause or resume (pid %d).\n",
+			len(arrivals), o.Speed, pauseSignals[0], os.Getpid())
+	}
+
+	var errs []error
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	workers := make(chan struct{}, o.Concurrency)
+	for _, a := range arrivals {
+		for {
+			wait, running := clock.Until(a.offset)
+			if running && wait <= 0 {
+				break
+			}
+			if !running || wait > maxReplayWait {
+				wait = maxReplayWait
+			}
+			select {
+			case <-toggles:
+				if clock.Toggle() {
+					o.results.Printf("Replay paused at %v.\n", clock.Now().Round(time.Second))
+				} else {
+					o.results.Printf("Replay resumed at %v.\n", clock.Now().Round(time.Second))
+				}
+			case <-time.After(wait):
+			}
+		}
+
+		workers <- struct{}{}
+		wg.Add(1)
+		go func(info *resource.Info) {
+			defer func() {
+				<-workers
+				wg.Done()
+			}()
+			if err := o.applyObject(info); err != nil {
+				lock.Lock()
+				errs = append(errs, err)
+				lock.Unlock()
+			}
+		}(a.info)
+	}
+	wg.Wait()
+	return errs
+}
+
+// applied returns true if info is recorded in the checkpoint of a resumed apply.
+func (o *ApplyOptions) applied(info *resource.Info) bool {
+	if o.checkpoint == nil {
+		return false
+	}
+	hash, err := objectHash(info.Object)
+	return err == nil && o.checkpoint.Done(o.checkpointKey(info), hash)
+}
@@ -0,0 +1,100 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apply
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/resource"
+
+	"github.com/D0m021ng/scheduler-simulator/pkg/simctl/generate"
+)
+
+func newTestInfo(kind, name, arrival string, created time.Time) *resource.Info {
+	obj := &unstructured.Unstructured{}
+	obj.SetKind(kind)
+	obj.SetName(name)
+	if len(arrival) > 0 {
+		obj.SetAnnotations(map[string]string{generate.ArrivalTimeAnnotationKey: arrival})
+	}
+	if !created.IsZero() {
+		obj.SetCreationTimestamp(metav1.NewTime(created))
+	}
+	return &resource.Info{
+		Name:   name,
+		Object: obj,
+		Mapping: &meta.RESTMapping{
+			GroupVersionKind: schema.GroupVersionKind{Version: "v1", Kind: kind},
+		},
+	}
+}
+
+func TestSplitArrivals(t *testing.T) {
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	infos := []*resource.Info{
+		newTestInfo("Node", "n1", "", start),
+		newTestInfo("Pod", "late", "1h", time.Time{}),
+		newTestInfo("Pod", "stamped", "", start.Add(10*time.Minute)),
+		newTestInfo("Pod", "first", "", start.Add(5*time.Minute)),
+		newTestInfo("Pod", "plain", "", time.Time{}),
+	}
+	upfront, arrivals, err := splitArrivals(infos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(upfront) != 2 || upfront[0].Name != "n1" || upfront[1].Name != "plain" {
+		t.Errorf("unexpected upfront objects: %v", upfront)
+	}
+	want := []struct {
+		name   string
+		offset time.Duration
+	}{{"first", 0}, {"stamped", 5 * time.Minute}, {"late", time.Hour}}
+	if len(arrivals) != len(want) {
+		t.Fatalf("got %d arrivals, want %d", len(arrivals), len(want))
+	}
+	for i, w := range want {
+		if arrivals[i].info.Name != w.name || arrivals[i].offset != w.offset {
+			t.Errorf("arrival %d = %s at %v, want %s at %v", i, arrivals[i].info.Name, arrivals[i].offset, w.name, w.offset)
+		}
+	}
+
+	if _, _, err := splitArrivals([]*resource.Info{newTestInfo("Pod", "bad", "soon", time.Time{})}); err == nil {
+		t.Errorf("expected an error for an invalid arrival annotation")
+	}
+}
+
+func TestReplayClockPause(t *testing.T) {
+	clock := newReplayClock(time.Minute, 60)
+	if !clock.Toggle() {
+		t.Fatalf("clock should be paused")
+	}
+	paused := clock.Now()
+	time.Sleep(20 * time.Millisecond)
+	if now := clock.Now(); now != paused {
+		t.Errorf("paused clock moved from %v to %v", paused, now)
+	}
+	if _, running := clock.Until(2 * time.Minute); running {
+		t.Errorf("paused clock reports running")
+	}
+	clock.Toggle()
+	if wait, running := clock.Until(2 * time.Minute); !running || wait > time.Second {
+		t.Errorf("a minute of trace at 60x should take about a second, got %v", wait)
+	}
+}
@@ -0,0 +1,209 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apply
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/cli-runtime/pkg/printers"
+)
+
+// The outcomes of waiting for a pod to be scheduled.
+const (
+	OutcomeBound         = "Bound"
+	OutcomeUnschedulable = "Unschedulable"
+	OutcomeTimedOut      = "TimedOut"
+	OutcomeMissing       = "Missing"
+)
+
+var outcomes = []string{OutcomeBound, OutcomeUnschedulable, OutcomeTimedOut, OutcomeMissing}
+
+// latencyPercentiles are the columns of the latency distribution.
+var latencyPercentiles = []float64{50, 90, 99}
+
+// schedulingReport summarizes the outcome of scheduling a set of pods.
+type schedulingReport struct {
+	// Outcomes counts the pods per outcome.
+	Outcomes map[string]int
+	// Reasons counts the pods which are not bound per reason of their latest FailedScheduling event.
+	Reasons map[string]int
+	// Latencies are the times from the creation to the scheduling of the bound pods, in ascending order.
+	Latencies []time.Duration
+}
+
+// newSchedulingReport builds the report of pods, with the FailedScheduling messages of events.
+func newSchedulingReport(pods map[types.NamespacedName]*corev1.Pod, events map[types.NamespacedName]string) *schedulingReport {
+	report := &schedulingReport{Outcomes: map[string]int{}, Reasons: map[string]int{}}
+	for name, pod := range pods {
+		outcome := podOutcome(pod)
+		if outcome == "" {
+			outcome = OutcomeTimedOut
+		}
+		report.Outcomes[outcome]++
+		if outcome == OutcomeBound {
+			if latency, ok := schedulingLatency(pod); ok {
+				report.Latencies = append(report.Latencies, latency)
+			}
+			continue
+		}
+		for _, reason := range failedSchedulingReasons(events[name]) {
+			report.Reasons[reason]++
+		}
+	}
+	sort.Slice(report.Latencies, func(i, j int) bool { return report.Latencies[i] < report.Latencies[j] })
+	return report
+}
+
+// podOutcome returns whether pod is bound, unschedulable or missing, or "" if it is still pending.
+func podOutcome(pod *corev1.Pod) string {
+	if pod == nil {
+		return OutcomeMissing
+	}
+	if len(pod.Spec.NodeName) > 0 {
+		return OutcomeBound
+	}
+	if condition := podScheduledCondition(pod); condition != nil &&
+		condition.Status == corev1.ConditionFalse && condition.Reason == corev1.PodReasonUnschedulable {
+		return OutcomeUnschedulable
+	}
+	return ""
+}
+
+func podScheduledCondition(pod *corev1.Pod) *corev1.PodCondition {
+	for i := range pod.Status.Conditions {
+		if pod.Status.Conditions[i].Type == corev1.PodScheduled {
+			return &pod.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// schedulingLatency returns the time from the creation of pod until it was scheduled.
+func schedulingLatency(pod *corev1.Pod) (time.Duration, bool) {
+	condition := podScheduledCondition(pod)
+	if condition == nil || condition.Status != corev1.ConditionTrue || condition.LastTransitionTime.IsZero() {
+		return 0, false
+	}
+	latency := condition.LastTransitionTime.Sub(pod.CreationTimestamp.Time)
+	if latency < 0 {
+		latency = 0
+	}
+	return latency, true
+}
+
+// failedSchedulingReasons splits the message of a FailedScheduling event, such as
+// "0/3 nodes are available: 1 node(s) were unschedulable, 2 Insufficient cpu.",
+// into its reasons without the node counts. Other messages are a single reason.
+func failedSchedulingReasons(message string) []string {
+	message = strings.TrimSpace(message)
+	if len(message) == 0 {
+		return nil
+	}
+	i := strings.Index(message, "nodes are available: ")
+	if i < 0 {
+		return []string{message}
+	}
+	message = strings.TrimSuffix(message[i+len("nodes are available: "):], ".")
+
+	// a reason may contain ", " itself, only a part starting with a count starts a new reason
+	var reasons []string
+	for _, part := range strings.Split(message, ", ") {
+		if reason, ok := trimCount(part); ok || len(reasons) == 0 {
+			reasons = append(reasons, reason)
+			continue
+		}
+		reasons[len(reasons)-1] += ", " + part
+	}
+	return reasons
+}
+
+// trimCount strips the leading node count from part, it returns false if part has none.
+func trimCount(part string) (string, bool) {
+	i := strings.IndexFunc(part, func(r rune) bool { return !unicode.IsDigit(r) })
+	if i <= 0 || part[i] != ' ' {
+		return part, false
+	}
+	return part[i+1:], true
+}
+
+// percentile returns the p-th percentile of the ascending durations, by the nearest rank.
+func percentile(durations []time.Duration, p float64) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p/100*float64(len(durations)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(durations) {
+		rank = len(durations) - 1
+	}
+	return durations[rank]
+}
+
+// Print writes the tables of outcomes, reasons and latencies.
+func (r *schedulingReport) Print(out io.Writer) error {
+	w := printers.GetNewTabWriter(out)
+	fmt.Fprintln(w, "OUTCOME\tPODS")
+	for _, outcome := range outcomes {
+		if count, ok := r.Outcomes[outcome]; ok {
+			fmt.Fprintf(w, "%s\t%d\n", outcome, count)
+		}
+	}
+
+	if len(r.Reasons) > 0 {
+		reasons := make([]string, 0, len(r.Reasons))
+		for reason := range r.Reasons {
+			reasons = append(reasons, reason)
+		}
+		// the most frequent reasons first
+		sort.Slice(reasons, func(i, j int) bool {
+			if r.Reasons[reasons[i]] != r.Reasons[reasons[j]] {
+				return r.Reasons[reasons[i]] > r.Reasons[reasons[j]]
+			}
+			return reasons[i] < reasons[j]
+		})
+		fmt.Fprintln(w, "\nFAILED SCHEDULING REASON\tPODS")
+		for _, reason := range reasons {
+			fmt.Fprintf(w, "%s\t%d\n", reason, r.Reasons[reason])
+		}
+	}
+
+	if len(r.Latencies) > 0 {
+		var sum time.Duration
+		for _, latency := range r.Latencies {
+			sum += latency
+		}
+		fmt.Fprint(w, "\nLATENCY\tMIN\tMEAN")
+		for _, p := range latencyPercentiles {
+			fmt.Fprintf(w, "\tP%g", p)
+		}
+		fmt.Fprint(w, "\tMAX\n")
+		fmt.Fprintf(w, "scheduling\t%v\t%v", r.Latencies[0], (sum / time.Duration(len(r.Latencies))).Round(time.Millisecond))
+		for _, p := range latencyPercentiles {
+			fmt.Fprintf(w, "\t%v", percentile(r.Latencies, p))
+		}
+		fmt.Fprintf(w, "\t%v\n", r.Latencies[len(r.Latencies)-1])
+	}
+	return w.Flush()
+}
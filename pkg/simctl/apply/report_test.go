@@ -0,0 +1,95 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apply
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestFailedSchedulingReasons(t *testing.T) {
+	tests := []struct {
+		message string
+		want    []string
+	}{
+		{"", nil},
+		{"0/3 nodes are available: 1 node(s) were unschedulable, 2 Insufficient cpu.",
+			[]string{"node(s) were unschedulable", "Insufficient cpu"}},
+		{"0/5 nodes are available: 2 node(s) had taint {role: infra}, that the pod didn't tolerate, 3 Insufficient memory.",
+			[]string{"node(s) had taint {role: infra}, that the pod didn't tolerate", "Insufficient memory"}},
+		{"pod has unbound immediate PersistentVolumeClaims",
+			[]string{"pod has unbound immediate PersistentVolumeClaims"}},
+	}
+	for _, test := range tests {
+		if got := failedSchedulingReasons(test.message); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("failedSchedulingReasons(%q) = %q, want %q", test.message, got, test.want)
+		}
+	}
+}
+
+func TestSchedulingReport(t *testing.T) {
+	created := metav1.NewTime(time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC))
+	bound := func(latency time.Duration) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{CreationTimestamp: created},
+			Spec:       corev1.PodSpec{NodeName: "n1"},
+			Status: corev1.PodStatus{Conditions: []corev1.PodCondition{{
+				Type: corev1.PodScheduled, Status: corev1.ConditionTrue,
+				LastTransitionTime: metav1.NewTime(created.Add(latency)),
+			}}},
+		}
+	}
+	unschedulable := &corev1.Pod{Status: corev1.PodStatus{Conditions: []corev1.PodCondition{{
+		Type: corev1.PodScheduled, Status: corev1.ConditionFalse, Reason: corev1.PodReasonUnschedulable,
+	}}}}
+
+	pods := map[types.NamespacedName]*corev1.Pod{
+		{Name: "b1"}: bound(3 * time.Second),
+		{Name: "b2"}: bound(time.Second),
+		{Name: "b3"}: bound(2 * time.Second),
+		{Name: "u1"}: unschedulable,
+		{Name: "p1"}: {},
+		{Name: "m1"}: nil,
+	}
+	events := map[types.NamespacedName]string{
+		{Name: "u1"}: "0/1 nodes are available: 1 Insufficient cpu.",
+		{Name: "p1"}: "0/1 nodes are available: 1 Insufficient cpu.",
+	}
+	report := newSchedulingReport(pods, events)
+
+	wantOutcomes := map[string]int{OutcomeBound: 3, OutcomeUnschedulable: 1, OutcomeTimedOut: 1, OutcomeMissing: 1}
+	if !reflect.DeepEqual(report.Outcomes, wantOutcomes) {
+		t.Errorf("outcomes = %v, want %v", report.Outcomes, wantOutcomes)
+	}
+	if report.Reasons["Insufficient cpu"] != 2 {
+		t.Errorf("reasons = %v, want 2 pods with Insufficient cpu", report.Reasons)
+	}
+	wantLatencies := []time.Duration{time.Second, 2 * time.Second, 3 * time.Second}
+	if !reflect.DeepEqual(report.Latencies, wantLatencies) {
+		t.Errorf("latencies = %v, want %v", report.Latencies, wantLatencies)
+	}
+	if p := percentile(report.Latencies, 50); p != 2*time.Second {
+		t.Errorf("p50 = %v, want 2s", p)
+	}
+	// the nearest rank of p40 of 3 latencies is the ceiling of 1.2
+	if p := percentile(report.Latencies, 40); p != 2*time.Second {
+		t.Errorf("p40 = %v, want 2s", p)
+	}
+}
@@ -0,0 +1,301 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apply
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/printers"
+	"k8s.io/cli-runtime/pkg/resource"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/scheme"
+	"k8s.io/kubectl/pkg/util/term"
+)
+
+// The operations done to applied objects.
+const (
+	OperationCreated           = "created"
+	OperationConfigured        = "configured"
+	OperationUnchanged         = "unchanged"
+	OperationServerSideApplied = "serverside-applied"
+	OperationPruned            = "pruned"
+	OperationSkipped           = "skipped"
+	OperationFailed            = "failed"
+)
+
+// summaryOperations are the columns of the summary table.
+var summaryOperations = []string{OperationCreated, OperationConfigured, OperationUnchanged,
+	OperationServerSideApplied, OperationPruned, OperationSkipped, OperationFailed}
+
+const (
+	progressBarWidth    = 30
+	progressRefreshRate = 200 * time.Millisecond
+)
+
+// applyResults collects the result of every object, prints it as it arrives,
+// and keeps the counts per kind and operation for the summary.
+type applyResults struct {
+	lock sync.Mutex
+
+	// outputFormat is one of "", name, json or yaml.
+	outputFormat   string
+	dryRunStrategy cmdutil.DryRunStrategy
+	out, errOut    io.Writer
+
+	counts   map[string]map[string]int
+	objects  []runtime.Object
+	progress *progressBar
+}
+
+func newApplyResults(outputFormat string, dryRunStrategy cmdutil.DryRunStrategy, out, errOut io.Writer) *applyResults {
+	return &applyResults{
+		outputFormat:   outputFormat,
+		dryRunStrategy: dryRunStrategy,
+		out:            out,
+		errOut:         errOut,
+		counts:         map[string]map[string]int{},
+	}
+}
+
+// startProgress shows a live progress bar of total objects, if the error output is a terminal.
+func (r *applyResults) startProgress(total int) {
+	if !term.IsTerminal(r.errOut) {
+		return
+	}
+	r.progress = newProgressBar(r.errOut, total, &r.lock)
+	r.progress.start()
+}
+
+// Record prints the result of applying obj, and counts it for the summary.
+func (r *applyResults) Record(kind string, obj runtime.Object, operation string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.counts[kind] == nil {
+		r.counts[kind] = map[string]int{}
+	}
+	r.counts[kind][operation]++
+	if r.progress != nil {
+		r.progress.add(operation == OperationFailed)
+		r.progress.clear()
+	}
+
+	switch r.outputFormat {
+	case "json", "yaml":
+		if operation != OperationFailed && operation != OperationPruned {
+			r.objects = append(r.objects, obj)
+		}
+	default:
+		out, suffix := r.out, dryRunSuffix(r.dryRunStrategy)
+		if operation == OperationFailed {
+			// the error itself is reported by the aggregate of Run
+			out, suffix = r.errOut, ""
+		}
+		printer := &printers.NamePrinter{
+			Operation:   operation + suffix,
+			ShortOutput: r.outputFormat == "name",
+		}
+		if err := printers.NewTypeSetter(scheme.Scheme).ToPrinter(printer).PrintObj(obj, out); err != nil {
+			fmt.Fprintf(r.errOut, "error printing %s: %v\n", operation, err)
+		}
+	}
+}
+
+// Printf prints a message to the error output, without garbling the progress bar.
+func (r *applyResults) Printf(format string, args ...interface{}) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.progress != nil {
+		r.progress.clear()
+	}
+	fmt.Fprintf(r.errOut, format, args...)
+}
+
+// RecordInfo records the result of an info, with its kind taken from the mapping.
+func (r *applyResults) RecordInfo(info *resource.Info, operation string) {
+	r.Record(info.Mapping.GroupVersionKind.Kind, info.Object, operation)
+}
+
+// Flush stops the progress bar, prints the objects in json or yaml, and the summary table.
+func (r *applyResults) Flush() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.progress != nil {
+		r.progress.stop()
+	}
+
+	summaryOut := r.out
+	switch r.outputFormat {
+	case "json", "yaml":
+		list := &unstructured.UnstructuredList{Object: map[string]interface{}{"kind": "List", "apiVersion": "v1"}}
+		for _, obj := range r.objects {
+			if u, ok := obj.(*unstructured.Unstructured); ok {
+				list.Items = append(list.Items, *u)
+			}
+		}
+		var printer printers.ResourcePrinter = &printers.JSONPrinter{}
+		if r.outputFormat == "yaml" {
+			printer = &printers.YAMLPrinter{}
+		}
+		if err := printer.PrintObj(list, r.out); err != nil {
+			return err
+		}
+		// keep the output parseable
+		summaryOut = r.errOut
+	case "name":
+		return nil
+	}
+	return r.printSummary(summaryOut)
+}
+
+func (r *applyResults) printSummary(out io.Writer) error {
+	if len(r.counts) == 0 {
+		return nil
+	}
+	kinds := make([]string, 0, len(r.counts))
+	for kind := range r.counts {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+
+	// only show the operations which happened
+	var columns []string
+	for _, operation := range summaryOperations {
+		for _, kind := range kinds {
+			if r.counts[kind][operation] > 0 {
+				columns = append(columns, operation)
+				break
+			}
+		}
+	}
+
+	w := printers.GetNewTabWriter(out)
+	fmt.Fprintf(w, "\nKIND\t%s\tTOTAL\n", strings.ToUpper(strings.Join(columns, "\t")))
+	for _, kind := range kinds {
+		total := 0
+		fmt.Fprintf(w, "%s", kind)
+		for _, operation := range columns {
+			fmt.Fprintf(w, "\t%d", r.counts[kind][operation])
+			total += r.counts[kind][operation]
+		}
+		fmt.Fprintf(w, "\t%d\n", total)
+	}
+	return w.Flush()
+}
+
+// progressBar draws the count of done objects, the rate and the estimated remaining
+// time on a single terminal line, which is redrawn periodically.
+type progressBar struct {
+	out   io.Writer
+	lock  *sync.Mutex
+	total int
+	done  int
+	fail  int
+	begin time.Time
+
+	stopCh chan struct{}
+	drawn  bool
+}
+
+func newProgressBar(out io.Writer, total int, lock *sync.Mutex) *progressBar {
+	return &progressBar{
+		out:    out,
+		lock:   lock,
+		total:  total,
+		begin:  time.Now(),
+		stopCh: make(chan struct{}),
+	}
+}
+
+func (p *progressBar) start() {
+	go func() {
+		ticker := time.NewTicker(progressRefreshRate)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.stopCh:
+				return
+			case <-ticker.C:
+				p.lock.Lock()
+				p.draw()
+				p.lock.Unlock()
+			}
+		}
+	}()
+}
+
+// add counts a done object, the caller must hold the lock. Objects beyond the total, such as
+// those pruned after apply, extend it.
+func (p *progressBar) add(failed bool) {
+	p.done++
+	if p.done > p.total {
+		p.total = p.done
+	}
+	if failed {
+		p.fail++
+	}
+}
+
+// clear erases the bar, so that a result line can be printed, the caller must hold the lock.
+func (p *progressBar) clear() {
+	if p.drawn {
+		fmt.Fprint(p.out, "\r\033[K")
+		p.drawn = false
+	}
+}
+
+// stop draws the final state of the bar, the caller must hold the lock.
+func (p *progressBar) stop() {
+	close(p.stopCh)
+	p.draw()
+	fmt.Fprintln(p.out)
+}
+
+func (p *progressBar) draw() {
+	fmt.Fprintf(p.out, "\r\033[K%s", p.String())
+	p.drawn = true
+}
+
+func (p *progressBar) String() string {
+	ratio := 1.0
+	if p.total > 0 && p.done < p.total {
+		ratio = float64(p.done) / float64(p.total)
+	}
+	filled := int(ratio * progressBarWidth)
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", progressBarWidth-filled)
+	if filled > 0 && filled < progressBarWidth {
+		bar = bar[:filled-1] + ">" + bar[filled:]
+	}
+
+	elapsed := time.Since(p.begin)
+	rate := 0.0
+	if elapsed > 0 {
+		rate = float64(p.done) / elapsed.Seconds()
+	}
+	eta := "-"
+	if rate > 0 && p.done <= p.total {
+		eta = (time.Duration(float64(p.total-p.done)/rate) * time.Second).Round(time.Second).String()
+	}
+	return fmt.Sprintf("[%s] %d/%d %5.1f%% %.1f/s ETA %s failed %d",
+		bar, p.done, p.total, ratio*100, rate, eta, p.fail)
+}
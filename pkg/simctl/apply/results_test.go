@@ -0,0 +1,38 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apply
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestProgressBarBeyondTotal(t *testing.T) {
+	bar := newProgressBar(&bytes.Buffer{}, 2, &sync.Mutex{})
+	// the objects pruned after apply are counted beyond the total of applied objects
+	for i := 0; i < 5; i++ {
+		bar.add(false)
+	}
+	if got := bar.String(); !strings.Contains(got, "5/5 100.0%") {
+		t.Errorf("got %q after 5 of 2 objects", got)
+	}
+
+	bar.total = 2
+	if got := bar.String(); !strings.HasPrefix(got, "["+strings.Repeat("=", progressBarWidth)+"]") {
+		t.Errorf("got %q with done above total", got)
+	}
+}
@@ -0,0 +1,114 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apply
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// maxRetryBackoff caps the exponential backoff between two attempts.
+	maxRetryBackoff = 30 * time.Second
+	// retryJitter randomizes the backoff, so that workers do not retry in lockstep.
+	retryJitter = 0.2
+)
+
+// applyWithRetries applies info, and retries the errors which are likely transient
+// with an exponential backoff, up to o.Retries times.
+func (o *ApplyOptions) applyWithRetries(info *resource.Info) (string, error) {
+	// applying replaces the object of info with the live one, so every
+	// attempt must start from the object in the file again.
+	original := info.Object.DeepCopyObject()
+	resourceVersion := info.ResourceVersion
+
+	backoff := o.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		operation, err := o.applyInfo(info)
+		if err == nil || attempt >= o.Retries || !isRetryable(err) {
+			return operation, err
+		}
+
+		delay := wait.Jitter(backoff, retryJitter)
+		if seconds, ok := apierrors.SuggestsClientDelay(err); ok && time.Duration(seconds)*time.Second > delay {
+			delay = time.Duration(seconds) * time.Second
+		}
+		klog.V(2).Infof("retrying %s in %v after attempt %d failed: %v", info.ObjectName(), delay, attempt+1, err)
+		time.Sleep(delay)
+
+		if backoff *= 2; backoff > maxRetryBackoff {
+			backoff = maxRetryBackoff
+		}
+		info.Object = original.DeepCopyObject()
+		info.ResourceVersion = resourceVersion
+		if len(info.Name) > 0 {
+			// the object was created with a generated name, it must not be created again
+			if metadata, err := meta.Accessor(info.Object); err == nil {
+				metadata.SetName(info.Name)
+			}
+		}
+	}
+}
+
+// isRetryable returns true if err is likely to go away by retrying the same request:
+// throttling, server errors, conflicts with concurrent writers and network failures.
+func isRetryable(err error) bool {
+	switch {
+	case apierrors.IsTooManyRequests(err),
+		apierrors.IsServerTimeout(err),
+		apierrors.IsTimeout(err),
+		apierrors.IsInternalError(err),
+		apierrors.IsServiceUnavailable(err),
+		apierrors.IsConflict(err),
+		apierrors.IsUnexpectedServerError(err):
+		return true
+	case utilnet.IsTimeout(err),
+		utilnet.IsProbableEOF(err),
+		utilnet.IsConnectionReset(err),
+		utilnet.IsConnectionRefused(err),
+		errors.Is(err, io.EOF),
+		errors.Is(err, io.ErrUnexpectedEOF):
+		return true
+	}
+	var status apierrors.APIStatus
+	if errors.As(err, &status) {
+		return status.Status().Code >= http.StatusInternalServerError
+	}
+	return false
+}
+
+// addSourceToErr adds the source of the object to err like cmdutil.AddSourceToErr,
+// but keeps errors other than api errors wrapped, so that isRetryable can inspect them.
+func addSourceToErr(verb string, source string, err error) error {
+	if source == "" {
+		return err
+	}
+	if statusError, ok := err.(apierrors.APIStatus); ok {
+		status := statusError.Status()
+		status.Message = fmt.Sprintf("error when %s %q: %v", verb, source, status.Message)
+		return &apierrors.StatusError{ErrStatus: status}
+	}
+	return fmt.Errorf("error when %s %q: %w", verb, source, err)
+}
@@ -0,0 +1,84 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apply
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestIsRetryable(t *testing.T) {
+	gr := schema.GroupResource{Resource: "pods"}
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{apierrors.NewTooManyRequests("slow down", 1), true},
+		{apierrors.NewInternalError(fmt.Errorf("etcd")), true},
+		{apierrors.NewServiceUnavailable("restarting"), true},
+		{apierrors.NewConflict(gr, "p", fmt.Errorf("modified")), true},
+		{apierrors.NewServerTimeout(gr, "create", 1), true},
+		{apierrors.NewGenericServerResponse(502, "create", gr, "p", "", 0, false), true},
+		{addSourceToErr("creating", "pods.yaml", io.ErrUnexpectedEOF), true},
+		{apierrors.NewNotFound(gr, "p"), false},
+		{apierrors.NewInvalid(schema.GroupKind{Kind: "Pod"}, "p", nil), false},
+		{addSourceToErr("creating", "pods.yaml", apierrors.NewForbidden(gr, "p", fmt.Errorf("denied"))), false},
+	}
+	for _, test := range tests {
+		if got := isRetryable(test.err); got != test.want {
+			t.Errorf("isRetryable(%v) = %v, want %v", test.err, got, test.want)
+		}
+	}
+}
+
+func TestCheckpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "apply.checkpoint")
+	c, err := openCheckpoint(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Add("Pod/default/p1", "h1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Add("Node//n1", "h2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Close(false); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err = openCheckpoint(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.Done("Pod/default/p1", "h1") || !c.Done("Node//n1", "h2") {
+		t.Errorf("resumed checkpoint is missing objects: %v", c.done)
+	}
+	if c.Done("Pod/default/p1", "changed") {
+		t.Errorf("changed object must be applied again")
+	}
+	if err := c.Close(true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("completed checkpoint was not removed: %v", err)
+	}
+}
@@ -0,0 +1,372 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apply
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/klog/v2"
+	"k8s.io/kubectl/pkg/scheme"
+	kubectlvalidation "k8s.io/kubectl/pkg/validation"
+	"sigs.k8s.io/yaml"
+)
+
+// The directives of the --validate flag.
+const (
+	ValidationStrict = "strict"
+	ValidationWarn   = "warn"
+	ValidationIgnore = "ignore"
+)
+
+// parseValidationDirective parses the --validate flag, true and false are kept for kubectl compatibility.
+func parseValidationDirective(directive string) (string, error) {
+	switch strings.ToLower(directive) {
+	case ValidationStrict, "true":
+		return ValidationStrict, nil
+	case ValidationWarn:
+		return ValidationWarn, nil
+	case ValidationIgnore, "false":
+		return ValidationIgnore, nil
+	}
+	return "", fmt.Errorf("invalid --validate value %q, must be one of %s, %s or %s",
+		directive, ValidationStrict, ValidationWarn, ValidationIgnore)
+}
+
+// objectValidator validates documents offline against the built-in types and the volcano
+// CRDs, and against the openapi schema of the target server if it is available.
+type objectValidator struct {
+	openapi kubectlvalidation.Schema
+}
+
+var _ kubectlvalidation.Schema = &objectValidator{}
+
+// ValidateBytes implements validation.Schema.
+func (v *objectValidator) ValidateBytes(data []byte) error {
+	errs := validateOffline(data)
+	if v.openapi != nil {
+		if err := v.openapi.ValidateBytes(data); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// validateFiles validates every document of the local files to apply, and reports all
+// problems with the file and index of their document before anything is sent.
+// Remote files and stdin are validated by the builder instead.
+func (o *ApplyOptions) validateFiles() error {
+	if o.ValidationDirective == ValidationIgnore || o.Validator == nil {
+		return nil
+	}
+	filenames, err := expandFilenames(o.FilenameOptions.Filenames, o.FilenameOptions.Recursive)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, filename := range filenames {
+		errs = append(errs, validateFile(filename, o.Validator)...)
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	if o.ValidationDirective == ValidationWarn {
+		for _, err := range errs {
+			fmt.Fprintf(o.ErrOut, "Warning: %v\n", err)
+		}
+		return nil
+	}
+	return fmt.Errorf("validation failed, nothing is applied (use --validate=warn or --validate=ignore to skip): %v",
+		utilerrors.NewAggregate(errs))
+}
+
+// builderSchema returns the schema which the builder checks, it only rejects objects in strict mode.
+func (o *ApplyOptions) builderSchema() kubectlvalidation.Schema {
+	if o.ValidationDirective != ValidationStrict || o.Validator == nil {
+		return kubectlvalidation.NullSchema{}
+	}
+	return o.Validator
+}
+
+func validateFile(filename string, validator kubectlvalidation.Schema) []error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return []error{err}
+	}
+	defer f.Close()
+
+	var errs []error
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(f))
+	for idx := 0; ; idx++ {
+		doc, err := reader.Read()
+		if err != nil {
+			if err != io.EOF {
+				errs = append(errs, fmt.Errorf("%s: document %d: %v", filename, idx, err))
+			}
+			break
+		}
+		if len(bytes.TrimSpace(doc)) == 0 || isCommentOnly(doc) {
+			continue
+		}
+		if err := validator.ValidateBytes(doc); err != nil {
+			errs = append(errs, fmt.Errorf("%s: document %d%s: %v", filename, idx, describeDocument(doc), err))
+		}
+	}
+	return errs
+}
+
+// expandFilenames returns the local files to apply, with the files of directories expanded
+// the same way as the builder does. Stdin and urls are left out.
+func expandFilenames(paths []string, recursive bool) ([]string, error) {
+	var filenames []string
+	for _, path := range paths {
+		if path == "-" || strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+			continue
+		}
+		fi, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !fi.IsDir() {
+			filenames = append(filenames, path)
+			continue
+		}
+		err = filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				if p != path && !recursive {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			switch filepath.Ext(p) {
+			case ".json", ".yaml", ".yml":
+				filenames = append(filenames, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return filenames, nil
+}
+
+func isCommentOnly(doc []byte) bool {
+	for _, line := range strings.Split(string(doc), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") && line != "---" {
+			return false
+		}
+	}
+	return true
+}
+
+// describeDocument names the object of a document for error messages, if it can be parsed.
+func describeDocument(doc []byte) string {
+	obj := struct {
+		Kind     string            `json:"kind"`
+		Metadata metav1.ObjectMeta `json:"metadata"`
+	}{}
+	if err := yaml.Unmarshal(doc, &obj); err != nil || obj.Kind == "" {
+		return ""
+	}
+	name := obj.Metadata.Name
+	if name == "" {
+		name = obj.Metadata.GenerateName
+	}
+	if obj.Metadata.Namespace != "" {
+		name = obj.Metadata.Namespace + "/" + name
+	}
+	return fmt.Sprintf(" (%s %s)", obj.Kind, name)
+}
+
+// validateOffline checks a yaml or json document against the types compiled into simctl.
+func validateOffline(doc []byte) []error {
+	data, err := yaml.YAMLToJSON(doc)
+	if err != nil {
+		return []error{err}
+	}
+	typeMeta := metav1.TypeMeta{}
+	if err := json.Unmarshal(data, &typeMeta); err != nil {
+		return []error{err}
+	}
+	if typeMeta.APIVersion == "" {
+		return []error{fmt.Errorf("apiVersion not set")}
+	}
+	if typeMeta.Kind == "" {
+		return []error{fmt.Errorf("kind not set")}
+	}
+	gv, err := schema.ParseGroupVersion(typeMeta.APIVersion)
+	if err != nil {
+		return []error{err}
+	}
+	gvk := gv.WithKind(typeMeta.Kind)
+
+	if validate, ok := volcanoValidators[gvk]; ok {
+		return validate(data)
+	}
+	obj, err := scheme.Scheme.New(gvk)
+	if err != nil {
+		if runtime.IsNotRegisteredError(err) {
+			klog.V(2).Infof("skip offline validation of %s, which is not a built-in kind", gvk)
+			return nil
+		}
+		return []error{err}
+	}
+	if err := strictUnmarshal(data, obj); err != nil {
+		return []error{err}
+	}
+	accessor, ok := obj.(metav1.Object)
+	if !ok {
+		return nil
+	}
+	return validateObjectMeta(accessor, gvk.Kind == "Namespace")
+}
+
+func strictUnmarshal(data []byte, into interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(into)
+}
+
+func validateObjectMeta(obj metav1.Object, isNamespace bool) []error {
+	var errs []error
+	name := obj.GetName()
+	switch {
+	case name == "" && obj.GetGenerateName() == "":
+		errs = append(errs, fmt.Errorf("metadata.name or metadata.generateName is required"))
+	case name != "" && isNamespace:
+		for _, msg := range validation.IsDNS1123Label(name) {
+			errs = append(errs, fmt.Errorf("metadata.name %q: %s", name, msg))
+		}
+	case name != "":
+		for _, msg := range validation.IsDNS1123Subdomain(name) {
+			errs = append(errs, fmt.Errorf("metadata.name %q: %s", name, msg))
+		}
+	}
+	if ns := obj.GetNamespace(); ns != "" {
+		for _, msg := range validation.IsDNS1123Label(ns) {
+			errs = append(errs, fmt.Errorf("metadata.namespace %q: %s", ns, msg))
+		}
+	}
+	keys := make([]string, 0, len(obj.GetLabels()))
+	for key := range obj.GetLabels() {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, msg := range validation.IsQualifiedName(key) {
+			errs = append(errs, fmt.Errorf("metadata.labels %q: %s", key, msg))
+		}
+		for _, msg := range validation.IsValidLabelValue(obj.GetLabels()[key]) {
+			errs = append(errs, fmt.Errorf("metadata.labels %q: %s", key, msg))
+		}
+	}
+	return errs
+}
+
+// volcanoValidators validate the kinds of the volcano CRDs, which are not compiled into simctl.
+var volcanoValidators = map[schema.GroupVersionKind]func(data []byte) []error{
+	{Group: "scheduling.volcano.sh", Version: "v1beta1", Kind: "Queue"}:    validateQueue,
+	{Group: "scheduling.volcano.sh", Version: "v1beta1", Kind: "PodGroup"}: validatePodGroup,
+}
+
+type volcanoObject struct {
+	metav1.TypeMeta `json:",inline"`
+	Metadata        metav1.ObjectMeta `json:"metadata"`
+	Spec            json.RawMessage   `json:"spec,omitempty"`
+	Status          json.RawMessage   `json:"status,omitempty"`
+}
+
+type queueSpec struct {
+	Weight         *int32                                  `json:"weight,omitempty"`
+	Capability     map[string]resource.Quantity            `json:"capability,omitempty"`
+	Reclaimable    *bool                                   `json:"reclaimable,omitempty"`
+	Guarantee      map[string]map[string]resource.Quantity `json:"guarantee,omitempty"`
+	Deserved       map[string]resource.Quantity            `json:"deserved,omitempty"`
+	Parent         string                                  `json:"parent,omitempty"`
+	Affinity       json.RawMessage                         `json:"affinity,omitempty"`
+	Type           string                                  `json:"type,omitempty"`
+	ExtendClusters json.RawMessage                         `json:"extendClusters,omitempty"`
+}
+
+type podGroupSpec struct {
+	MinMember         *int32                       `json:"minMember,omitempty"`
+	MinTaskMember     map[string]int32             `json:"minTaskMember,omitempty"`
+	Queue             string                       `json:"queue,omitempty"`
+	PriorityClassName string                       `json:"priorityClassName,omitempty"`
+	MinResources      map[string]resource.Quantity `json:"minResources,omitempty"`
+}
+
+func decodeVolcanoObject(data []byte, spec interface{}) (*volcanoObject, []error) {
+	obj := &volcanoObject{}
+	if err := strictUnmarshal(data, obj); err != nil {
+		return nil, []error{err}
+	}
+	errs := validateObjectMeta(&obj.Metadata, false)
+	if len(obj.Spec) > 0 {
+		if err := strictUnmarshal(obj.Spec, spec); err != nil {
+			errs = append(errs, fmt.Errorf("spec: %v", err))
+		}
+	}
+	return obj, errs
+}
+
+func validateQueue(data []byte) []error {
+	spec := &queueSpec{}
+	obj, errs := decodeVolcanoObject(data, spec)
+	if obj == nil {
+		return errs
+	}
+	if obj.Metadata.Namespace != "" {
+		errs = append(errs, fmt.Errorf("queues are cluster scoped, metadata.namespace must be empty"))
+	}
+	if spec.Weight != nil && *spec.Weight < 1 {
+		errs = append(errs, fmt.Errorf("spec.weight must be at least 1, got %d", *spec.Weight))
+	}
+	return errs
+}
+
+func validatePodGroup(data []byte) []error {
+	spec := &podGroupSpec{}
+	_, errs := decodeVolcanoObject(data, spec)
+	if spec.MinMember != nil && *spec.MinMember < 0 {
+		errs = append(errs, fmt.Errorf("spec.minMember must not be negative, got %d", *spec.MinMember))
+	}
+	for task, count := range spec.MinTaskMember {
+		if count < 0 {
+			errs = append(errs, fmt.Errorf("spec.minTaskMember[%s] must not be negative, got %d", task, count))
+		}
+	}
+	return errs
+}
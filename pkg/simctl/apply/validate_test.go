@@ -0,0 +1,96 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apply
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateFile(t *testing.T) {
+	data := `apiVersion: v1
+kind: Pod
+metadata:
+  name: valid-pod
+spec:
+  containers:
+  - name: c
+    image: nginx
+    resources:
+      requests:
+        cpu: "2"
+---
+# only a comment
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: Invalid_Pod
+spec:
+  containers:
+  - name: c
+    image: nginx
+    resource: {}
+---
+apiVersion: scheduling.volcano.sh/v1beta1
+kind: PodGroup
+metadata:
+  name: pg
+  namespace: default
+spec:
+  minMember: -1
+  queue: default
+---
+apiVersion: scheduling.volcano.sh/v1beta1
+kind: Queue
+metadata:
+  name: q
+spec:
+  weight: 1
+  capability:
+    cpu: 10x
+---
+apiVersion: example.com/v1
+kind: Unknown
+metadata:
+  name: anything
+spec:
+  whatever: true
+`
+	filename := filepath.Join(t.TempDir(), "data.yaml")
+	if err := os.WriteFile(filename, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	errs := validateFile(filename, &objectValidator{})
+	if len(errs) != 3 {
+		t.Fatalf("expected 3 invalid documents, got %d: %v", len(errs), errs)
+	}
+	expected := []string{
+		"document 2 (Pod Invalid_Pod)",
+		"document 3 (PodGroup default/pg)",
+		"document 4 (Queue q)",
+	}
+	for idx, err := range errs {
+		if !strings.Contains(err.Error(), filename+": "+expected[idx]) {
+			t.Errorf("expected error of %s, got %v", expected[idx], err)
+		}
+	}
+	if !strings.Contains(errs[0].Error(), `unknown field "resource"`) {
+		t.Errorf("expected unknown field to be reported, got %v", errs[0])
+	}
+}
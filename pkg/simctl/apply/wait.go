@@ -0,0 +1,323 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apply
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	utilpointer "k8s.io/utils/pointer"
+
+	"github.com/D0m021ng/scheduler-simulator/pkg/simctl/generate"
+)
+
+const defaultWaitTimeout = 5 * time.Minute
+
+// WaitFlags directly reflect the information that CLI is gathering via flags.
+type WaitFlags struct {
+	FileNameFlags   *genericclioptions.FileNameFlags
+	KubeConfigFlags *genericclioptions.ConfigFlags
+
+	LabelSelector string
+	AllNamespaces bool
+	Timeout       time.Duration
+
+	genericclioptions.IOStreams
+}
+
+// WaitOptions defines flags and other configuration parameters for the `wait` command
+type WaitOptions struct {
+	Builder *resource.Builder
+	Client  kubernetes.Interface
+
+	FilenameOptions  resource.FilenameOptions
+	Namespace        string
+	EnforceNamespace bool
+
+	// Pods are the pods to wait for, if they are empty, all pods
+	// matching LabelSelector in Namespace or AllNamespaces.
+	Pods          []types.NamespacedName
+	LabelSelector string
+	AllNamespaces bool
+
+	Timeout time.Duration
+
+	genericclioptions.IOStreams
+}
+
+func NewWaitFlags(ioStreams genericclioptions.IOStreams) *WaitFlags {
+	filenames := []string{}
+	kustomize := ""
+	recursive := false
+	usage := "The files that contain the pods to wait for."
+	return &WaitFlags{
+		FileNameFlags: &genericclioptions.FileNameFlags{Usage: usage, Filenames: &filenames, Kustomize: &kustomize, Recursive: &recursive},
+		KubeConfigFlags: &genericclioptions.ConfigFlags{
+			Timeout:    utilpointer.String("0"),
+			KubeConfig: utilpointer.String(""),
+			APIServer:  utilpointer.String(""),
+			Namespace:  utilpointer.String(""),
+		},
+		LabelSelector: generate.SimulatorLabelKey + "=true",
+		Timeout:       defaultWaitTimeout,
+		IOStreams:     ioStreams,
+	}
+}
+
+// NewCmdWait creates the `wait` command
+func NewCmdWait(ioStreams genericclioptions.IOStreams) *cobra.Command {
+	flags := NewWaitFlags(ioStreams)
+
+	cmd := &cobra.Command{
+		Use:                   "wait [-f FILENAME | -l SELECTOR]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Wait for pods to be scheduled and report the outcome"),
+		Run: func(cmd *cobra.Command, args []string) {
+			o, err := flags.ToOptions(cmd)
+			cmdutil.CheckErr(err)
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+	flags.AddFlags(cmd)
+	return cmd
+}
+
+// AddFlags registers flags for a cli
+func (flags *WaitFlags) AddFlags(cmd *cobra.Command) {
+	flags.FileNameFlags.AddFlags(cmd.Flags())
+	flags.KubeConfigFlags.AddFlags(cmd.Flags())
+
+	cmd.Flags().StringVarP(&flags.LabelSelector, "selector", "l", flags.LabelSelector, "Wait for the pods matching this label selector, unless the pods are given by files.")
+	cmd.Flags().BoolVarP(&flags.AllNamespaces, "all-namespaces", "A", flags.AllNamespaces, "If true, wait for the pods matching the selector in all namespaces.")
+	cmd.Flags().DurationVar(&flags.Timeout, "timeout", flags.Timeout, "The length of time to wait for the pods to be bound or unschedulable.")
+}
+
+// ToOptions converts from CLI inputs to runtime inputs
+func (flags *WaitFlags) ToOptions(cmd *cobra.Command) (*WaitOptions, error) {
+	if _, err := labels.Parse(flags.LabelSelector); err != nil {
+		return nil, err
+	}
+	if flags.Timeout <= 0 {
+		return nil, fmt.Errorf("--timeout must be greater than 0")
+	}
+
+	f := cmdutil.NewFactory(flags.KubeConfigFlags)
+	namespace, enforceNamespace, err := f.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return nil, err
+	}
+	client, err := f.KubernetesClientSet()
+	if err != nil {
+		return nil, err
+	}
+
+	return &WaitOptions{
+		Builder:          f.NewBuilder(),
+		Client:           client,
+		FilenameOptions:  flags.FileNameFlags.ToOptions(),
+		Namespace:        namespace,
+		EnforceNamespace: enforceNamespace,
+		LabelSelector:    flags.LabelSelector,
+		AllNamespaces:    flags.AllNamespaces,
+		Timeout:          flags.Timeout,
+		IOStreams:        flags.IOStreams,
+	}, nil
+}
+
+func (o *WaitOptions) Run() error {
+	if len(o.FilenameOptions.Filenames) > 0 || len(o.FilenameOptions.Kustomize) > 0 {
+		infos, err := o.Builder.
+			Unstructured().
+			ContinueOnError().
+			NamespaceParam(o.Namespace).DefaultNamespace().
+			FilenameParam(o.EnforceNamespace, &o.FilenameOptions).
+			Flatten().
+			Do().
+			Infos()
+		if err != nil {
+			return err
+		}
+		o.Pods = podNames(infos)
+		if len(o.Pods) == 0 {
+			return fmt.Errorf("no pods passed to wait")
+		}
+	}
+	return o.WaitAndReport()
+}
+
+// WaitAndReport waits for the pods, prints the report of their scheduling, and
+// returns an error if any pod was neither bound nor unschedulable in time.
+func (o *WaitOptions) WaitAndReport() error {
+	pods, err := o.WaitForPods()
+	if err != nil {
+		return err
+	}
+	report := newSchedulingReport(pods, o.failedSchedulingEvents(pods))
+	if err := report.Print(o.Out); err != nil {
+		return err
+	}
+	if pending := report.Outcomes[OutcomeTimedOut] + report.Outcomes[OutcomeMissing]; pending > 0 {
+		return fmt.Errorf("%d pods were neither bound nor unschedulable after %v", pending, o.Timeout)
+	}
+	return nil
+}
+
+// WaitForPods watches the pods until every one is bound or unschedulable, or the timeout expires,
+// and returns their last states. A pod of o.Pods which does not exist maps to nil.
+func (o *WaitOptions) WaitForPods() (map[types.NamespacedName]*corev1.Pod, error) {
+	namespace, selector := o.Namespace, o.LabelSelector
+	if o.AllNamespaces {
+		namespace = metav1.NamespaceAll
+	}
+	if len(o.Pods) > 0 {
+		// the pods are known by name, they need not be labeled
+		namespace, selector = o.Pods[0].Namespace, ""
+		for _, pod := range o.Pods {
+			if pod.Namespace != namespace {
+				namespace = metav1.NamespaceAll
+				break
+			}
+		}
+	}
+
+	changed := make(chan struct{}, 1)
+	notify := func(interface{}) {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	}
+	informer := coreinformers.NewFilteredPodInformer(o.Client, namespace, 0, cache.Indexers{},
+		func(options *metav1.ListOptions) { options.LabelSelector = selector })
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    notify,
+		UpdateFunc: func(_, obj interface{}) { notify(obj) },
+		DeleteFunc: notify,
+	})
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go informer.Run(stopCh)
+
+	// the timeout covers listing the pods too
+	timeout := make(chan struct{})
+	timer := time.AfterFunc(o.Timeout, func() { close(timeout) })
+	defer timer.Stop()
+	if !cache.WaitForCacheSync(timeout, informer.HasSynced) {
+		return nil, fmt.Errorf("failed to list pods within %v", o.Timeout)
+	}
+	for {
+		pods := o.currentPods(informer.GetStore())
+		if allDecided(pods) {
+			return pods, nil
+		}
+		select {
+		case <-changed:
+		case <-timeout:
+			return o.currentPods(informer.GetStore()), nil
+		}
+	}
+}
+
+func (o *WaitOptions) currentPods(store cache.Store) map[types.NamespacedName]*corev1.Pod {
+	pods := map[types.NamespacedName]*corev1.Pod{}
+	if len(o.Pods) > 0 {
+		for _, name := range o.Pods {
+			pods[name] = nil
+			if obj, exists, _ := store.GetByKey(name.String()); exists {
+				pods[name] = obj.(*corev1.Pod)
+			}
+		}
+		return pods
+	}
+	for _, obj := range store.List() {
+		pod := obj.(*corev1.Pod)
+		pods[types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}] = pod
+	}
+	return pods
+}
+
+// allDecided returns true if every pod is bound or unschedulable.
+func allDecided(pods map[types.NamespacedName]*corev1.Pod) bool {
+	for _, pod := range pods {
+		if outcome := podOutcome(pod); outcome != OutcomeBound && outcome != OutcomeUnschedulable {
+			return false
+		}
+	}
+	return true
+}
+
+// failedSchedulingEvents returns the message of the latest FailedScheduling event of each pod.
+func (o *WaitOptions) failedSchedulingEvents(pods map[types.NamespacedName]*corev1.Pod) map[types.NamespacedName]string {
+	namespaces := map[string]bool{}
+	for name, pod := range pods {
+		if pod != nil && podOutcome(pod) != OutcomeBound {
+			namespaces[name.Namespace] = true
+		}
+	}
+
+	messages := map[types.NamespacedName]string{}
+	latest := map[types.NamespacedName]time.Time{}
+	for namespace := range namespaces {
+		events, err := o.Client.CoreV1().Events(namespace).List(context.TODO(), metav1.ListOptions{
+			FieldSelector: "involvedObject.kind=Pod,reason=FailedScheduling",
+		})
+		if err != nil {
+			fmt.Fprintf(o.ErrOut, "Warning: listing events of namespace %s: %v\n", namespace, err)
+			continue
+		}
+		for _, event := range events.Items {
+			name := types.NamespacedName{Namespace: event.InvolvedObject.Namespace, Name: event.InvolvedObject.Name}
+			if _, ok := pods[name]; !ok {
+				continue
+			}
+			at := event.LastTimestamp.Time
+			if at.IsZero() {
+				at = event.EventTime.Time
+			}
+			if at.Before(latest[name]) {
+				continue
+			}
+			latest[name] = at
+			messages[name] = event.Message
+		}
+	}
+	return messages
+}
+
+// podNames returns the names of the pods in infos.
+func podNames(infos []*resource.Info) []types.NamespacedName {
+	var names []types.NamespacedName
+	for _, info := range infos {
+		if info.Mapping == nil || info.Mapping.GroupVersionKind.GroupKind() != corev1.SchemeGroupVersion.WithKind("Pod").GroupKind() {
+			continue
+		}
+		names = append(names, types.NamespacedName{Namespace: info.Namespace, Name: info.Name})
+	}
+	return names
+}
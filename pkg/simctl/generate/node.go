@@ -76,10 +76,10 @@ func InitGenerateNodeFlags(cmd *cobra.Command) {
 
 func GenFakeNode() error {
 	if len(genNodeFlags.ResourcesList) > 0 {
-		nodeResources = parseMapArgs(genNodeFlags.ResourcesList)
+		nodeResources = ParseMapArgs(genNodeFlags.ResourcesList)
 	}
 	if len(genNodeFlags.LabelsList) > 0 {
-		nodeLabels = parseMapArgs(genNodeFlags.LabelsList)
+		nodeLabels = ParseMapArgs(genNodeFlags.LabelsList)
 	}
 	fmt.Printf("Generate test data of %d node(s) with following config: \n", genNodeFlags.Count)
 	fmt.Printf("Node capacity resources list: %s\n", nodeResources)
@@ -96,17 +96,7 @@ func fakeNodes(nodeCount int, resourceList []map[string]string, labelList []map[
 	rand.Seed(time.Now().Unix())
 	for idx := 1; idx <= nodeCount; idx++ {
 		name = fmt.Sprintf("instance-%04d", idx)
-		// generate node labels
-		labels := labelList[rand.Intn(labelLen)]
-		if labels == nil {
-			labels = map[string]string{}
-		}
-		labels["kubernetes.io/hostname"] = name
-		// generate node resources
-		nodeRes := resourceList[rand.Intn(allocLen)]
-		nodeRes["pods"] = "110"
-		capacity, alloc := genNodeResources(BuildResources(nodeRes))
-		fakeNode := BuildFakeNode(name, false, capacity, alloc, nodeConditions, labels)
+		fakeNode := BuildFakeNodeOfShape(name, resourceList[rand.Intn(allocLen)], labelList[rand.Intn(labelLen)])
 		nodeStr, err := yaml.Marshal(fakeNode)
 		if err != nil {
 			fmt.Printf("json marshal failed, err: %v", err)
@@ -130,6 +120,23 @@ func fakeNodes(nodeCount int, resourceList []map[string]string, labelList []map[
 	return nil
 }
 
+// BuildFakeNodeOfShape builds a ready node like those of generate node, with the resources and labels
+// of a shape such as "cpu=24;memory=128Gi" and room for 110 pods.
+func BuildFakeNodeOfShape(name string, resources, labels map[string]string) *v1.Node {
+	nodeLabels := map[string]string{}
+	for key, value := range labels {
+		nodeLabels[key] = value
+	}
+	nodeLabels["kubernetes.io/hostname"] = name
+	nodeLabels[SimulatorLabelKey] = "true"
+	nodeRes := map[string]string{"pods": "110"}
+	for key, value := range resources {
+		nodeRes[key] = value
+	}
+	capacity, alloc := genNodeResources(BuildResources(nodeRes))
+	return BuildFakeNode(name, false, capacity, alloc, nodeConditions, nodeLabels)
+}
+
 func genNodeResources(res v1.ResourceList) (v1.ResourceList, v1.ResourceList) {
 	// TODO: reserve resource for node
 	return res, res
@@ -22,6 +22,7 @@ import (
 
 	"github.com/spf13/cobra"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"sigs.k8s.io/yaml"
 )
 
@@ -41,7 +42,7 @@ var (
 	}
 	podLabelsList = []map[string]string{
 		{
-			"scheduler-simulator": "true",
+			SimulatorLabelKey: "true",
 		},
 	}
 )
@@ -89,10 +90,10 @@ func GenFakePods() error {
 		podNSList = genPodFlags.NamespaceList
 	}
 	if len(genPodFlags.ResourceList) != 0 {
-		podReqList = parseMapArgs(genPodFlags.ResourceList)
+		podReqList = ParseMapArgs(genPodFlags.ResourceList)
 	}
 	if len(genPodFlags.LabelList) != 0 {
-		podLabelsList = parseMapArgs(genPodFlags.LabelList)
+		podLabelsList = ParseMapArgs(genPodFlags.LabelList)
 	}
 	fmt.Printf("Generate test data of %d pod(s) with following config: \n", genPodFlags.Count)
 	fmt.Printf("Pod namespace list: %s\n", podNSList)
@@ -115,6 +116,15 @@ func fakePods(podCount int, nsList, queueList []string, phaseList []v1.PodPhase,
 	var err error
 	rand.Seed(time.Now().Unix())
 
+	// create the namespaces of the pods, except the default one which always exists
+	for _, ns := range sets.NewString(nsList...).Delete(v1.NamespaceDefault).List() {
+		nsStr, err := yaml.Marshal(BuildFakeNamespace(ns))
+		if err != nil {
+			return fmt.Errorf("yaml marshal failed, err: %v", err)
+		}
+		podsYaml = append(podsYaml, append(nsStr, []byte("---\n")...)...)
+	}
+
 	for idx := 0; idx < podCount; idx++ {
 		name = generateIDWithLength("test-pod", 16)
 		namespace = nsList[rand.Intn(nsLen)]
@@ -15,18 +15,39 @@ limitations under the License.
 package generate
 
 import (
+	"fmt"
+	"io"
+	"os"
 	"strings"
 
 	"github.com/google/uuid"
 	"k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
 )
 
 const (
-	uuidMaxLen         = 32
-	defaultQueue       = "default"
-	queueAnnotationKey = "volcano.sh/queue-name"
+	uuidMaxLen   = 32
+	defaultQueue = "default"
+
+	// SimulatorLabelKey marks the objects which are generated by simctl, which `simctl reset` deletes.
+	SimulatorLabelKey = "scheduler-simulator"
+	// QueueAnnotationKey is the volcano queue of a pod which belongs to no PodGroup.
+	QueueAnnotationKey = "volcano.sh/queue-name"
+	// PodGroupAnnotationKey binds a pod to the volcano PodGroup of its job.
+	PodGroupAnnotationKey = "scheduling.k8s.io/group-name"
+	// ArrivalTimeAnnotationKey is the submit time of a pod, or the time a node joins a simulated
+	// cluster, as a duration offset from the start of the trace.
+	ArrivalTimeAnnotationKey = "scheduler-simulator/arrival-time"
+	// RuntimeAnnotationKey is how long a pod runs once it is started, as a duration.
+	RuntimeAnnotationKey = "scheduler-simulator/runtime"
+	// NodePoolLabelKey is the node pool of a node which the simulated autoscaler scales.
+	NodePoolLabelKey = "scheduler-simulator/node-pool"
+
+	podGroupAPIVersion = "scheduling.volcano.sh/v1beta1"
 )
 
 var (
@@ -50,7 +71,7 @@ func generateIDWithLength(Prefix string, Len int) string {
 	return uuidStr
 }
 
-func parseMapArgs(argsList []string) []map[string]string {
+func ParseMapArgs(argsList []string) []map[string]string {
 	var mapArgs []map[string]string
 	if len(argsList) > 0 {
 		for _, value := range argsList {
@@ -87,7 +108,7 @@ func BuildFakePod(name, namespace, schedulerName, queueName string, labels map[s
 			Namespace: namespace,
 			Labels:    labels,
 			Annotations: map[string]string{
-				queueAnnotationKey: queueName,
+				QueueAnnotationKey: queueName,
 			},
 		},
 		Spec: v1.PodSpec{
@@ -130,3 +151,116 @@ func BuildFakeNode(name string, unsched bool, capacity, alloc v1.ResourceList, n
 		},
 	}
 }
+
+// BuildFakeNamespace builds a namespace for generated objects, labeled so that `simctl reset` deletes it.
+func BuildFakeNamespace(name string) *v1.Namespace {
+	return &v1.Namespace{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Namespace"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{SimulatorLabelKey: "true"},
+		},
+	}
+}
+
+// BuildFakePodGroup builds a volcano PodGroup which admits its pods only when minMember of them can be scheduled.
+func BuildFakePodGroup(name, namespace, queueName string, minMember int32, minRes v1.ResourceList) *unstructured.Unstructured {
+	if queueName == "" {
+		queueName = defaultQueue
+	}
+	minResources := map[string]interface{}{}
+	for rName, rValue := range minRes {
+		minResources[string(rName)] = rValue.String()
+	}
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": podGroupAPIVersion,
+			"kind":       "PodGroup",
+			"metadata": map[string]interface{}{
+				"name":      name,
+				"namespace": namespace,
+				"labels":    map[string]interface{}{SimulatorLabelKey: "true"},
+			},
+			"spec": map[string]interface{}{
+				"minMember":    int64(minMember),
+				"queue":        queueName,
+				"minResources": minResources,
+			},
+		},
+	}
+}
+
+// BuildFakeQueue builds a volcano Queue, which shares the resources of the cluster with the other
+// queues by weight. An empty capability does not limit the queue.
+func BuildFakeQueue(name string, weight int32, capability v1.ResourceList, reclaimable bool) *unstructured.Unstructured {
+	spec := map[string]interface{}{
+		"weight":      int64(weight),
+		"reclaimable": reclaimable,
+	}
+	if len(capability) > 0 {
+		capabilityRes := map[string]interface{}{}
+		for rName, rValue := range capability {
+			capabilityRes[string(rName)] = rValue.String()
+		}
+		spec["capability"] = capabilityRes
+	}
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": podGroupAPIVersion,
+			"kind":       "Queue",
+			"metadata": map[string]interface{}{
+				"name":   name,
+				"labels": map[string]interface{}{SimulatorLabelKey: "true"},
+			},
+			"spec": spec,
+		},
+	}
+}
+
+// WriteYamlFile writes objs to the output file as a multi-document yaml, in the same layout as generated test data.
+func WriteYamlFile(output string, objs []interface{}) error {
+	var objsYaml []byte
+	for _, obj := range objs {
+		objStr, err := yaml.Marshal(obj)
+		if err != nil {
+			return fmt.Errorf("yaml marshal failed, err: %v", err)
+		}
+		objStr = append(objStr, []byte("---\n")...)
+		objsYaml = append(objsYaml, objStr...)
+	}
+
+	yamlfile, err := os.OpenFile(output, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("error opening/creating file: %v", err)
+	}
+	defer yamlfile.Close()
+	_, err = yamlfile.Write(objsYaml)
+	return err
+}
+
+// ReadYamlFile reads all objects of a multi-document yaml or json file, such as one written by WriteYamlFile.
+func ReadYamlFile(filename string) ([]*unstructured.Unstructured, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var objs []*unstructured.Unstructured
+	decoder := utilyaml.NewYAMLOrJSONDecoder(f, 4096)
+	for idx := 0; ; idx++ {
+		obj := &unstructured.Unstructured{}
+		if err := decoder.Decode(&obj.Object); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("%s: document %d: %v", filename, idx, err)
+		}
+		// skip empty documents, such as the one after the last separator
+		if len(obj.Object) == 0 {
+			continue
+		}
+		objs = append(objs, obj)
+	}
+	return objs, nil
+}
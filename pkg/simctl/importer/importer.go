@@ -0,0 +1,210 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package importer
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/D0m021ng/scheduler-simulator/pkg/simctl/generate"
+)
+
+// Job is a batch job read from an HPC workload trace. Every job becomes a gang of
+// Tasks identical pods, which are only admitted together.
+type Job struct {
+	ID    string
+	Queue string
+	// Submit is the submit time of the job, relative to the first job of the trace.
+	Submit time.Duration
+	// Runtime is the wall clock time the job ran for.
+	Runtime time.Duration
+	// Tasks is the count of pods of the job, each requesting Resources.
+	Tasks     int
+	Resources v1.ResourceList
+	// LastTaskResources, if set, replaces Resources for the last pod, e.g. the remaining
+	// processors of a SWF job which are fewer than the cores per pod.
+	LastTaskResources v1.ResourceList
+}
+
+// taskResources returns the resources requested by the pod idx of the job.
+func (job *Job) taskResources(idx int) v1.ResourceList {
+	if idx == job.Tasks-1 && job.LastTaskResources != nil {
+		return job.LastTaskResources
+	}
+	return job.Resources
+}
+
+type importFlags struct {
+	Input  string
+	Output string
+	Limit  int
+
+	Namespace     string
+	SchedulerName string
+	DefaultQueue  string
+	LabelList     []string
+
+	// CoresPerPod splits SWF jobs into pods of at most CoresPerPod processors.
+	CoresPerPod int
+	// MemoryPerCore is used for SWF jobs which record no memory.
+	MemoryPerCore string
+}
+
+var impFlags = &importFlags{}
+
+// InitImportFlags is used to init all flags during import trace.
+func InitImportFlags(cmd *cobra.Command) {
+
+	cmd.Flags().StringVarP(&impFlags.Input, "filename", "f", "", "the trace file to import")
+	cmd.Flags().StringVarP(&impFlags.Output, "output", "o", "testdata-pod.yaml", "the name of pod test data file")
+	cmd.Flags().IntVarP(&impFlags.Limit, "limit", "", 0, "the max count of jobs to import, 0 means all jobs")
+	cmd.Flags().StringVarP(&impFlags.Namespace, "namespace", "", "default", "namespace for pods")
+	cmd.Flags().StringVarP(&impFlags.SchedulerName, "schedulerName", "n", "volcano", "the name of scheduler")
+	cmd.Flags().StringVarP(&impFlags.DefaultQueue, "queue", "q", "default", "queue for jobs without queue or partition")
+	cmd.Flags().StringSliceVarP(&impFlags.LabelList, "labels", "l",
+		[]string{"scheduler-simulator=true"}, "labels for pods. e.g. --labels \"a=b;c=d\" ")
+}
+
+// InitImportSWFFlags is used to init the flags which only apply to SWF traces.
+func InitImportSWFFlags(cmd *cobra.Command) {
+	cmd.Flags().IntVarP(&impFlags.CoresPerPod, "cores-per-pod", "", 0,
+		"split jobs into pods of at most this many processors, 0 means one pod per job")
+	cmd.Flags().StringVarP(&impFlags.MemoryPerCore, "memory-per-core", "", "",
+		"memory request per processor for jobs without memory in the trace, e.g. 2Gi")
+}
+
+// ImportSWF converts a Standard Workload Format trace into pod test data.
+func ImportSWF() error {
+	var memPerCore *resource.Quantity
+	if impFlags.MemoryPerCore != "" {
+		q, err := resource.ParseQuantity(impFlags.MemoryPerCore)
+		if err != nil {
+			return fmt.Errorf("invalid memory-per-core %s: %v", impFlags.MemoryPerCore, err)
+		}
+		memPerCore = &q
+	}
+	f, err := os.Open(impFlags.Input)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	jobs, err := ParseSWF(f, impFlags.CoresPerPod, memPerCore)
+	if err != nil {
+		return err
+	}
+	return writeJobs(jobs)
+}
+
+// ImportSacct converts the pipe-delimited output of `sacct -P` into pod test data.
+func ImportSacct() error {
+	f, err := os.Open(impFlags.Input)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	jobs, err := ParseSacct(f)
+	if err != nil {
+		return err
+	}
+	return writeJobs(jobs)
+}
+
+func writeJobs(jobs []Job) error {
+	sort.SliceStable(jobs, func(i, j int) bool {
+		return jobs[i].Submit < jobs[j].Submit
+	})
+	if impFlags.Limit > 0 && len(jobs) > impFlags.Limit {
+		jobs = jobs[:impFlags.Limit]
+	}
+	var labels map[string]string
+	if labelsList := generate.ParseMapArgs(impFlags.LabelList); len(labelsList) > 0 {
+		labels = labelsList[0]
+	}
+
+	fmt.Printf("Import %d job(s) from %s\n", len(jobs), impFlags.Input)
+	objs := BuildJobObjects(jobs, impFlags.Namespace, impFlags.SchedulerName, impFlags.DefaultQueue, labels)
+	return generate.WriteYamlFile(impFlags.Output, objs)
+}
+
+// BuildJobObjects builds the namespace, unless it is the default one, and the queues of the jobs,
+// then a PodGroup and its pods for every job, in submit order.
+func BuildJobObjects(jobs []Job, namespace, schedulerName, defaultQueue string, labels map[string]string) []interface{} {
+	var objs []interface{}
+	if namespace != v1.NamespaceDefault {
+		objs = append(objs, generate.BuildFakeNamespace(namespace))
+	}
+	// the queues share the cluster equally, except the default queue which volcano creates
+	queues := sets.NewString("default")
+	for _, job := range jobs {
+		queue := job.Queue
+		if queue == "" {
+			queue = defaultQueue
+		}
+		if !queues.Has(queue) {
+			queues.Insert(queue)
+			objs = append(objs, generate.BuildFakeQueue(queue, 1, nil, true))
+		}
+	}
+	for _, job := range jobs {
+		queue := job.Queue
+		if queue == "" {
+			queue = defaultQueue
+		}
+		name := "job-" + sanitizeName(job.ID)
+
+		minRes := v1.ResourceList{}
+		for idx := 0; idx < job.Tasks; idx++ {
+			for rName, rValue := range job.taskResources(idx) {
+				total := minRes[rName]
+				total.Add(rValue)
+				minRes[rName] = total
+			}
+		}
+		objs = append(objs, generate.BuildFakePodGroup(name, namespace, queue, int32(job.Tasks), minRes))
+
+		for idx := 0; idx < job.Tasks; idx++ {
+			pod := generate.BuildFakePod(fmt.Sprintf("%s-%d", name, idx), namespace, schedulerName, queue,
+				labels, v1.PodPending, job.taskResources(idx).DeepCopy())
+			pod.Annotations[generate.PodGroupAnnotationKey] = name
+			pod.Annotations[generate.ArrivalTimeAnnotationKey] = job.Submit.String()
+			pod.Annotations[generate.RuntimeAnnotationKey] = job.Runtime.String()
+			objs = append(objs, pod)
+		}
+	}
+	return objs
+}
+
+// sanitizeName turns a job id, such as the array job 1234_5 of slurm, into a valid object name.
+func sanitizeName(id string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '.':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		}
+		return '-'
+	}, id)
+}
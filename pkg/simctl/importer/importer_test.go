@@ -0,0 +1,130 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/D0m021ng/scheduler-simulator/pkg/simctl/generate"
+)
+
+func TestParseSWF(t *testing.T) {
+	trace := `; Version: 2.2
+; UnixStartTime: 1000000000
+1 100 5 3600 8 -1 -1 8 7200 1024 1 1 1 -1 2 -1 -1 -1
+2 160 0 -1 -1 -1 -1 -1 60 -1 5 1 1 -1 -1 -1 -1 -1
+3 200 0 60 64 -1 -1 64 60 -1 1 2 1 -1 -1 -1 -1 -1
+`
+	jobs, err := ParseSWF(strings.NewReader(trace), 32, nil)
+	if err != nil {
+		t.Fatalf("parse swf failed: %v", err)
+	}
+	if len(jobs) != 2 {
+		t.Fatalf("expected 2 jobs, got %d", len(jobs))
+	}
+	first := jobs[0]
+	if first.Submit != 0 || first.Runtime != time.Hour || first.Tasks != 1 || first.Queue != "queue-2" {
+		t.Errorf("unexpected first job: %+v", first)
+	}
+	if mem := first.Resources[v1.ResourceMemory]; mem.Value() != 8*1024*1024 {
+		t.Errorf("expected 8Mi memory, got %s", mem.String())
+	}
+	second := jobs[1]
+	if second.Submit != 100*time.Second || second.Tasks != 2 {
+		t.Errorf("unexpected second job: %+v", second)
+	}
+	if cpu := second.Resources[v1.ResourceCPU]; cpu.Value() != 32 {
+		t.Errorf("expected 32 cpu per pod, got %s", cpu.String())
+	}
+}
+
+func TestParseSWFUnevenTasks(t *testing.T) {
+	// 10 processors of 1024KB each, split into pods of at most 4 cores
+	trace := "1 0 0 60 10 -1 -1 10 60 1024 1 1 1 -1 -1 -1 -1 -1\n"
+	jobs, err := ParseSWF(strings.NewReader(trace), 4, nil)
+	if err != nil {
+		t.Fatalf("parse swf failed: %v", err)
+	}
+	if len(jobs) != 1 || jobs[0].Tasks != 3 {
+		t.Fatalf("expected a job of 3 tasks, got %+v", jobs)
+	}
+
+	objs := BuildJobObjects(jobs, "default", "volcano", "default", nil)
+	if len(objs) != 4 {
+		t.Fatalf("expected 4 objects, got %d", len(objs))
+	}
+	for i, cores := range []int64{4, 4, 2} {
+		pod := objs[i+1].(*v1.Pod)
+		requests := pod.Spec.Containers[0].Resources.Requests
+		cpu, mem := requests[v1.ResourceCPU], requests[v1.ResourceMemory]
+		if cpu.Value() != cores || mem.Value() != cores*1024*1024 {
+			t.Errorf("pod %s requests %s cpu and %s memory, want %d cores", pod.Name, cpu.String(), mem.String(), cores)
+		}
+	}
+	minResources, _, _ := unstructured.NestedStringMap(objs[0].(*unstructured.Unstructured).Object, "spec", "minResources")
+	if minResources["cpu"] != "10" || minResources["memory"] != "10Mi" {
+		t.Errorf("unexpected podgroup min resources: %v", minResources)
+	}
+}
+
+func TestParseSacct(t *testing.T) {
+	output := `JobID|Submit|Elapsed|AllocCPUS|NNodes|ReqMem|AllocTRES|Partition
+100|2023-01-02T03:00:00|1-00:00:10|16|2|4Gc|billing=16,cpu=16,gres/gpu=4,mem=128G,node=2|gpu,cpu
+100.batch|2023-01-02T03:00:00|1-00:00:10|8|1|4Gc||gpu
+101_3|2023-01-02T02:59:00|05:00|2|1|1000Mn|cpu=2,mem=1000M,node=1|cpu
+102|2023-01-02T03:01:00|00:00:00|0|1|1000Mn||cpu
+`
+	jobs, err := ParseSacct(strings.NewReader(output))
+	if err != nil {
+		t.Fatalf("parse sacct failed: %v", err)
+	}
+	if len(jobs) != 2 {
+		t.Fatalf("expected 2 jobs, got %d", len(jobs))
+	}
+	gang := jobs[0]
+	if gang.Submit != time.Minute || gang.Runtime != 24*time.Hour+10*time.Second || gang.Tasks != 2 || gang.Queue != "gpu" {
+		t.Errorf("unexpected gang job: %+v", gang)
+	}
+	cpu, mem, gpu := gang.Resources[v1.ResourceCPU], gang.Resources[v1.ResourceMemory], gang.Resources[gpuResourceName]
+	if cpu.Value() != 8 || mem.Value() != 32<<30 || gpu.Value() != 2 {
+		t.Errorf("unexpected gang resources: %v", gang.Resources)
+	}
+	if jobs[1].Submit != 0 || jobs[1].Runtime != 5*time.Minute {
+		t.Errorf("unexpected array job: %+v", jobs[1])
+	}
+
+	objs := BuildJobObjects(jobs, "default", "volcano", "default", nil)
+	// the queues of the jobs, then a podgroup and its pods for every job
+	if len(objs) != 7 {
+		t.Fatalf("expected 7 objects, got %d", len(objs))
+	}
+	for _, obj := range objs[:2] {
+		if queue := obj.(*unstructured.Unstructured); queue.GetKind() != "Queue" {
+			t.Errorf("expected the queues first, got %s %s", queue.GetKind(), queue.GetName())
+		}
+	}
+	pod, ok := objs[3].(*v1.Pod)
+	if !ok {
+		t.Fatalf("expected pod after podgroup, got %T", objs[3])
+	}
+	if pod.Name != "job-100-0" || pod.Annotations[generate.PodGroupAnnotationKey] != "job-100" {
+		t.Errorf("unexpected pod: %s %v", pod.Name, pod.Annotations)
+	}
+}
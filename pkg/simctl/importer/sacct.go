@@ -0,0 +1,250 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package importer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	sacctTimeLayout = "2006-01-02T15:04:05"
+
+	gpuResourceName = "nvidia.com/gpu"
+)
+
+// ParseSacct reads the jobs of `sacct -P` output, whose first line names the columns. For example,
+//
+//	sacct -a -P -X -o JobID,Submit,Elapsed,AllocCPUS,NNodes,ReqMem,AllocTRES,Partition
+//
+// Job steps, such as 1234.batch, are skipped, and so are jobs which never got any cpu.
+func ParseSacct(r io.Reader) ([]Job, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("empty sacct output")
+	}
+	columns := map[string]int{}
+	for idx, name := range strings.Split(strings.TrimSpace(scanner.Text()), "|") {
+		columns[strings.ToLower(name)] = idx
+	}
+	for _, required := range []string{"jobid", "submit"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("column %s is required in sacct output", required)
+		}
+	}
+
+	var jobs []Job
+	lineNo := 1
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		row := sacctRow{columns: columns, fields: strings.Split(line, "|")}
+		jobID := row.get("jobid")
+		if strings.Contains(jobID, ".") {
+			continue
+		}
+		submit, err := time.Parse(sacctTimeLayout, row.get("submit"))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid submit time %q: %v", lineNo, row.get("submit"), err)
+		}
+		runtime, err := row.elapsed()
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNo, err)
+		}
+		tres := parseTRES(row.get("alloctres"))
+		if len(tres) == 0 {
+			tres = parseTRES(row.get("reqtres"))
+		}
+
+		nodes := row.int("nnodes", tres["node"], 1)
+		if nodes <= 0 {
+			nodes = 1
+		}
+		cpus := row.int("alloccpus", row.int("ncpus", row.int("reqcpus", tres["cpu"], 0), 0), 0)
+		if cpus <= 0 {
+			continue
+		}
+		res := v1.ResourceList{
+			v1.ResourceCPU: *resource.NewQuantity(divCeil(cpus, nodes), resource.DecimalSI),
+		}
+		if mem, err := row.memoryPerNode(cpus, nodes, tres["mem"]); err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNo, err)
+		} else if mem > 0 {
+			res[v1.ResourceMemory] = *resource.NewQuantity(mem, resource.BinarySI)
+		}
+		if gpus := tres["gres/gpu"]; gpus > 0 {
+			res[gpuResourceName] = *resource.NewQuantity(divCeil(gpus, nodes), resource.DecimalSI)
+		}
+
+		jobs = append(jobs, Job{
+			ID:        jobID,
+			Queue:     strings.Split(row.get("partition"), ",")[0],
+			Submit:    time.Duration(submit.Unix()) * time.Second,
+			Runtime:   runtime,
+			Tasks:     int(nodes),
+			Resources: res,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	normalizeSubmit(jobs)
+	return jobs, nil
+}
+
+type sacctRow struct {
+	columns map[string]int
+	fields  []string
+}
+
+func (r sacctRow) get(column string) string {
+	idx, ok := r.columns[column]
+	if !ok || idx >= len(r.fields) {
+		return ""
+	}
+	return strings.TrimSpace(r.fields[idx])
+}
+
+func (r sacctRow) int(column string, fallback, defaultValue int64) int64 {
+	if v, err := strconv.ParseInt(r.get(column), 10, 64); err == nil {
+		return v
+	}
+	if fallback > 0 {
+		return fallback
+	}
+	return defaultValue
+}
+
+// elapsed parses ElapsedRaw in seconds, or Elapsed in the [DD-[HH:]]MM:SS format of slurm.
+func (r sacctRow) elapsed() (time.Duration, error) {
+	if raw := r.get("elapsedraw"); raw != "" {
+		secs, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid elapsed %q: %v", raw, err)
+		}
+		return time.Duration(secs) * time.Second, nil
+	}
+	elapsed := r.get("elapsed")
+	if elapsed == "" {
+		return 0, nil
+	}
+	var days int64
+	if idx := strings.Index(elapsed, "-"); idx >= 0 {
+		d, err := strconv.ParseInt(elapsed[:idx], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid elapsed %q: %v", elapsed, err)
+		}
+		days, elapsed = d, elapsed[idx+1:]
+	}
+	var secs int64
+	for _, part := range strings.Split(elapsed, ":") {
+		v, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid elapsed %q: %v", r.get("elapsed"), err)
+		}
+		secs = secs*60 + int64(v)
+	}
+	return time.Duration(days*86400+secs) * time.Second, nil
+}
+
+// memoryPerNode returns the memory in bytes requested on every node. ReqMem is per node with a
+// "n" suffix, per cpu with a "c" suffix, and per node otherwise; the mem of TRES is for the job.
+func (r sacctRow) memoryPerNode(cpus, nodes, tresMem int64) (int64, error) {
+	reqMem := r.get("reqmem")
+	if reqMem == "" {
+		return divCeil(tresMem, nodes), nil
+	}
+	perCPU := false
+	switch reqMem[len(reqMem)-1] {
+	case 'c':
+		perCPU = true
+		reqMem = reqMem[:len(reqMem)-1]
+	case 'n':
+		reqMem = reqMem[:len(reqMem)-1]
+	}
+	mem, err := parseSlurmMemory(reqMem)
+	if err != nil {
+		return 0, err
+	}
+	if perCPU {
+		return mem * divCeil(cpus, nodes), nil
+	}
+	return mem, nil
+}
+
+// parseTRES parses trackable resources such as "billing=4,cpu=4,gres/gpu=2,mem=16G,node=1",
+// memory is converted into bytes.
+func parseTRES(tres string) map[string]int64 {
+	result := map[string]int64{}
+	for _, item := range strings.Split(tres, ",") {
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		if kv[0] == "mem" {
+			if mem, err := parseSlurmMemory(kv[1]); err == nil {
+				result[kv[0]] = mem
+			}
+			continue
+		}
+		if v, err := strconv.ParseInt(kv[1], 10, 64); err == nil {
+			result[kv[0]] = v
+		}
+	}
+	return result
+}
+
+// parseSlurmMemory parses memory such as 4000M or 16G into bytes, values without unit are megabytes.
+func parseSlurmMemory(mem string) (int64, error) {
+	multiplier := int64(1 << 20)
+	if n := len(mem); n > 0 {
+		switch mem[n-1] {
+		case 'K', 'k':
+			multiplier = 1 << 10
+		case 'M', 'm':
+			multiplier = 1 << 20
+		case 'G', 'g':
+			multiplier = 1 << 30
+		case 'T', 't':
+			multiplier = 1 << 40
+		}
+		if mem[n-1] < '0' || mem[n-1] > '9' {
+			mem = mem[:n-1]
+		}
+	}
+	v, err := strconv.ParseFloat(mem, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid memory %q: %v", mem, err)
+	}
+	return int64(v * float64(multiplier)), nil
+}
+
+func divCeil(a, b int64) int64 {
+	return (a + b - 1) / b
+}
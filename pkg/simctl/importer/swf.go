@@ -0,0 +1,158 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package importer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// The fields of a job line in the Standard Workload Format, see
+// https://www.cs.huji.ac.il/labs/parallel/workload/swf.html
+const (
+	swfJobNumber = iota
+	swfSubmitTime
+	swfWaitTime
+	swfRunTime
+	swfAllocatedProcs
+	swfAverageCPUTime
+	swfUsedMemory
+	swfRequestedProcs
+	swfRequestedTime
+	swfRequestedMemory
+	swfStatus
+	swfUserID
+	swfGroupID
+	swfExecutable
+	swfQueueNumber
+	swfPartitionNumber
+	swfPrecedingJob
+	swfThinkTime
+	swfFieldCount
+)
+
+// ParseSWF reads the jobs of a SWF trace. Jobs are split into pods of at most coresPerPod
+// processors, and memPerCore is requested for jobs that record no memory.
+func ParseSWF(r io.Reader, coresPerPod int, memPerCore *resource.Quantity) ([]Job, error) {
+	var jobs []Job
+
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		// skip the header comments and blank lines
+		if line == "" || strings.HasPrefix(line, ";") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < swfFieldCount {
+			return nil, fmt.Errorf("line %d: expected %d fields, got %d", lineNo, swfFieldCount, len(fields))
+		}
+		values := make([]int64, swfFieldCount)
+		for i := 0; i < swfFieldCount; i++ {
+			// some traces record fractional values, e.g. average cpu time
+			v, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid field %d %q: %v", lineNo, i+1, fields[i], err)
+			}
+			values[i] = int64(v)
+		}
+
+		procs := firstValid(values[swfAllocatedProcs], values[swfRequestedProcs])
+		runtime := firstValid(values[swfRunTime], values[swfRequestedTime])
+		if procs <= 0 || runtime < 0 || values[swfSubmitTime] < 0 {
+			// cancelled before start, nothing to replay
+			continue
+		}
+
+		tasks, coresPerTask := int64(1), procs
+		if coresPerPod > 0 && procs > int64(coresPerPod) {
+			coresPerTask = int64(coresPerPod)
+			tasks = (procs + coresPerTask - 1) / coresPerTask
+		}
+		memKB := firstValid(values[swfRequestedMemory], values[swfUsedMemory])
+		res := swfResources(coresPerTask, memKB, memPerCore)
+		var lastRes v1.ResourceList
+		if lastCores := procs - (tasks-1)*coresPerTask; lastCores != coresPerTask {
+			lastRes = swfResources(lastCores, memKB, memPerCore)
+		}
+
+		queue := ""
+		if values[swfQueueNumber] >= 0 {
+			queue = fmt.Sprintf("queue-%d", values[swfQueueNumber])
+		}
+		jobs = append(jobs, Job{
+			ID:                fields[swfJobNumber],
+			Queue:             queue,
+			Submit:            time.Duration(values[swfSubmitTime]) * time.Second,
+			Runtime:           time.Duration(runtime) * time.Second,
+			Tasks:             int(tasks),
+			Resources:         res,
+			LastTaskResources: lastRes,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	normalizeSubmit(jobs)
+	return jobs, nil
+}
+
+// swfResources returns the resources of a pod of cores processors, memKB is the memory per
+// processor in KB, or -1 if missing.
+func swfResources(cores, memKB int64, memPerCore *resource.Quantity) v1.ResourceList {
+	res := v1.ResourceList{
+		v1.ResourceCPU: *resource.NewQuantity(cores, resource.DecimalSI),
+	}
+	if memKB > 0 {
+		res[v1.ResourceMemory] = *resource.NewQuantity(memKB*1024*cores, resource.BinarySI)
+	} else if memPerCore != nil {
+		res[v1.ResourceMemory] = *resource.NewQuantity(memPerCore.Value()*cores, resource.BinarySI)
+	}
+	return res
+}
+
+// firstValid returns the first value which is not missing, SWF records missing values as -1.
+func firstValid(values ...int64) int64 {
+	for _, v := range values {
+		if v >= 0 {
+			return v
+		}
+	}
+	return -1
+}
+
+func normalizeSubmit(jobs []Job) {
+	if len(jobs) == 0 {
+		return
+	}
+	first := jobs[0].Submit
+	for _, job := range jobs {
+		if job.Submit < first {
+			first = job.Submit
+		}
+	}
+	for i := range jobs {
+		jobs[i].Submit -= first
+	}
+}
@@ -0,0 +1,301 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	utilpointer "k8s.io/utils/pointer"
+
+	"github.com/D0m021ng/scheduler-simulator/pkg/simctl/generate"
+)
+
+// KubeletFlags directly reflect the information that CLI is gathering via flags.
+type KubeletFlags struct {
+	KubeConfigFlags *genericclioptions.ConfigFlags
+
+	LabelSelector string
+
+	LeaseDuration      time.Duration
+	LeaseRenewInterval time.Duration
+	NodeStatusInterval time.Duration
+
+	Workers int
+	QPS     float32
+	Burst   int
+
+	CompletePods        bool
+	RuntimeDistribution string
+	FailureProbability  float64
+	Seed                int64
+
+	genericclioptions.IOStreams
+}
+
+// KubeletOptions defines flags and other configuration parameters for the `kubelet` command
+type KubeletOptions struct {
+	Client kubernetes.Interface
+
+	// LabelSelector selects the simulated nodes, which are kept alive.
+	LabelSelector string
+
+	// LeaseDuration is the duration of the node leases, which are renewed every
+	// LeaseRenewInterval. The conditions of nodes are reported every NodeStatusInterval.
+	LeaseDuration      time.Duration
+	LeaseRenewInterval time.Duration
+	NodeStatusInterval time.Duration
+
+	// Workers is the count of nodes and pods which are updated in parallel.
+	Workers int
+
+	// lifecycle finishes the running pods after their runtime, if it is not nil.
+	lifecycle *podLifecycle
+
+	genericclioptions.IOStreams
+
+	nodeLister corelisters.NodeLister
+	podLister  corelisters.PodLister
+	nodeQueue  workqueue.RateLimitingInterface
+	podQueue   workqueue.RateLimitingInterface
+
+	lock           sync.Mutex
+	leases         map[string]*coordinationv1.Lease
+	statusReported map[string]time.Time
+}
+
+func NewKubeletFlags(ioStreams genericclioptions.IOStreams) *KubeletFlags {
+	return &KubeletFlags{
+		KubeConfigFlags: &genericclioptions.ConfigFlags{
+			Timeout:    utilpointer.String("0"),
+			KubeConfig: utilpointer.String(""),
+			APIServer:  utilpointer.String(""),
+		},
+		LabelSelector:      generate.SimulatorLabelKey + "=true",
+		LeaseDuration:      40 * time.Second,
+		LeaseRenewInterval: 10 * time.Second,
+		NodeStatusInterval: time.Minute,
+		Workers:            20,
+		QPS:                100,
+		Burst:              200,
+		Seed:               time.Now().UnixNano(),
+		IOStreams:          ioStreams,
+	}
+}
+
+// NewCmdKubelet creates the `kubelet` command
+func NewCmdKubelet(ioStreams genericclioptions.IOStreams) *cobra.Command {
+	flags := NewKubeletFlags(ioStreams)
+
+	cmd := &cobra.Command{
+		Use:                   "kubelet",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Keep simulated nodes ready and run the pods bound to them without containers"),
+		Run: func(cmd *cobra.Command, args []string) {
+			o, err := flags.ToOptions()
+			cmdutil.CheckErr(err)
+			cmdutil.CheckErr(o.Run(signalContext()))
+		},
+	}
+	flags.AddFlags(cmd)
+	return cmd
+}
+
+// AddFlags registers flags for a cli
+func (flags *KubeletFlags) AddFlags(cmd *cobra.Command) {
+	flags.KubeConfigFlags.AddFlags(cmd.Flags())
+
+	cmd.Flags().StringVarP(&flags.LabelSelector, "selector", "l", flags.LabelSelector, "The label selector of the simulated nodes.")
+	cmd.Flags().DurationVar(&flags.LeaseDuration, "lease-duration", flags.LeaseDuration, "The duration of the node leases.")
+	cmd.Flags().DurationVar(&flags.LeaseRenewInterval, "lease-renew-interval", flags.LeaseRenewInterval, "The interval of renewing the node leases.")
+	cmd.Flags().DurationVar(&flags.NodeStatusInterval, "node-status-interval", flags.NodeStatusInterval, "The interval of reporting the conditions of nodes.")
+	cmd.Flags().IntVar(&flags.Workers, "workers", flags.Workers, "The count of nodes and pods which are updated in parallel.")
+	cmd.Flags().Float32Var(&flags.QPS, "qps", flags.QPS, "The maximum requests per second sent to the api server.")
+	cmd.Flags().IntVar(&flags.Burst, "burst", flags.Burst, "The maximum burst of requests above --qps.")
+	cmd.Flags().BoolVar(&flags.CompletePods, "complete-pods", flags.CompletePods, "If true, running pods succeed or fail after the runtime in their "+generate.RuntimeAnnotationKey+" annotation, or else sampled from --runtime-distribution.")
+	cmd.Flags().StringVar(&flags.RuntimeDistribution, "runtime-distribution", flags.RuntimeDistribution, "The runtime of pods without a runtime annotation, one of fixed:D, uniform:MIN,MAX, exponential:MEAN or lognormal:MEDIAN,SIGMA. Such pods run until they are deleted if it is empty.")
+	cmd.Flags().Float64Var(&flags.FailureProbability, "failure-probability", flags.FailureProbability, "The probability of a pod to fail at the end of its runtime, instead of succeeding.")
+	cmd.Flags().Int64Var(&flags.Seed, "seed", flags.Seed, "The seed of the random runtimes and failures.")
+}
+
+// ToOptions converts from CLI inputs to runtime inputs
+func (flags *KubeletFlags) ToOptions() (*KubeletOptions, error) {
+	if _, err := labels.Parse(flags.LabelSelector); err != nil {
+		return nil, err
+	}
+	if flags.Workers < 1 {
+		return nil, fmt.Errorf("--workers must be at least 1")
+	}
+	if flags.LeaseRenewInterval <= 0 || flags.LeaseRenewInterval >= flags.LeaseDuration {
+		return nil, fmt.Errorf("--lease-renew-interval must be greater than 0 and less than --lease-duration")
+	}
+	if flags.NodeStatusInterval <= 0 {
+		return nil, fmt.Errorf("--node-status-interval must be greater than 0")
+	}
+	if flags.FailureProbability < 0 || flags.FailureProbability > 1 {
+		return nil, fmt.Errorf("--failure-probability must be between 0 and 1")
+	}
+	var lifecycle *podLifecycle
+	if flags.CompletePods {
+		var distribution RuntimeDistribution
+		if len(flags.RuntimeDistribution) > 0 {
+			var err error
+			if distribution, err = ParseRuntimeDistribution(flags.RuntimeDistribution); err != nil {
+				return nil, err
+			}
+		}
+		lifecycle = newPodLifecycle(distribution, flags.FailureProbability, flags.Seed)
+	}
+
+	config, err := flags.KubeConfigFlags.ToRESTConfig()
+	if err != nil {
+		return nil, err
+	}
+	config.QPS = flags.QPS
+	config.Burst = flags.Burst
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &KubeletOptions{
+		Client:             client,
+		LabelSelector:      flags.LabelSelector,
+		LeaseDuration:      flags.LeaseDuration,
+		LeaseRenewInterval: flags.LeaseRenewInterval,
+		NodeStatusInterval: flags.NodeStatusInterval,
+		Workers:            flags.Workers,
+		IOStreams:          flags.IOStreams,
+		lifecycle:          lifecycle,
+		leases:             map[string]*coordinationv1.Lease{},
+		statusReported:     map[string]time.Time{},
+	}, nil
+}
+
+// Run keeps the simulated nodes alive and acknowledges the pods bound to them, until ctx is done.
+func (o *KubeletOptions) Run(ctx context.Context) error {
+	nodeFactory := informers.NewSharedInformerFactoryWithOptions(o.Client, 0,
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) { options.LabelSelector = o.LabelSelector }))
+	nodeInformer := nodeFactory.Core().V1().Nodes()
+	o.nodeLister = nodeInformer.Lister()
+
+	// only the bound pods are of interest, the pods bound to other nodes are skipped by syncPod
+	podFactory := informers.NewSharedInformerFactoryWithOptions(o.Client, 0,
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) { options.FieldSelector = "spec.nodeName!=" }))
+	podInformer := podFactory.Core().V1().Pods()
+	o.podLister = podInformer.Lister()
+
+	o.nodeQueue = workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "nodes")
+	o.podQueue = workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "pods")
+	defer o.nodeQueue.ShutDown()
+	defer o.podQueue.ShutDown()
+
+	nodeInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			o.enqueue(o.nodeQueue, obj)
+			// the pods bound to a node before it was seen must be started now
+			o.enqueueNodePods(obj.(*corev1.Node).Name)
+		},
+	})
+	podInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { o.enqueue(o.podQueue, obj) },
+		UpdateFunc: func(_, obj interface{}) { o.enqueue(o.podQueue, obj) },
+	})
+
+	nodeFactory.Start(ctx.Done())
+	podFactory.Start(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), nodeInformer.Informer().HasSynced, podInformer.Informer().HasSynced) {
+		return fmt.Errorf("failed to sync the nodes and pods")
+	}
+	nodes, _ := o.nodeLister.List(labels.Everything())
+	fmt.Fprintf(o.Out, "Running %d simulated nodes matching %q\n", len(nodes), o.LabelSelector)
+
+	for i := 0; i < o.Workers; i++ {
+		go o.runWorker(o.nodeQueue, o.syncNode)
+		go o.runWorker(o.podQueue, o.syncPod)
+	}
+	go o.renewLeases(ctx)
+
+	<-ctx.Done()
+	return nil
+}
+
+func (o *KubeletOptions) enqueue(queue workqueue.RateLimitingInterface, obj interface{}) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		return
+	}
+	queue.Add(key)
+}
+
+func (o *KubeletOptions) enqueueNodePods(nodeName string) {
+	pods, err := o.podLister.List(labels.Everything())
+	if err != nil {
+		return
+	}
+	for _, pod := range pods {
+		if pod.Spec.NodeName == nodeName {
+			o.enqueue(o.podQueue, pod)
+		}
+	}
+}
+
+// runWorker syncs the keys of queue until it is shut down, and retries failed keys with backoff.
+func (o *KubeletOptions) runWorker(queue workqueue.RateLimitingInterface, sync func(key string) error) {
+	for {
+		item, shutdown := queue.Get()
+		if shutdown {
+			return
+		}
+		key := item.(string)
+		if err := sync(key); err != nil {
+			fmt.Fprintf(o.ErrOut, "Error syncing %s: %v\n", key, err)
+			queue.AddRateLimited(key)
+		} else {
+			queue.Forget(key)
+		}
+		queue.Done(key)
+	}
+}
+
+// signalContext returns a context which is canceled on SIGINT or SIGTERM.
+func signalContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ch
+		cancel()
+	}()
+	return ctx
+}
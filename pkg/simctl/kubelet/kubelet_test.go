@@ -0,0 +1,208 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/util/workqueue"
+
+	"github.com/D0m021ng/scheduler-simulator/pkg/simctl/generate"
+)
+
+// newTestKubelet returns a kubelet for objects, with its listers synced.
+func newTestKubelet(t *testing.T, objects ...interface{}) *KubeletOptions {
+	client := fake.NewSimpleClientset()
+	for _, obj := range objects {
+		var err error
+		switch obj := obj.(type) {
+		case *corev1.Node:
+			_, err = client.CoreV1().Nodes().Create(context.TODO(), obj, metav1.CreateOptions{})
+		case *corev1.Pod:
+			_, err = client.CoreV1().Pods(obj.Namespace).Create(context.TODO(), obj, metav1.CreateOptions{})
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	factory := informers.NewSharedInformerFactory(client, 0)
+	o := &KubeletOptions{
+		Client:             client,
+		LeaseDuration:      40 * time.Second,
+		LeaseRenewInterval: 10 * time.Second,
+		NodeStatusInterval: time.Minute,
+		nodeLister:         factory.Core().V1().Nodes().Lister(),
+		podLister:          factory.Core().V1().Pods().Lister(),
+		leases:             map[string]*coordinationv1.Lease{},
+		statusReported:     map[string]time.Time{},
+	}
+	stopCh := make(chan struct{})
+	t.Cleanup(func() { close(stopCh) })
+	factory.Start(stopCh)
+	factory.WaitForCacheSync(stopCh)
+	return o
+}
+
+func TestSyncNode(t *testing.T) {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "n1"}}
+	o := newTestKubelet(t, node)
+
+	for i := 0; i < 2; i++ {
+		if err := o.syncNode("n1"); err != nil {
+			t.Fatalf("sync %d: %v", i, err)
+		}
+	}
+	lease, err := o.Client.CoordinationV1().Leases(corev1.NamespaceNodeLease).Get(context.TODO(), "n1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("lease was not created: %v", err)
+	}
+	if lease.Spec.RenewTime == nil || *lease.Spec.HolderIdentity != "n1" {
+		t.Errorf("unexpected lease spec: %+v", lease.Spec)
+	}
+	if _, ok := o.statusReported["n1"]; !ok {
+		t.Errorf("node status was not reported")
+	}
+}
+
+func TestSyncPod(t *testing.T) {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "n1"},
+		Status:     corev1.NodeStatus{Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: "10.0.0.1"}}},
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "p1", Namespace: "default"},
+		Spec:       corev1.PodSpec{NodeName: "n1", Containers: []corev1.Container{{Name: "c", Image: "nginx"}}},
+		Status:     corev1.PodStatus{Phase: corev1.PodPending},
+	}
+	other := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "p2", Namespace: "default"},
+		Spec:       corev1.PodSpec{NodeName: "real-node"},
+		Status:     corev1.PodStatus{Phase: corev1.PodPending},
+	}
+	o := newTestKubelet(t, node, pod, other)
+
+	for _, key := range []string{"default/p1", "default/p2"} {
+		if err := o.syncPod(key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	started, _ := o.Client.CoreV1().Pods("default").Get(context.TODO(), "p1", metav1.GetOptions{})
+	if started.Status.Phase != corev1.PodRunning || started.Status.HostIP != "10.0.0.1" {
+		t.Errorf("pod was not started: %+v", started.Status)
+	}
+	if len(started.Status.ContainerStatuses) != 1 || !started.Status.ContainerStatuses[0].Ready {
+		t.Errorf("container is not ready: %+v", started.Status.ContainerStatuses)
+	}
+	skipped, _ := o.Client.CoreV1().Pods("default").Get(context.TODO(), "p2", metav1.GetOptions{})
+	if skipped.Status.Phase != corev1.PodPending {
+		t.Errorf("pod of a node which is not simulated was changed: %+v", skipped.Status)
+	}
+}
+
+func TestParseRuntimeDistribution(t *testing.T) {
+	valid := []string{"fixed:10m", "uniform:1m,5m", "exponential:30s", "lognormal:10m,0.5"}
+	for _, value := range valid {
+		distribution, err := ParseRuntimeDistribution(value)
+		if err != nil {
+			t.Errorf("ParseRuntimeDistribution(%q): %v", value, err)
+			continue
+		}
+		// the string of a distribution parses to the same distribution
+		if again, err := ParseRuntimeDistribution(distribution.String()); err != nil || again != distribution {
+			t.Errorf("ParseRuntimeDistribution(%q) = %s, which does not parse back: %v", value, distribution, err)
+		}
+	}
+	invalid := []string{"", "fixed", "fixed:-1m", "uniform:5m,1m", "normal:1m", "lognormal:1m,x"}
+	for _, value := range invalid {
+		if _, err := ParseRuntimeDistribution(value); err == nil {
+			t.Errorf("ParseRuntimeDistribution(%q) should fail", value)
+		}
+	}
+
+	uniform, _ := ParseRuntimeDistribution("uniform:1m,5m")
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		if d := uniform.Sample(r); d < time.Minute || d > 5*time.Minute {
+			t.Fatalf("uniform sample %v out of range", d)
+		}
+	}
+}
+
+func TestSyncRunningPod(t *testing.T) {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "n1"}}
+	running := func(name, runtime string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   "default",
+				UID:         types.UID(name),
+				Annotations: map[string]string{generate.RuntimeAnnotationKey: runtime},
+			},
+			Spec: corev1.PodSpec{NodeName: "n1", Containers: []corev1.Container{{Name: "c"}}},
+			Status: corev1.PodStatus{
+				Phase:             corev1.PodRunning,
+				StartTime:         &metav1.Time{Time: time.Now().Add(-time.Minute)},
+				ContainerStatuses: []corev1.ContainerStatus{{Name: "c", Ready: true}},
+			},
+		}
+	}
+	o := newTestKubelet(t, node, running("done", "30s"), running("busy", "1h"))
+	o.lifecycle = newPodLifecycle(nil, 1, 1)
+	o.podQueue = workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	defer o.podQueue.ShutDown()
+
+	for _, key := range []string{"default/done", "default/busy"} {
+		if err := o.syncPod(key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	done, _ := o.Client.CoreV1().Pods("default").Get(context.TODO(), "done", metav1.GetOptions{})
+	if done.Status.Phase != corev1.PodFailed {
+		t.Errorf("pod past its runtime should have failed, got %s", done.Status.Phase)
+	}
+	if terminated := done.Status.ContainerStatuses[0].State.Terminated; terminated == nil || terminated.ExitCode != 1 {
+		t.Errorf("container should have terminated with exit code 1: %+v", done.Status.ContainerStatuses[0].State)
+	}
+	busy, _ := o.Client.CoreV1().Pods("default").Get(context.TODO(), "busy", metav1.GetOptions{})
+	if busy.Status.Phase != corev1.PodRunning {
+		t.Errorf("pod within its runtime should be running, got %s", busy.Status.Phase)
+	}
+}
+
+func TestFailsOncePerPod(t *testing.T) {
+	l := newPodLifecycle(nil, 0.5, 1)
+	for i := 0; i < 100; i++ {
+		uid := types.UID(fmt.Sprint(i))
+		// a retried sync of the pod gets the same outcome
+		if fails := l.Fails(uid); l.Fails(uid) != fails {
+			t.Fatalf("pod %s was sampled twice", uid)
+		}
+		l.Forget(uid)
+	}
+	if len(l.fates) != 0 {
+		t.Errorf("the fates of %d finished pods were kept", len(l.fates))
+	}
+}
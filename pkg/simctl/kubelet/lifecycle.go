@@ -0,0 +1,267 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilpointer "k8s.io/utils/pointer"
+
+	"github.com/D0m021ng/scheduler-simulator/pkg/simctl/generate"
+)
+
+// RuntimeDistribution samples the runtimes of pods which have no runtime annotation.
+type RuntimeDistribution interface {
+	Sample(r *rand.Rand) time.Duration
+	String() string
+}
+
+type fixedRuntime struct{ d time.Duration }
+
+func (f fixedRuntime) Sample(*rand.Rand) time.Duration { return f.d }
+func (f fixedRuntime) String() string                  { return fmt.Sprintf("fixed:%v", f.d) }
+
+type uniformRuntime struct{ min, max time.Duration }
+
+func (u uniformRuntime) Sample(r *rand.Rand) time.Duration {
+	return u.min + time.Duration(r.Int63n(int64(u.max-u.min)+1))
+}
+func (u uniformRuntime) String() string { return fmt.Sprintf("uniform:%v,%v", u.min, u.max) }
+
+type exponentialRuntime struct{ mean time.Duration }
+
+func (e exponentialRuntime) Sample(r *rand.Rand) time.Duration {
+	return time.Duration(r.ExpFloat64() * float64(e.mean))
+}
+func (e exponentialRuntime) String() string { return fmt.Sprintf("exponential:%v", e.mean) }
+
+// lognormalRuntime has the given median, and sigma as the standard deviation of its logarithm.
+type lognormalRuntime struct {
+	median time.Duration
+	sigma  float64
+}
+
+func (l lognormalRuntime) Sample(r *rand.Rand) time.Duration {
+	return time.Duration(float64(l.median) * math.Exp(r.NormFloat64()*l.sigma))
+}
+func (l lognormalRuntime) String() string { return fmt.Sprintf("lognormal:%v,%g", l.median, l.sigma) }
+
+// ParseRuntimeDistribution parses a distribution of runtimes, one of
+// fixed:D, uniform:MIN,MAX, exponential:MEAN or lognormal:MEDIAN,SIGMA.
+func ParseRuntimeDistribution(value string) (RuntimeDistribution, error) {
+	name, args := value, ""
+	if i := strings.Index(value, ":"); i >= 0 {
+		name, args = value[:i], value[i+1:]
+	}
+	params := strings.Split(args, ",")
+	duration := func(i int) (time.Duration, error) {
+		d, err := time.ParseDuration(strings.TrimSpace(params[i]))
+		if err != nil || d < 0 {
+			return 0, fmt.Errorf("invalid duration %q in runtime distribution %q", params[i], value)
+		}
+		return d, nil
+	}
+
+	switch {
+	case name == "fixed" && len(params) == 1:
+		d, err := duration(0)
+		return fixedRuntime{d}, err
+	case name == "uniform" && len(params) == 2:
+		min, err := duration(0)
+		if err != nil {
+			return nil, err
+		}
+		max, err := duration(1)
+		if err != nil {
+			return nil, err
+		}
+		if max < min {
+			return nil, fmt.Errorf("the maximum of runtime distribution %q is less than its minimum", value)
+		}
+		return uniformRuntime{min, max}, nil
+	case name == "exponential" && len(params) == 1:
+		mean, err := duration(0)
+		return exponentialRuntime{mean}, err
+	case name == "lognormal" && len(params) == 2:
+		median, err := duration(0)
+		if err != nil {
+			return nil, err
+		}
+		sigma, err := strconv.ParseFloat(strings.TrimSpace(params[1]), 64)
+		if err != nil || sigma < 0 {
+			return nil, fmt.Errorf("invalid sigma %q in runtime distribution %q", params[1], value)
+		}
+		return lognormalRuntime{median, sigma}, nil
+	}
+	return nil, fmt.Errorf("invalid runtime distribution %q, expected one of fixed:D, uniform:MIN,MAX, exponential:MEAN or lognormal:MEDIAN,SIGMA", value)
+}
+
+// podLifecycle decides how long the pods run, and whether they fail.
+type podLifecycle struct {
+	// Distribution samples the runtimes of pods without the runtime annotation,
+	// if it is nil, such pods run until they are deleted.
+	Distribution RuntimeDistribution
+	// FailureProbability is the probability of a pod to fail at the end of its runtime.
+	FailureProbability float64
+
+	lock  sync.Mutex
+	rand  *rand.Rand
+	fates map[types.UID]*podFate
+}
+
+// podFate is the sampled runtime of a pod and whether it fails, each sampled once, so that
+// a pod whose sync is retried keeps them.
+type podFate struct {
+	runtime *time.Duration
+	fails   *bool
+}
+
+func newPodLifecycle(distribution RuntimeDistribution, failureProbability float64, seed int64) *podLifecycle {
+	return &podLifecycle{
+		Distribution:       distribution,
+		FailureProbability: failureProbability,
+		rand:               rand.New(rand.NewSource(seed)),
+		fates:              map[types.UID]*podFate{},
+	}
+}
+
+// fate returns the fate of the pod with uid, the lock must be held.
+func (l *podLifecycle) fate(uid types.UID) *podFate {
+	fate, ok := l.fates[uid]
+	if !ok {
+		fate = &podFate{}
+		l.fates[uid] = fate
+	}
+	return fate
+}
+
+// Runtime returns how long pod runs, from its runtime annotation or else sampled once
+// from the distribution. It returns false if the pod runs until it is deleted.
+func (l *podLifecycle) Runtime(pod *corev1.Pod) (time.Duration, bool, error) {
+	if value, ok := pod.Annotations[generate.RuntimeAnnotationKey]; ok {
+		runtime, err := time.ParseDuration(value)
+		if err != nil || runtime < 0 {
+			return 0, false, fmt.Errorf("invalid %s annotation %q", generate.RuntimeAnnotationKey, value)
+		}
+		return runtime, true, nil
+	}
+	if l.Distribution == nil {
+		return 0, false, nil
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	fate := l.fate(pod.UID)
+	if fate.runtime == nil {
+		runtime := l.Distribution.Sample(l.rand)
+		fate.runtime = &runtime
+	}
+	return *fate.runtime, true, nil
+}
+
+// Fails returns whether the pod with uid fails at the end of its runtime, sampled once.
+func (l *podLifecycle) Fails(uid types.UID) bool {
+	if l.FailureProbability <= 0 {
+		return false
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	fate := l.fate(uid)
+	if fate.fails == nil {
+		fails := l.rand.Float64() < l.FailureProbability
+		fate.fails = &fails
+	}
+	return *fate.fails
+}
+
+// Forget drops the sampled fate of a pod which has finished.
+func (l *podLifecycle) Forget(uid types.UID) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	delete(l.fates, uid)
+}
+
+// syncRunningPod finishes a running pod at the end of its runtime, or
+// queues it again for then.
+func (o *KubeletOptions) syncRunningPod(key string, pod *corev1.Pod) error {
+	runtime, ok, err := o.lifecycle.Runtime(pod)
+	if err != nil || !ok {
+		return err
+	}
+	started := pod.CreationTimestamp.Time
+	if pod.Status.StartTime != nil {
+		started = pod.Status.StartTime.Time
+	}
+	if remaining := time.Until(started.Add(runtime)); remaining > 0 {
+		o.podQueue.AddAfter(key, remaining)
+		return nil
+	}
+	if err := o.finishPod(pod, o.lifecycle.Fails(pod.UID)); err != nil {
+		return err
+	}
+	o.lifecycle.Forget(pod.UID)
+	return nil
+}
+
+// finishPod sets the status of pod to Succeeded, or to Failed if failed is true,
+// with all of its containers terminated.
+func (o *KubeletOptions) finishPod(pod *corev1.Pod, failed bool) error {
+	now := metav1.Now()
+	pod = pod.DeepCopy()
+	status := &pod.Status
+
+	phase, reason, exitCode := corev1.PodSucceeded, "Completed", int32(0)
+	if failed {
+		phase, reason, exitCode = corev1.PodFailed, "Error", 1
+	}
+	status.Phase = phase
+	for _, conditionType := range []corev1.PodConditionType{corev1.ContainersReady, corev1.PodReady} {
+		setPodCondition(status, conditionType, corev1.ConditionFalse, now)
+		for i := range status.Conditions {
+			if status.Conditions[i].Type == conditionType {
+				status.Conditions[i].Reason = "PodCompleted"
+			}
+		}
+	}
+	for i := range status.ContainerStatuses {
+		containerStatus := &status.ContainerStatuses[i]
+		startedAt := now
+		if containerStatus.State.Running != nil {
+			startedAt = containerStatus.State.Running.StartedAt
+		}
+		containerStatus.Ready = false
+		containerStatus.Started = utilpointer.BoolPtr(false)
+		containerStatus.State = corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+			ExitCode:    exitCode,
+			Reason:      reason,
+			StartedAt:   startedAt,
+			FinishedAt:  now,
+			ContainerID: containerStatus.ContainerID,
+		}}
+	}
+
+	_, err := o.Client.CoreV1().Pods(pod.Namespace).UpdateStatus(context.TODO(), pod, metav1.UpdateOptions{})
+	return err
+}
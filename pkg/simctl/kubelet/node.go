@@ -0,0 +1,191 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	utilpointer "k8s.io/utils/pointer"
+)
+
+// nodeConditions are the conditions of a healthy node, as reported by the kubelet.
+var nodeConditions = []struct {
+	Type    corev1.NodeConditionType
+	Status  corev1.ConditionStatus
+	Reason  string
+	Message string
+}{
+	{corev1.NodeReady, corev1.ConditionTrue, "KubeletReady", "kubelet is posting ready status"},
+	{corev1.NodeMemoryPressure, corev1.ConditionFalse, "KubeletHasSufficientMemory", "kubelet has sufficient memory available"},
+	{corev1.NodeDiskPressure, corev1.ConditionFalse, "KubeletHasNoDiskPressure", "kubelet has no disk pressure"},
+	{corev1.NodePIDPressure, corev1.ConditionFalse, "KubeletHasSufficientPID", "kubelet has sufficient PID available"},
+}
+
+// renewLeases queues every simulated node each LeaseRenewInterval, so that its lease is renewed.
+func (o *KubeletOptions) renewLeases(ctx context.Context) {
+	wait.Until(func() {
+		nodes, err := o.nodeLister.List(labels.Everything())
+		if err != nil {
+			return
+		}
+		for _, node := range nodes {
+			o.enqueue(o.nodeQueue, node)
+		}
+	}, o.LeaseRenewInterval, ctx.Done())
+}
+
+// syncNode renews the lease of a node, and reports its status if it is due.
+func (o *KubeletOptions) syncNode(name string) error {
+	node, err := o.nodeLister.Get(name)
+	if errors.IsNotFound(err) {
+		o.lock.Lock()
+		delete(o.leases, name)
+		delete(o.statusReported, name)
+		o.lock.Unlock()
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := o.renewLease(node); err != nil {
+		return err
+	}
+	o.lock.Lock()
+	reported, ok := o.statusReported[name]
+	o.lock.Unlock()
+	if ok && time.Since(reported) < o.NodeStatusInterval {
+		return nil
+	}
+	if err := o.reportNodeStatus(node); err != nil {
+		return err
+	}
+	o.lock.Lock()
+	o.statusReported[name] = time.Now()
+	o.lock.Unlock()
+	return nil
+}
+
+// renewLease renews the lease of node in the kube-node-lease namespace, creating it if it is
+// missing. The last lease is cached, so that a renewal is a single update.
+func (o *KubeletOptions) renewLease(node *corev1.Node) error {
+	leases := o.Client.CoordinationV1().Leases(corev1.NamespaceNodeLease)
+	o.lock.Lock()
+	lease := o.leases[node.Name]
+	o.lock.Unlock()
+
+	now := metav1.NewMicroTime(time.Now())
+	if lease == nil {
+		live, err := leases.Get(context.TODO(), node.Name, metav1.GetOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		if errors.IsNotFound(err) {
+			created, err := leases.Create(context.TODO(), o.newLease(node, now), metav1.CreateOptions{})
+			if err != nil {
+				return err
+			}
+			o.cacheLease(node.Name, created)
+			return nil
+		}
+		lease = live
+	}
+
+	lease = lease.DeepCopy()
+	lease.Spec.HolderIdentity = utilpointer.StringPtr(node.Name)
+	lease.Spec.LeaseDurationSeconds = utilpointer.Int32Ptr(int32(o.LeaseDuration.Seconds()))
+	lease.Spec.RenewTime = &now
+	updated, err := leases.Update(context.TODO(), lease, metav1.UpdateOptions{})
+	if err != nil {
+		// the lease is fetched again by the retry, e.g. after a conflict
+		o.cacheLease(node.Name, nil)
+		return err
+	}
+	o.cacheLease(node.Name, updated)
+	return nil
+}
+
+func (o *KubeletOptions) cacheLease(name string, lease *coordinationv1.Lease) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	if lease == nil {
+		delete(o.leases, name)
+		return
+	}
+	o.leases[name] = lease
+}
+
+// newLease returns the lease of node, which is deleted together with the node.
+func (o *KubeletOptions) newLease(node *corev1.Node, now metav1.MicroTime) *coordinationv1.Lease {
+	return &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      node.Name,
+			Namespace: corev1.NamespaceNodeLease,
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: corev1.SchemeGroupVersion.String(),
+				Kind:       "Node",
+				Name:       node.Name,
+				UID:        node.UID,
+			}},
+		},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       utilpointer.StringPtr(node.Name),
+			LeaseDurationSeconds: utilpointer.Int32Ptr(int32(o.LeaseDuration.Seconds())),
+			RenewTime:            &now,
+		},
+	}
+}
+
+// reportNodeStatus patches the conditions of a healthy node into the status of node, and sets
+// its allocatable resources to its capacity if they are missing.
+func (o *KubeletOptions) reportNodeStatus(node *corev1.Node) error {
+	now := metav1.Now()
+	var conditions []corev1.NodeCondition
+	for _, c := range nodeConditions {
+		condition := corev1.NodeCondition{
+			Type:               c.Type,
+			Status:             c.Status,
+			Reason:             c.Reason,
+			Message:            c.Message,
+			LastHeartbeatTime:  now,
+			LastTransitionTime: now,
+		}
+		for _, existing := range node.Status.Conditions {
+			if existing.Type == c.Type && existing.Status == c.Status {
+				condition.LastTransitionTime = existing.LastTransitionTime
+			}
+		}
+		conditions = append(conditions, condition)
+	}
+
+	status := map[string]interface{}{"conditions": conditions}
+	if len(node.Status.Allocatable) == 0 && len(node.Status.Capacity) > 0 {
+		status["allocatable"] = node.Status.Capacity
+	}
+	data, err := json.Marshal(map[string]interface{}{"status": status})
+	if err != nil {
+		return err
+	}
+	_, err = o.Client.CoreV1().Nodes().PatchStatus(context.TODO(), node.Name, data)
+	return err
+}
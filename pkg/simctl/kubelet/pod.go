@@ -0,0 +1,170 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	utilpointer "k8s.io/utils/pointer"
+)
+
+// syncPod acknowledges a pod bound to a simulated node: a new pod is started as if
+// its containers were running, a running pod is finished after its runtime if pods
+// are completed, and a pod which is being deleted is removed.
+func (o *KubeletOptions) syncPod(key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return nil
+	}
+	pod, err := o.podLister.Pods(namespace).Get(name)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	node, err := o.nodeLister.Get(pod.Spec.NodeName)
+	if errors.IsNotFound(err) {
+		// the pod runs on a node which is not simulated
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if pod.DeletionTimestamp != nil {
+		return o.removePod(pod)
+	}
+	switch pod.Status.Phase {
+	case corev1.PodPending, "":
+		return o.startPod(pod, node)
+	case corev1.PodRunning:
+		if o.lifecycle != nil {
+			return o.syncRunningPod(key, pod)
+		}
+	}
+	return nil
+}
+
+// startPod sets the status of pod to Running, with all of its containers running and ready.
+func (o *KubeletOptions) startPod(pod *corev1.Pod, node *corev1.Node) error {
+	now := metav1.Now()
+	pod = pod.DeepCopy()
+	status := &pod.Status
+	status.Phase = corev1.PodRunning
+	status.Reason, status.Message = "", ""
+	status.StartTime = &now
+	status.HostIP = nodeIP(node)
+	// simulated pods have no network, they share the address of their node
+	status.PodIP = status.HostIP
+	status.PodIPs = nil
+	if len(status.PodIP) > 0 {
+		status.PodIPs = []corev1.PodIP{{IP: status.PodIP}}
+	}
+
+	for _, conditionType := range []corev1.PodConditionType{corev1.PodScheduled, corev1.PodInitialized, corev1.ContainersReady, corev1.PodReady} {
+		setPodCondition(status, conditionType, corev1.ConditionTrue, now)
+	}
+
+	status.InitContainerStatuses = nil
+	for _, container := range pod.Spec.InitContainers {
+		status.InitContainerStatuses = append(status.InitContainerStatuses, corev1.ContainerStatus{
+			Name:        container.Name,
+			Image:       container.Image,
+			ImageID:     container.Image,
+			ContainerID: containerID(pod, container.Name),
+			Ready:       true,
+			State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+				Reason:     "Completed",
+				StartedAt:  now,
+				FinishedAt: now,
+			}},
+		})
+	}
+	status.ContainerStatuses = nil
+	for _, container := range pod.Spec.Containers {
+		status.ContainerStatuses = append(status.ContainerStatuses, corev1.ContainerStatus{
+			Name:        container.Name,
+			Image:       container.Image,
+			ImageID:     container.Image,
+			ContainerID: containerID(pod, container.Name),
+			Ready:       true,
+			Started:     utilpointer.BoolPtr(true),
+			State:       corev1.ContainerState{Running: &corev1.ContainerStateRunning{StartedAt: now}},
+		})
+	}
+
+	_, err := o.Client.CoreV1().Pods(pod.Namespace).UpdateStatus(context.TODO(), pod, metav1.UpdateOptions{})
+	return err
+}
+
+// removePod deletes a terminating pod right away, as the kubelet does once its containers are stopped.
+func (o *KubeletOptions) removePod(pod *corev1.Pod) error {
+	options := metav1.DeleteOptions{
+		GracePeriodSeconds: utilpointer.Int64Ptr(0),
+		Preconditions:      metav1.NewUIDPreconditions(string(pod.UID)),
+	}
+	err := o.Client.CoreV1().Pods(pod.Namespace).Delete(context.TODO(), pod.Name, options)
+	if err != nil && !errors.IsNotFound(err) && !errors.IsConflict(err) {
+		return err
+	}
+	return nil
+}
+
+// setPodCondition sets the condition of conditionType in status, keeping its
+// transition time if its status does not change.
+func setPodCondition(status *corev1.PodStatus, conditionType corev1.PodConditionType, conditionStatus corev1.ConditionStatus, now metav1.Time) {
+	for i := range status.Conditions {
+		condition := &status.Conditions[i]
+		if condition.Type != conditionType {
+			continue
+		}
+		if condition.Status != conditionStatus {
+			condition.Status = conditionStatus
+			condition.LastTransitionTime = now
+		}
+		condition.Reason, condition.Message = "", ""
+		return
+	}
+	status.Conditions = append(status.Conditions, corev1.PodCondition{
+		Type:               conditionType,
+		Status:             conditionStatus,
+		LastTransitionTime: now,
+	})
+}
+
+// nodeIP returns the internal address of node, or its first address.
+func nodeIP(node *corev1.Node) string {
+	for _, address := range node.Status.Addresses {
+		if address.Type == corev1.NodeInternalIP {
+			return address.Address
+		}
+	}
+	for _, address := range node.Status.Addresses {
+		if address.Type == corev1.NodeExternalIP {
+			return address.Address
+		}
+	}
+	return ""
+}
+
+func containerID(pod *corev1.Pod, container string) string {
+	return fmt.Sprintf("simctl://%s/%s", pod.UID, container)
+}
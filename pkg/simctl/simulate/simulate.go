@@ -0,0 +1,246 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulate
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/D0m021ng/scheduler-simulator/pkg/simctl/generate"
+	"github.com/D0m021ng/scheduler-simulator/pkg/simulator"
+	"github.com/D0m021ng/scheduler-simulator/pkg/simulator/upstream"
+	"github.com/D0m021ng/scheduler-simulator/pkg/simulator/volcano"
+)
+
+type simulateFlags struct {
+	NodeFiles []string
+	PodFiles  []string
+	Output    string
+
+	Scheduler       string
+	SchedulerConfig string
+	VolcanoConf     string
+	Autoscaler      string
+
+	Timeline      string
+	PreemptionLog string
+	Period        time.Duration
+	Until         time.Duration
+}
+
+var simFlags = &simulateFlags{}
+
+// InitSimulateFlags is used to init all flags during simulate scheduling.
+func InitSimulateFlags(cmd *cobra.Command) {
+
+	cmd.Flags().StringSliceVarP(&simFlags.NodeFiles, "nodes", "", nil, "the files of nodes to schedule pods to")
+	cmd.Flags().StringSliceVarP(&simFlags.PodFiles, "pods", "", nil, "the files of pods to schedule")
+	cmd.Flags().StringVarP(&simFlags.Output, "output", "o", "simulated-pods.yaml", "the name of the file of scheduled pods")
+	cmd.Flags().StringVarP(&simFlags.Scheduler, "scheduler", "", "default", "the scheduler to simulate, one of default, volcano and upstream, the kube-scheduler of k8s.io/kubernetes")
+	cmd.Flags().StringVarP(&simFlags.SchedulerConfig, "scheduler-config", "", "", "the KubeSchedulerConfiguration of the default or upstream scheduler, pods are scheduled by the profiles of their schedulerName if set")
+	cmd.Flags().StringVarP(&simFlags.VolcanoConf, "volcano-conf", "", "", "the volcano-scheduler.conf of the volcano scheduler, the default configuration of volcano if empty")
+	cmd.Flags().StringVarP(&simFlags.Autoscaler, "autoscaler", "", "", "the node pools of the simulated cluster autoscaler with their shapes, sizes and provisioning delays, no autoscaling if empty")
+	cmd.Flags().StringVarP(&simFlags.Timeline, "timeline", "", "", "the csv file of nodes, pending and running pods and utilization over time, none if empty")
+	cmd.Flags().StringVarP(&simFlags.PreemptionLog, "preemption-log", "", "", "the csv file of the preemptions of the default scheduler with their preemptor, node and victims, none if empty. Victims are evicted back to pending and rescheduled, not deleted")
+	cmd.Flags().DurationVarP(&simFlags.Period, "period", "", 0, "the interval of scheduling cycles on the simulated clock, 0 schedules at every arrival and completion")
+	cmd.Flags().DurationVarP(&simFlags.Until, "until", "", 0, "the simulated time to stop at, 0 runs until all pods completed or no more can be scheduled")
+}
+
+// Simulate schedules the pods of the given files on their nodes in memory, pods arrive and
+// complete on a simulated clock by their arrival-time and runtime annotations. It writes
+// all pods with the nodes they were bound to, or why they are unschedulable.
+func Simulate() error {
+	if len(simFlags.NodeFiles) == 0 {
+		return fmt.Errorf("must specify --nodes")
+	}
+	if simFlags.Period < 0 || simFlags.Until < 0 {
+		return fmt.Errorf("--period and --until must not be negative")
+	}
+	algorithm, err := newAlgorithm()
+	if err != nil {
+		return err
+	}
+	if closer, ok := algorithm.(interface{ Close() }); ok {
+		defer closer.Close()
+	}
+	cluster, err := simulator.LoadFiles(append(simFlags.NodeFiles, simFlags.PodFiles...)...)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Simulate scheduling of %d pod(s) on %d node(s)\n", len(cluster.Pods()), len(cluster.Nodes()))
+
+	engine := simulator.NewEngine(cluster, algorithm)
+	engine.Period, engine.Until = simFlags.Period, simFlags.Until
+	if simFlags.Autoscaler != "" {
+		conf, err := simulator.LoadAutoscalerConfiguration(simFlags.Autoscaler)
+		if err != nil {
+			return err
+		}
+		engine.Autoscaler = simulator.NewAutoscaler(conf)
+	}
+	start := time.Now()
+	report, err := engine.Run()
+	if err != nil {
+		return err
+	}
+	printReport(report, time.Since(start))
+	if engine.Autoscaler != nil {
+		printNodes(report)
+	}
+
+	if simFlags.Timeline != "" {
+		if err := writeTimeline(report, simFlags.Timeline); err != nil {
+			return err
+		}
+	}
+	if simFlags.PreemptionLog != "" {
+		if err := writePreemptionLog(report, simFlags.PreemptionLog); err != nil {
+			return err
+		}
+	}
+
+	var objs []interface{}
+	for _, pod := range cluster.Pods() {
+		objs = append(objs, pod)
+	}
+	fmt.Printf("Write %d pod(s) to %s\n", len(objs), simFlags.Output)
+	return generate.WriteYamlFile(simFlags.Output, objs)
+}
+
+// newAlgorithm returns the scheduler selected by --scheduler.
+func newAlgorithm() (simulator.Algorithm, error) {
+	switch simFlags.Scheduler {
+	case "default":
+		if simFlags.SchedulerConfig == "" {
+			return simulator.NewDefaultScheduler(), nil
+		}
+		profiles, err := simulator.LoadSchedulerConfiguration(simFlags.SchedulerConfig)
+		if err != nil {
+			return nil, err
+		}
+		if len(profiles.Ignored) > 0 {
+			fmt.Printf("Ignore plugins which are not simulated: %s\n", strings.Join(profiles.Ignored, ", "))
+		}
+		return profiles, nil
+	case "volcano":
+		if simFlags.SchedulerConfig != "" {
+			return nil, fmt.Errorf("--scheduler-config only applies to the default and upstream schedulers")
+		}
+		conf, err := volcano.LoadConfiguration(simFlags.VolcanoConf)
+		if err != nil {
+			return nil, err
+		}
+		if len(conf.Ignored) > 0 {
+			fmt.Printf("Ignore volcano configuration keys which are not simulated: %s\n", strings.Join(conf.Ignored, ", "))
+		}
+		return volcano.New(conf), nil
+	case "upstream":
+		conf, err := upstream.LoadConfiguration(simFlags.SchedulerConfig)
+		if err != nil {
+			return nil, err
+		}
+		return upstream.New(conf)
+	}
+	return nil, fmt.Errorf("unknown scheduler %q, must be one of default, volcano and upstream", simFlags.Scheduler)
+}
+
+func printReport(report *simulator.Report, elapsed time.Duration) {
+	started, finished := report.Counts()
+	fmt.Printf("Simulated %s in %s with %d scheduling cycle(s)\n", report.End, elapsed.Round(time.Millisecond), report.Cycles)
+	fmt.Printf("Started %d pod(s), %d pod(s) completed, %d pod(s) are unschedulable\n",
+		started, finished, len(report.Unschedulable))
+	if waits := report.WaitTimes(); len(waits) > 0 && waits[len(waits)-1] > 0 {
+		var total time.Duration
+		for _, wait := range waits {
+			total += wait
+		}
+		fmt.Printf("Wait time of pods: mean %s, p50 %s, p90 %s, p99 %s, max %s\n",
+			total/time.Duration(len(waits)), simulator.Percentile(waits, 50), simulator.Percentile(waits, 90),
+			simulator.Percentile(waits, 99), waits[len(waits)-1])
+	}
+
+	if len(report.Preemptions) > 0 {
+		victims := 0
+		for _, preemption := range report.Preemptions {
+			victims += len(preemption.Victims)
+		}
+		fmt.Printf("Preempted %d pod(s) to schedule %d pod(s)\n", victims, len(report.Preemptions))
+	}
+
+	utilization := report.AverageUtilization()
+	var names []string
+	for name := range utilization {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+	var usages []string
+	for _, name := range names {
+		usages = append(usages, fmt.Sprintf("%s %.1f%%", name, utilization[corev1.ResourceName(name)]*100))
+	}
+	if len(usages) > 0 {
+		fmt.Printf("Average requested resources of nodes: %s\n", strings.Join(usages, ", "))
+	}
+
+	if len(report.Unschedulable) == 0 {
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "NAMESPACE\tNAME\tREASON")
+	for _, placement := range report.Unschedulable {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", placement.Pod.Namespace, placement.Pod.Name, placement.Reason)
+	}
+	w.Flush()
+}
+
+func printNodes(report *simulator.Report) {
+	least, most := 0, 0
+	for i, sample := range report.Timeline {
+		if i == 0 || sample.Nodes < least {
+			least = sample.Nodes
+		}
+		if sample.Nodes > most {
+			most = sample.Nodes
+		}
+	}
+	fmt.Printf("Autoscaler added %d node(s) and removed %d node(s), %d to %d node(s) with %.1f node-hour(s)\n",
+		report.NodesAdded, report.NodesRemoved, least, most, report.NodeHours())
+}
+
+func writeTimeline(report *simulator.Report, filename string) error {
+	f, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("error opening/creating file: %v", err)
+	}
+	defer f.Close()
+	fmt.Printf("Write %d sample(s) of the timeline to %s\n", len(report.Timeline), filename)
+	return report.WriteTimeline(f)
+}
+
+func writePreemptionLog(report *simulator.Report, filename string) error {
+	f, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("error opening/creating file: %v", err)
+	}
+	defer f.Close()
+	fmt.Printf("Write %d preemption(s) to %s\n", len(report.Preemptions), filename)
+	return report.WritePreemptions(f)
+}
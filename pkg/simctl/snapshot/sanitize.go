@@ -0,0 +1,130 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package snapshot
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const lastAppliedAnnotationKey = "kubectl.kubernetes.io/last-applied-configuration"
+
+var (
+	// serverManagedFields are set by the api server, and rejected or ignored when an object is created.
+	serverManagedFields = [][]string{
+		{"metadata", "uid"},
+		{"metadata", "resourceVersion"},
+		{"metadata", "selfLink"},
+		{"metadata", "creationTimestamp"},
+		{"metadata", "generation"},
+		{"metadata", "managedFields"},
+		{"metadata", "deletionTimestamp"},
+		{"metadata", "deletionGracePeriodSeconds"},
+		// the owners, such as ReplicaSets and Jobs, are not exported, and the garbage collector
+		// would delete the re-applied objects.
+		{"metadata", "ownerReferences"},
+	}
+
+	// serviceAccountVolumePrefixes are the names of the token volumes which the service account
+	// admission adds to pods.
+	serviceAccountVolumePrefixes = []string{"default-token-", "kube-api-access-"}
+)
+
+// Sanitize strips the fields of obj which are managed by the api server, so that it re-applies cleanly.
+func Sanitize(obj *unstructured.Unstructured) {
+	for _, field := range serverManagedFields {
+		unstructured.RemoveNestedField(obj.Object, field...)
+	}
+	if annotations := obj.GetAnnotations(); annotations != nil {
+		delete(annotations, lastAppliedAnnotationKey)
+		if len(annotations) == 0 {
+			annotations = nil
+		}
+		obj.SetAnnotations(annotations)
+	}
+
+	switch obj.GetKind() {
+	case "Namespace":
+		unstructured.RemoveNestedField(obj.Object, "status")
+	case "Node":
+		// the images and volumes are large, and meaningless without a kubelet
+		unstructured.RemoveNestedField(obj.Object, "status", "images")
+		unstructured.RemoveNestedField(obj.Object, "status", "volumesInUse")
+		unstructured.RemoveNestedField(obj.Object, "status", "volumesAttached")
+	case "Pod":
+		sanitizePod(obj)
+	}
+}
+
+// sanitizePod removes the service account of pod, and the token volumes mounted for it,
+// as neither the service account nor its secrets are exported.
+func sanitizePod(pod *unstructured.Unstructured) {
+	unstructured.RemoveNestedField(pod.Object, "spec", "serviceAccountName")
+	unstructured.RemoveNestedField(pod.Object, "spec", "serviceAccount")
+
+	volumes, _, _ := unstructured.NestedSlice(pod.Object, "spec", "volumes")
+	removed := map[string]bool{}
+	var kept []interface{}
+	for _, volume := range volumes {
+		name, _, _ := unstructured.NestedString(volume.(map[string]interface{}), "name")
+		if isServiceAccountVolume(name) {
+			removed[name] = true
+			continue
+		}
+		kept = append(kept, volume)
+	}
+	if len(removed) == 0 {
+		return
+	}
+	if len(kept) == 0 {
+		unstructured.RemoveNestedField(pod.Object, "spec", "volumes")
+	} else {
+		_ = unstructured.SetNestedSlice(pod.Object, kept, "spec", "volumes")
+	}
+
+	for _, field := range []string{"initContainers", "containers"} {
+		containers, found, _ := unstructured.NestedSlice(pod.Object, "spec", field)
+		if !found {
+			continue
+		}
+		for _, c := range containers {
+			container := c.(map[string]interface{})
+			mounts, _, _ := unstructured.NestedSlice(container, "volumeMounts")
+			var keptMounts []interface{}
+			for _, mount := range mounts {
+				name, _, _ := unstructured.NestedString(mount.(map[string]interface{}), "name")
+				if !removed[name] {
+					keptMounts = append(keptMounts, mount)
+				}
+			}
+			if len(keptMounts) == 0 {
+				delete(container, "volumeMounts")
+			} else {
+				container["volumeMounts"] = keptMounts
+			}
+		}
+		_ = unstructured.SetNestedSlice(pod.Object, containers, "spec", field)
+	}
+}
+
+func isServiceAccountVolume(name string) bool {
+	for _, prefix := range serviceAccountVolumePrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
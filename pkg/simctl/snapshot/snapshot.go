@@ -0,0 +1,222 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package snapshot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/klog/v2"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	utilpointer "k8s.io/utils/pointer"
+
+	"github.com/D0m021ng/scheduler-simulator/pkg/simctl/anonymize"
+	"github.com/D0m021ng/scheduler-simulator/pkg/simctl/generate"
+)
+
+// snapshotKinds are exported in this order, so that `simctl apply` creates
+// the objects which others depend on first.
+var snapshotKinds = []schema.GroupVersionKind{
+	{Version: "v1", Kind: "Namespace"},
+	{Group: "scheduling.k8s.io", Version: "v1", Kind: "PriorityClass"},
+	{Group: "scheduling.volcano.sh", Version: "v1beta1", Kind: "Queue"},
+	{Version: "v1", Kind: "Node"},
+	{Version: "v1", Kind: "ResourceQuota"},
+	{Group: "scheduling.volcano.sh", Version: "v1beta1", Kind: "PodGroup"},
+	{Version: "v1", Kind: "Pod"},
+}
+
+// SnapshotFlags directly reflect the information that CLI is gathering via flags.
+type SnapshotFlags struct {
+	KubeConfigFlags *genericclioptions.ConfigFlags
+
+	Output            string
+	ExcludeNamespaces []string
+	Anonymize         bool
+	AnonymizeRules    string
+
+	genericclioptions.IOStreams
+}
+
+// SnapshotOptions defines flags and other configuration parameters for the `snapshot` command
+type SnapshotOptions struct {
+	DynamicClient dynamic.Interface
+	Mapper        meta.RESTMapper
+
+	Output string
+	// Namespace limits namespaced objects to a single namespace, all namespaces are exported if empty.
+	Namespace         string
+	ExcludeNamespaces sets.String
+	// Anonymizer hashes the identifiers of exported objects, if it is set.
+	Anonymizer *anonymize.Anonymizer
+
+	genericclioptions.IOStreams
+}
+
+func NewSnapshotFlags(ioStreams genericclioptions.IOStreams) *SnapshotFlags {
+	return &SnapshotFlags{
+		KubeConfigFlags: &genericclioptions.ConfigFlags{
+			Timeout:    utilpointer.String("0"),
+			KubeConfig: utilpointer.String(""),
+			APIServer:  utilpointer.String(""),
+			Namespace:  utilpointer.String(""),
+		},
+		Output:            "snapshot.yaml",
+		ExcludeNamespaces: []string{"kube-system", "kube-public", "kube-node-lease"},
+		IOStreams:         ioStreams,
+	}
+}
+
+// NewCmdSnapshot creates the `snapshot` command
+func NewCmdSnapshot(ioStreams genericclioptions.IOStreams) *cobra.Command {
+	flags := NewSnapshotFlags(ioStreams)
+
+	cmd := &cobra.Command{
+		Use:                   "snapshot [-o FILENAME]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Export the nodes and workloads of a cluster as test data"),
+		Run: func(cmd *cobra.Command, args []string) {
+			o, err := flags.ToOptions(cmd, args)
+			cmdutil.CheckErr(err)
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+	flags.AddFlags(cmd)
+	return cmd
+}
+
+// AddFlags registers flags for a cli
+func (flags *SnapshotFlags) AddFlags(cmd *cobra.Command) {
+	flags.KubeConfigFlags.AddFlags(cmd.Flags())
+	cmd.Flags().StringVarP(&flags.Output, "output", "o", flags.Output, "the file to write the snapshot to")
+	cmd.Flags().StringSliceVar(&flags.ExcludeNamespaces, "exclude-namespaces", flags.ExcludeNamespaces,
+		"namespaces which are not exported")
+	cmd.Flags().BoolVar(&flags.Anonymize, "anonymize", flags.Anonymize,
+		"hash node names, namespaces, labels and other identifiers of exported objects")
+	cmd.Flags().StringVar(&flags.AnonymizeRules, "anonymize-rules", flags.AnonymizeRules,
+		"the yaml file of allow/deny rules for --anonymize, the default rules are used if empty")
+}
+
+// ToOptions converts from CLI inputs to runtime inputs
+func (flags *SnapshotFlags) ToOptions(cmd *cobra.Command, args []string) (*SnapshotOptions, error) {
+	f := cmdutil.NewFactory(flags.KubeConfigFlags)
+	dynamicClient, err := f.DynamicClient()
+	if err != nil {
+		return nil, err
+	}
+	mapper, err := f.ToRESTMapper()
+	if err != nil {
+		return nil, err
+	}
+
+	o := &SnapshotOptions{
+		DynamicClient:     dynamicClient,
+		Mapper:            mapper,
+		Output:            flags.Output,
+		ExcludeNamespaces: sets.NewString(flags.ExcludeNamespaces...),
+		IOStreams:         flags.IOStreams,
+	}
+	if flags.Anonymize || flags.AnonymizeRules != "" {
+		rules, err := anonymize.LoadRules(flags.AnonymizeRules)
+		if err != nil {
+			return nil, err
+		}
+		o.Anonymizer = anonymize.NewAnonymizer(rules)
+	}
+	// export all namespaces, unless one is given explicitly
+	if cmd.Flags().Changed("namespace") {
+		o.Namespace = *flags.KubeConfigFlags.Namespace
+	}
+	return o, nil
+}
+
+func (o *SnapshotOptions) Run() error {
+	objs, err := o.GetObjects()
+	if err != nil {
+		return err
+	}
+	if err := generate.WriteYamlFile(o.Output, objs); err != nil {
+		return err
+	}
+	fmt.Fprintf(o.Out, "Snapshot %d object(s) to %s\n", len(objs), o.Output)
+	return nil
+}
+
+// GetObjects lists the objects of all snapshot kinds, stripped of server-managed fields.
+// Kinds which are not served by the cluster, such as the volcano CRDs, are skipped.
+func (o *SnapshotOptions) GetObjects() ([]interface{}, error) {
+	var objs []interface{}
+	for _, gvk := range snapshotKinds {
+		mapping, err := o.Mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			if meta.IsNoMatchError(err) {
+				klog.V(2).Infof("skip %s, which is not served by the cluster", gvk.Kind)
+				continue
+			}
+			return nil, err
+		}
+
+		var client dynamic.ResourceInterface = o.DynamicClient.Resource(mapping.Resource)
+		namespaced := mapping.Scope.Name() == meta.RESTScopeNameNamespace
+		if namespaced && o.Namespace != "" {
+			client = o.DynamicClient.Resource(mapping.Resource).Namespace(o.Namespace)
+		}
+		list, err := client.List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("listing %s: %v", mapping.Resource.Resource, err)
+		}
+
+		count := 0
+		for i := range list.Items {
+			item := &list.Items[i]
+			if o.excluded(item, namespaced) {
+				continue
+			}
+			item.SetAPIVersion(gvk.GroupVersion().String())
+			item.SetKind(gvk.Kind)
+			Sanitize(item)
+			if o.Anonymizer != nil {
+				o.Anonymizer.Anonymize(item)
+			}
+			objs = append(objs, item)
+			count++
+		}
+		fmt.Fprintf(o.Out, "Export %d %s(s)\n", count, strings.ToLower(gvk.Kind))
+	}
+	return objs, nil
+}
+
+func (o *SnapshotOptions) excluded(obj *unstructured.Unstructured, namespaced bool) bool {
+	if namespaced {
+		return o.ExcludeNamespaces.Has(obj.GetNamespace())
+	}
+	if obj.GetKind() == "Namespace" {
+		if o.Namespace != "" {
+			return obj.GetName() != o.Namespace
+		}
+		return o.ExcludeNamespaces.Has(obj.GetName())
+	}
+	return false
+}
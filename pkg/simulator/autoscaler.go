@@ -0,0 +1,433 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulator
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/D0m021ng/scheduler-simulator/pkg/simctl/generate"
+)
+
+const (
+	defaultScanInterval                  = 10 * time.Second
+	defaultScaleDownUnneededTime         = 10 * time.Minute
+	defaultScaleDownUtilizationThreshold = 0.5
+)
+
+// AutoscalerConfiguration are the node pools of the Autoscaler and its settings, named like the
+// flags of cluster-autoscaler.
+type AutoscalerConfiguration struct {
+	NodePools []NodePool `json:"nodePools"`
+	// ScanInterval is how often nodes are checked for scale down, 10s if zero.
+	ScanInterval metav1.Duration `json:"scanInterval,omitempty"`
+	// ScaleDownUnneededTime is how long a node must be unneeded before it is removed, 10m if zero.
+	ScaleDownUnneededTime metav1.Duration `json:"scaleDownUnneededTime,omitempty"`
+	// ScaleDownUtilizationThreshold is the ratio of the requested to the allocatable cpu and memory
+	// of a node below which it is unneeded, if its pods fit on other nodes. 0.5 if zero.
+	ScaleDownUtilizationThreshold float64 `json:"scaleDownUtilizationThreshold,omitempty"`
+}
+
+// NodePool is a group of nodes of the same shape which are added and removed together by the
+// Autoscaler, between MinSize and MaxSize nodes. Its nodes have the NodePoolLabelKey label.
+type NodePool struct {
+	Name    string `json:"name"`
+	MinSize int    `json:"minSize,omitempty"`
+	MaxSize int    `json:"maxSize"`
+	// ProvisioningDelay is how long a node takes to join the cluster once it is requested.
+	ProvisioningDelay metav1.Duration `json:"provisioningDelay,omitempty"`
+	// Resources and Labels are the shape of the nodes, as with simctl generate node.
+	Resources map[string]string `json:"resources"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Taints    []corev1.Taint    `json:"taints,omitempty"`
+}
+
+// LoadAutoscalerConfiguration reads an AutoscalerConfiguration from filename.
+func LoadAutoscalerConfiguration(filename string) (*AutoscalerConfiguration, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return ParseAutoscalerConfiguration(data)
+}
+
+// ParseAutoscalerConfiguration parses and validates an AutoscalerConfiguration, with the defaults of cluster-autoscaler.
+func ParseAutoscalerConfiguration(data []byte) (*AutoscalerConfiguration, error) {
+	conf := &AutoscalerConfiguration{}
+	if err := yaml.Unmarshal(data, conf); err != nil {
+		return nil, err
+	}
+	if len(conf.NodePools) == 0 {
+		return nil, fmt.Errorf("no node pools")
+	}
+	names := map[string]bool{}
+	for _, pool := range conf.NodePools {
+		switch {
+		case pool.Name == "":
+			return nil, fmt.Errorf("node pool without name")
+		case names[pool.Name]:
+			return nil, fmt.Errorf("duplicate node pool %s", pool.Name)
+		case pool.MinSize < 0 || pool.MaxSize <= 0 || pool.MinSize > pool.MaxSize:
+			return nil, fmt.Errorf("node pool %s: invalid size %d to %d", pool.Name, pool.MinSize, pool.MaxSize)
+		case len(pool.Resources) == 0:
+			return nil, fmt.Errorf("node pool %s: no resources", pool.Name)
+		case pool.ProvisioningDelay.Duration < 0:
+			return nil, fmt.Errorf("node pool %s: negative provisioning delay", pool.Name)
+		}
+		names[pool.Name] = true
+	}
+	if conf.ScanInterval.Duration <= 0 {
+		conf.ScanInterval.Duration = defaultScanInterval
+	}
+	if conf.ScaleDownUnneededTime.Duration <= 0 {
+		conf.ScaleDownUnneededTime.Duration = defaultScaleDownUnneededTime
+	}
+	if conf.ScaleDownUtilizationThreshold <= 0 {
+		conf.ScaleDownUtilizationThreshold = defaultScaleDownUtilizationThreshold
+	}
+	if conf.ScaleDownUtilizationThreshold > 1 {
+		return nil, fmt.Errorf("scale down utilization threshold %v is above 1", conf.ScaleDownUtilizationThreshold)
+	}
+	return conf, nil
+}
+
+// Autoscaler adds and removes the nodes of node pools in a simulation, like cluster-autoscaler.
+// After a scheduling cycle with unschedulable pods, it adds the nodes of the pool which fit the most
+// of them, first fit, which join after the provisioning delay of the pool. Every scan interval, the
+// nodes of pools above their min size whose utilization is below the threshold and whose pods fit on
+// other nodes are unneeded, and unneeded nodes are removed after the unneeded time.
+// Whether pods fit is decided by their resources, node selectors, affinity and tolerations.
+type Autoscaler struct {
+	conf    *AutoscalerConfiguration
+	engine  *Engine
+	filters []FilterPlugin
+	// sizes are the nodes of the pools in the cluster or being provisioned,
+	// provisioning only those being provisioned.
+	sizes        map[string]int
+	provisioning map[string]int
+	// unneeded are the times since which the nodes are unneeded.
+	unneeded      map[string]time.Duration
+	nextID        map[string]int
+	scanScheduled bool
+}
+
+// NewAutoscaler returns an autoscaler of the node pools of conf, set it as the Autoscaler of an Engine.
+func NewAutoscaler(conf *AutoscalerConfiguration) *Autoscaler {
+	return &Autoscaler{
+		conf:         conf,
+		filters:      []FilterPlugin{&NodeUnschedulable{}, &TaintToleration{}, &NodeAffinity{}},
+		sizes:        map[string]int{},
+		provisioning: map[string]int{},
+		unneeded:     map[string]time.Duration{},
+		nextID:       map[string]int{},
+	}
+}
+
+// start counts the nodes of the pools in the cluster of e, adds nodes to the pools
+// below their min size and starts to scan for unneeded nodes.
+func (a *Autoscaler) start(e *Engine) error {
+	a.engine = e
+	for _, node := range e.Cluster.Nodes() {
+		if pool := a.pool(node.Node); pool != nil {
+			a.sizes[pool.Name]++
+		}
+	}
+	for i := range a.conf.NodePools {
+		pool := &a.conf.NodePools[i]
+		for a.sizes[pool.Name] < pool.MinSize {
+			if err := e.Cluster.AddNode(a.newNode(pool)); err != nil {
+				return err
+			}
+			a.sizes[pool.Name]++
+		}
+	}
+	a.scheduleScan()
+	return nil
+}
+
+// scaleUp adds nodes for the unschedulable pods of result which do not fit on the nodes being provisioned.
+func (a *Autoscaler) scaleUp(result *Result) {
+	e := a.engine
+	var pods []*corev1.Pod
+	for _, placement := range result.Unschedulable {
+		if pod := e.Cluster.Pod(placement.Pod); pod != nil && len(pod.Spec.NodeName) == 0 {
+			pods = append(pods, pod)
+		}
+	}
+	var upcoming []*NodeInfo
+	for i := range a.conf.NodePools {
+		pool := &a.conf.NodePools[i]
+		for n := 0; n < a.provisioning[pool.Name]; n++ {
+			upcoming = append(upcoming, newNodeInfo(a.newTemplate(pool, pool.Name)))
+		}
+	}
+	pods, _ = a.pack(pods, upcoming, nil)
+	if len(pods) == 0 {
+		return
+	}
+
+	// the expander: the pool which fits the most pods, with the fewest nodes
+	var best *NodePool
+	var bestNodes, bestFits int
+	for i := range a.conf.NodePools {
+		pool := &a.conf.NodePools[i]
+		room := pool.MaxSize - a.sizes[pool.Name]
+		if room <= 0 {
+			continue
+		}
+		left, nodes := a.pack(pods, nil, func() *NodeInfo {
+			if room == 0 {
+				return nil
+			}
+			room--
+			return newNodeInfo(a.newTemplate(pool, pool.Name))
+		})
+		if fits := len(pods) - len(left); fits > bestFits || (fits == bestFits && fits > 0 && len(nodes) < bestNodes) {
+			best, bestNodes, bestFits = pool, len(nodes), fits
+		}
+	}
+	if best == nil {
+		return
+	}
+	for n := 0; n < bestNodes; n++ {
+		node := a.newNode(best)
+		a.sizes[best.Name]++
+		a.provisioning[best.Name]++
+		pool := best
+		e.At(e.now+best.ProvisioningDelay.Duration, func() error {
+			a.provisioning[pool.Name]--
+			if err := e.Cluster.AddNode(node); err != nil {
+				return err
+			}
+			e.report.NodesAdded++
+			e.RequestCycle()
+			return nil
+		})
+	}
+	a.scheduleScan()
+}
+
+// pack places pods first fit on nodes, and on the nodes of grow once they fit on none of them, until it
+// returns nil. It returns the pods which fit nowhere and the nodes of grow.
+func (a *Autoscaler) pack(pods []*corev1.Pod, nodes []*NodeInfo, grow func() *NodeInfo) ([]*corev1.Pod, []*NodeInfo) {
+	var left []*corev1.Pod
+	var grown []*NodeInfo
+	exhausted := grow == nil
+	for _, pod := range pods {
+		placed := false
+		for _, node := range append(nodes, grown...) {
+			if a.fits(pod, node) {
+				node.addPod(pod)
+				placed = true
+				break
+			}
+		}
+		if !placed && !exhausted {
+			if node := grow(); node == nil {
+				exhausted = true
+			} else if a.fits(pod, node) {
+				node.addPod(pod)
+				grown = append(grown, node)
+				placed = true
+			}
+		}
+		if !placed {
+			left = append(left, pod)
+		}
+	}
+	return left, grown
+}
+
+// scheduleScan scans for unneeded nodes after the scan interval, unless a scan is scheduled already.
+func (a *Autoscaler) scheduleScan() {
+	if a.scanScheduled {
+		return
+	}
+	a.scanScheduled = true
+	a.engine.At(a.engine.now+a.conf.ScanInterval.Duration, a.scan)
+}
+
+// scan updates the unneeded nodes and removes those which were unneeded for the unneeded time. Nodes
+// are not removed while others are provisioned. Scans continue while other events are left or nodes
+// are unneeded, otherwise the cluster does not change any more.
+func (a *Autoscaler) scan() error {
+	a.scanScheduled = false
+	e := a.engine
+	provisioning := 0
+	for _, count := range a.provisioning {
+		provisioning += count
+	}
+	if provisioning == 0 {
+		if err := a.scaleDown(); err != nil {
+			return err
+		}
+	}
+	if e.events.Len() > 0 || len(a.unneeded) > 0 {
+		a.scheduleScan()
+	}
+	return nil
+}
+
+func (a *Autoscaler) scaleDown() error {
+	e := a.engine
+	sizes := map[string]int{}
+	for name, size := range a.sizes {
+		sizes[name] = size
+	}
+	// the unneeded nodes are removed together, their pods are moved to the other nodes
+	removing := map[string]bool{}
+	destinations := map[string]*NodeInfo{}
+	var remove []string
+	for _, node := range e.Cluster.Nodes() {
+		name := node.Node.Name
+		pool := a.pool(node.Node)
+		if pool == nil || sizes[pool.Name] <= pool.MinSize || utilization(node) >= a.conf.ScaleDownUtilizationThreshold ||
+			!a.movePods(node, removing, destinations) {
+			delete(a.unneeded, name)
+			continue
+		}
+		removing[name] = true
+		sizes[pool.Name]--
+		since, ok := a.unneeded[name]
+		if !ok {
+			a.unneeded[name] = e.now
+		} else if e.now-since >= a.conf.ScaleDownUnneededTime.Duration {
+			remove = append(remove, name)
+		}
+	}
+
+	for _, name := range remove {
+		pool := a.pool(e.Cluster.Node(name).Node)
+		pods, err := e.Cluster.RemoveNode(name)
+		if err != nil {
+			return err
+		}
+		delete(a.unneeded, name)
+		a.sizes[pool.Name]--
+		e.report.NodesRemoved++
+		for _, pod := range pods {
+			e.report.record(PodKey(pod)).Evictions++
+		}
+		if len(pods) > 0 {
+			e.RequestCycle()
+		}
+	}
+	return nil
+}
+
+// movePods places the pods of node on the destinations, copies of the other nodes which are not removing,
+// and reports whether all of them fit. The destinations are unchanged if not.
+func (a *Autoscaler) movePods(node *NodeInfo, removing map[string]bool, destinations map[string]*NodeInfo) bool {
+	type move struct {
+		pod  *corev1.Pod
+		dest *NodeInfo
+	}
+	var moves []move
+	for _, pod := range node.Pods {
+		var found *NodeInfo
+		for _, other := range a.engine.Cluster.Nodes() {
+			name := other.Node.Name
+			if other == node || removing[name] {
+				continue
+			}
+			dest, ok := destinations[name]
+			if !ok {
+				dest = other.clone()
+				destinations[name] = dest
+			}
+			if a.fits(pod, dest) {
+				found = dest
+				break
+			}
+		}
+		if found == nil {
+			for _, m := range moves {
+				m.dest.removePod(m.pod)
+			}
+			return false
+		}
+		found.addPod(pod)
+		moves = append(moves, move{pod: pod, dest: found})
+	}
+	return true
+}
+
+// fits reports whether pod fits on node by its resources, node selectors, affinity and tolerations.
+func (a *Autoscaler) fits(pod *corev1.Pod, node *NodeInfo) bool {
+	for _, filter := range a.filters {
+		if len(filter.Filter(pod, node)) > 0 {
+			return false
+		}
+	}
+	return len(node.Fits(pod)) == 0
+}
+
+// pool returns the node pool of node, or nil if it belongs to none.
+func (a *Autoscaler) pool(node *corev1.Node) *NodePool {
+	name, ok := node.Labels[generate.NodePoolLabelKey]
+	if !ok {
+		return nil
+	}
+	for i := range a.conf.NodePools {
+		if a.conf.NodePools[i].Name == name {
+			return &a.conf.NodePools[i]
+		}
+	}
+	return nil
+}
+
+// newNode returns a node of pool with a name which is not taken in the cluster.
+func (a *Autoscaler) newNode(pool *NodePool) *corev1.Node {
+	for {
+		a.nextID[pool.Name]++
+		name := fmt.Sprintf("%s-%04d", pool.Name, a.nextID[pool.Name])
+		if a.engine.Cluster.Node(name) == nil {
+			return a.newTemplate(pool, name)
+		}
+	}
+}
+
+// newTemplate returns a node of the shape of pool.
+func (a *Autoscaler) newTemplate(pool *NodePool, name string) *corev1.Node {
+	labels := map[string]string{generate.NodePoolLabelKey: pool.Name}
+	for key, value := range pool.Labels {
+		labels[key] = value
+	}
+	node := generate.BuildFakeNodeOfShape(name, pool.Resources, labels)
+	node.Spec.Taints = pool.Taints
+	return node
+}
+
+// utilization returns the highest ratio of the requested to the allocatable cpu and memory of node.
+func utilization(node *NodeInfo) float64 {
+	var highest float64
+	allocatable := node.Allocatable()
+	for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+		total, ok := allocatable[name]
+		if !ok || total.IsZero() {
+			continue
+		}
+		requested := node.Requested[name]
+		if ratio := float64(requested.MilliValue()) / float64(total.MilliValue()); ratio > highest {
+			highest = ratio
+		}
+	}
+	return highest
+}
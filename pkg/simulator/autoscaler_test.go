@@ -0,0 +1,91 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulator
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/D0m021ng/scheduler-simulator/pkg/simctl/generate"
+)
+
+const testAutoscalerConfiguration = `
+nodePools:
+- name: pool
+  maxSize: 2
+  provisioningDelay: 2m
+  resources:
+    cpu: "4"
+    memory: 8Gi
+`
+
+func TestAutoscaler(t *testing.T) {
+	c := newTestCluster(t, "z1")
+	for _, name := range []string{"a", "b", "c"} {
+		pod := newTestPod(name, "3", nil)
+		pod.Annotations[generate.RuntimeAnnotationKey] = "30m"
+		if err := c.AddPod(pod); err != nil {
+			t.Fatal(err)
+		}
+	}
+	conf, err := ParseAutoscalerConfiguration([]byte(testAutoscalerConfiguration))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	engine := NewEngine(c, NewDefaultScheduler())
+	engine.Autoscaler = NewAutoscaler(conf)
+	report, err := engine.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// b and c wait for a node each, which are removed 10m after the pods completed at 32m
+	if report.NodesAdded != 2 || report.NodesRemoved != 2 {
+		t.Fatalf("added %d and removed %d nodes, want 2", report.NodesAdded, report.NodesRemoved)
+	}
+	wantWaits := []time.Duration{0, 2 * time.Minute, 2 * time.Minute}
+	for i, record := range report.Pods {
+		if record.Wait() != wantWaits[i] || !record.Finished {
+			t.Errorf("pod %s waited %s, want %s", record.Pod, record.Wait(), wantWaits[i])
+		}
+	}
+	if report.End != 42*time.Minute {
+		t.Errorf("simulation ended at %s", report.End)
+	}
+	if got, want := report.NodeHours(), (42.0+2*40.0)/60; math.Abs(got-want) > 1e-9 {
+		t.Errorf("got %f node-hours, want %f", got, want)
+	}
+}
+
+func TestParseAutoscalerConfiguration(t *testing.T) {
+	conf, err := ParseAutoscalerConfiguration([]byte(testAutoscalerConfiguration))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.ScanInterval.Duration != 10*time.Second || conf.ScaleDownUnneededTime.Duration != 10*time.Minute ||
+		conf.ScaleDownUtilizationThreshold != 0.5 {
+		t.Errorf("got defaults %+v", conf)
+	}
+	for _, invalid := range []string{
+		"nodePools: []",
+		"nodePools:\n- name: pool\n  minSize: 3\n  maxSize: 2\n  resources: {cpu: '4'}\n",
+		"nodePools:\n- name: pool\n  maxSize: 2\n",
+	} {
+		if _, err := ParseAutoscalerConfiguration([]byte(invalid)); err == nil {
+			t.Errorf("invalid configuration %q was accepted", invalid)
+		}
+	}
+}
@@ -0,0 +1,393 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package simulator models a cluster in memory, so that scheduling can be
+// simulated on the data generated by simctl without an api server.
+package simulator
+
+import (
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// NodeInfo is a node with the pods bound to it and the resources they request.
+type NodeInfo struct {
+	Node *corev1.Node
+	// Pods are the pods bound to the node, in the order they were bound.
+	Pods []*corev1.Pod
+	// Requested is the sum of the requests of Pods.
+	Requested corev1.ResourceList
+
+	// nonZeroRequested is the sum of the requests of Pods with default cpu and memory requests.
+	nonZeroRequested corev1.ResourceList
+	// podsWithAffinity are the pods of Pods which have pod affinity or anti-affinity terms.
+	podsWithAffinity []*corev1.Pod
+}
+
+func newNodeInfo(node *corev1.Node) *NodeInfo {
+	return &NodeInfo{
+		Node:             node,
+		Requested:        corev1.ResourceList{},
+		nonZeroRequested: corev1.ResourceList{},
+	}
+}
+
+// Allocatable returns the resources of the node which can be requested by pods, which
+// are its capacity if the node has no allocatable resources.
+func (n *NodeInfo) Allocatable() corev1.ResourceList {
+	if len(n.Node.Status.Allocatable) > 0 {
+		return n.Node.Status.Allocatable
+	}
+	return n.Node.Status.Capacity
+}
+
+// Free returns the allocatable resources of the node which are not requested yet.
+func (n *NodeInfo) Free() corev1.ResourceList {
+	free := n.Allocatable().DeepCopy()
+	subtractResources(free, n.Requested)
+	return free
+}
+
+// Fits returns the reasons why pod does not fit into the free resources of the
+// node, such as "Insufficient cpu", or nothing if it fits.
+func (n *NodeInfo) Fits(pod *corev1.Pod) []string {
+	var reasons []string
+	if allowed, ok := n.Allocatable()[corev1.ResourcePods]; ok && int64(len(n.Pods)) >= allowed.Value() {
+		reasons = append(reasons, "Too many pods")
+	}
+	requests := PodRequests(pod)
+	delete(requests, corev1.ResourcePods)
+	for _, name := range insufficientResources(requests, n.Requested, n.Allocatable()) {
+		reasons = append(reasons, fmt.Sprintf("Insufficient %s", name))
+	}
+	return reasons
+}
+
+// clone returns a copy of the node which pods can be added to without changing it.
+func (n *NodeInfo) clone() *NodeInfo {
+	clone := newNodeInfo(n.Node)
+	for _, pod := range n.Pods {
+		clone.addPod(pod)
+	}
+	return clone
+}
+
+func (n *NodeInfo) addPod(pod *corev1.Pod) {
+	n.Pods = append(n.Pods, pod)
+	addResources(n.Requested, PodRequests(pod))
+	addResources(n.nonZeroRequested, nonZeroRequests(pod))
+	if hasPodAffinity(pod) {
+		n.podsWithAffinity = append(n.podsWithAffinity, pod)
+	}
+}
+
+func (n *NodeInfo) removePod(pod *corev1.Pod) {
+	for i, p := range n.Pods {
+		if p == pod {
+			n.Pods = append(n.Pods[:i], n.Pods[i+1:]...)
+			subtractResources(n.Requested, PodRequests(pod))
+			subtractResources(n.nonZeroRequested, nonZeroRequests(pod))
+			break
+		}
+	}
+	for i, p := range n.podsWithAffinity {
+		if p == pod {
+			n.podsWithAffinity = append(n.podsWithAffinity[:i], n.podsWithAffinity[i+1:]...)
+			break
+		}
+	}
+}
+
+// Cluster is an in-memory model of the nodes and pods of a cluster. Pods are bound,
+// unbound and evicted directly, there are no controllers. It is not safe for concurrent use.
+type Cluster struct {
+	nodes     map[string]*NodeInfo
+	nodeNames []string
+	pods      map[types.NamespacedName]*corev1.Pod
+	podNames  []types.NamespacedName
+	// podOrder is the order pods were added in, pending are the pending pods.
+	podOrder  map[types.NamespacedName]int
+	pending   map[types.NamespacedName]bool
+	nextOrder int
+
+	// Objects are the loaded objects which are neither nodes nor pods, such as
+	// priority classes, queues and podgroups.
+	Objects []*unstructured.Unstructured
+}
+
+func NewCluster() *Cluster {
+	return &Cluster{
+		nodes:    map[string]*NodeInfo{},
+		pods:     map[types.NamespacedName]*corev1.Pod{},
+		podOrder: map[types.NamespacedName]int{},
+		pending:  map[types.NamespacedName]bool{},
+	}
+}
+
+// PodKey returns the key of pod in a cluster.
+func PodKey(pod *corev1.Pod) types.NamespacedName {
+	return types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}
+}
+
+// AddNode adds a copy of node without pods.
+func (c *Cluster) AddNode(node *corev1.Node) error {
+	if _, ok := c.nodes[node.Name]; ok {
+		return fmt.Errorf("node %s already exists", node.Name)
+	}
+	c.nodes[node.Name] = newNodeInfo(node.DeepCopy())
+	c.nodeNames = append(c.nodeNames, node.Name)
+	return nil
+}
+
+// RemoveNode removes a node, its pods are unbound and returned.
+func (c *Cluster) RemoveNode(name string) ([]*corev1.Pod, error) {
+	node, ok := c.nodes[name]
+	if !ok {
+		return nil, fmt.Errorf("node %s not found", name)
+	}
+	pods := append([]*corev1.Pod(nil), node.Pods...)
+	for _, pod := range pods {
+		if err := c.Unbind(PodKey(pod)); err != nil {
+			return nil, err
+		}
+	}
+	delete(c.nodes, name)
+	for i, n := range c.nodeNames {
+		if n == name {
+			c.nodeNames = append(c.nodeNames[:i], c.nodeNames[i+1:]...)
+			break
+		}
+	}
+	return pods, nil
+}
+
+// Node returns the node with name, or nil if there is none.
+func (c *Cluster) Node(name string) *NodeInfo {
+	return c.nodes[name]
+}
+
+// Nodes returns all nodes in the order they were added.
+func (c *Cluster) Nodes() []*NodeInfo {
+	nodes := make([]*NodeInfo, 0, len(c.nodeNames))
+	for _, name := range c.nodeNames {
+		nodes = append(nodes, c.nodes[name])
+	}
+	return nodes
+}
+
+// AddPod adds a copy of pod. A pod with a node name which has not terminated is bound to the node.
+func (c *Cluster) AddPod(pod *corev1.Pod) error {
+	pod = pod.DeepCopy()
+	if len(pod.Namespace) == 0 {
+		pod.Namespace = metav1.NamespaceDefault
+	}
+	key := PodKey(pod)
+	if _, ok := c.pods[key]; ok {
+		return fmt.Errorf("pod %s already exists", key)
+	}
+	if len(pod.Spec.NodeName) > 0 && !isTerminated(pod) {
+		node, ok := c.nodes[pod.Spec.NodeName]
+		if !ok {
+			return fmt.Errorf("pod %s is bound to node %s, which does not exist", key, pod.Spec.NodeName)
+		}
+		node.addPod(pod)
+	} else if len(pod.Spec.NodeName) == 0 && !isTerminated(pod) {
+		c.pending[key] = true
+	}
+	c.podOrder[key] = c.nextOrder
+	c.nextOrder++
+	c.pods[key] = pod
+	c.podNames = append(c.podNames, key)
+	return nil
+}
+
+// RemovePod removes a pod, and releases its resources if it is bound.
+func (c *Cluster) RemovePod(key types.NamespacedName) error {
+	pod, ok := c.pods[key]
+	if !ok {
+		return fmt.Errorf("pod %s not found", key)
+	}
+	if node := c.boundNode(pod); node != nil {
+		node.removePod(pod)
+	}
+	delete(c.pods, key)
+	delete(c.pending, key)
+	delete(c.podOrder, key)
+	for i, name := range c.podNames {
+		if name == key {
+			c.podNames = append(c.podNames[:i], c.podNames[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// Pod returns the pod with key, or nil if there is none.
+func (c *Cluster) Pod(key types.NamespacedName) *corev1.Pod {
+	return c.pods[key]
+}
+
+// Pods returns all pods in the order they were added.
+func (c *Cluster) Pods() []*corev1.Pod {
+	pods := make([]*corev1.Pod, 0, len(c.podNames))
+	for _, key := range c.podNames {
+		pods = append(pods, c.pods[key])
+	}
+	return pods
+}
+
+// PendingPods returns the pods which are neither bound nor terminated, in the order they were added.
+func (c *Cluster) PendingPods() []*corev1.Pod {
+	keys := make([]types.NamespacedName, 0, len(c.pending))
+	for key := range c.pending {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return c.podOrder[keys[i]] < c.podOrder[keys[j]] })
+	pods := make([]*corev1.Pod, 0, len(keys))
+	for _, key := range keys {
+		pods = append(pods, c.pods[key])
+	}
+	return pods
+}
+
+// PendingCount returns the count of pending pods.
+func (c *Cluster) PendingCount() int {
+	return len(c.pending)
+}
+
+// Bind binds a pending pod to a node and starts it. The resources of the node are not checked,
+// so that a scheduler can decide to overcommit them, see NodeInfo.Fits.
+func (c *Cluster) Bind(key types.NamespacedName, nodeName string) error {
+	pod, ok := c.pods[key]
+	if !ok {
+		return fmt.Errorf("pod %s not found", key)
+	}
+	if len(pod.Spec.NodeName) > 0 {
+		return fmt.Errorf("pod %s is already bound to node %s", key, pod.Spec.NodeName)
+	}
+	if isTerminated(pod) {
+		return fmt.Errorf("pod %s has terminated", key)
+	}
+	node, ok := c.nodes[nodeName]
+	if !ok {
+		return fmt.Errorf("node %s not found", nodeName)
+	}
+	pod.Spec.NodeName = nodeName
+	pod.Status.Phase = corev1.PodRunning
+	node.addPod(pod)
+	delete(c.pending, key)
+	return nil
+}
+
+// Unbind removes a pod from its node, and makes it pending again.
+func (c *Cluster) Unbind(key types.NamespacedName) error {
+	pod, ok := c.pods[key]
+	if !ok {
+		return fmt.Errorf("pod %s not found", key)
+	}
+	node := c.boundNode(pod)
+	if node == nil {
+		return fmt.Errorf("pod %s is not bound", key)
+	}
+	node.removePod(pod)
+	pod.Spec.NodeName = ""
+	pod.Status.Phase = corev1.PodPending
+	c.pending[key] = true
+	return nil
+}
+
+// Complete removes a pod from its node and succeeds it, the pod is kept as terminated.
+func (c *Cluster) Complete(key types.NamespacedName) error {
+	pod, ok := c.pods[key]
+	if !ok {
+		return fmt.Errorf("pod %s not found", key)
+	}
+	node := c.boundNode(pod)
+	if node == nil {
+		return fmt.Errorf("pod %s is not bound", key)
+	}
+	node.removePod(pod)
+	pod.Status.Phase = corev1.PodSucceeded
+	return nil
+}
+
+// Evict removes a pod from its node and fails it with reason, the pod is kept as terminated.
+func (c *Cluster) Evict(key types.NamespacedName, reason string) error {
+	pod, ok := c.pods[key]
+	if !ok {
+		return fmt.Errorf("pod %s not found", key)
+	}
+	node := c.boundNode(pod)
+	if node == nil {
+		return fmt.Errorf("pod %s is not bound", key)
+	}
+	node.removePod(pod)
+	pod.Status.Phase = corev1.PodFailed
+	pod.Status.Reason = "Evicted"
+	pod.Status.Message = reason
+	return nil
+}
+
+// Totals returns the sum of the requested and of the allocatable resources of all nodes.
+func (c *Cluster) Totals() (requested, allocatable corev1.ResourceList) {
+	requested, allocatable = corev1.ResourceList{}, corev1.ResourceList{}
+	for _, node := range c.nodes {
+		addResources(requested, node.Requested)
+		addResources(allocatable, node.Allocatable())
+	}
+	return requested, allocatable
+}
+
+// Utilization returns the ratio of the requested to the allocatable resources of all nodes, per resource.
+func (c *Cluster) Utilization() map[corev1.ResourceName]float64 {
+	requested, allocatable := c.Totals()
+	utilization := map[corev1.ResourceName]float64{}
+	for name, total := range allocatable {
+		if total.IsZero() {
+			continue
+		}
+		used := requested[name]
+		utilization[name] = float64(quantityValue(name, used)) / float64(quantityValue(name, total))
+	}
+	if pods, ok := allocatable[corev1.ResourcePods]; ok && !pods.IsZero() {
+		bound := 0
+		for _, node := range c.nodes {
+			bound += len(node.Pods)
+		}
+		utilization[corev1.ResourcePods] = float64(bound) / float64(pods.Value())
+	}
+	return utilization
+}
+
+// boundNode returns the node which pod is bound to, or nil.
+func (c *Cluster) boundNode(pod *corev1.Pod) *NodeInfo {
+	if len(pod.Spec.NodeName) == 0 || isTerminated(pod) {
+		return nil
+	}
+	return c.nodes[pod.Spec.NodeName]
+}
+
+func isTerminated(pod *corev1.Pod) bool {
+	return pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed
+}
+
+func hasPodAffinity(pod *corev1.Pod) bool {
+	affinity := pod.Spec.Affinity
+	return affinity != nil && (affinity.PodAffinity != nil || affinity.PodAntiAffinity != nil)
+}
@@ -0,0 +1,98 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulator
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/D0m021ng/scheduler-simulator/pkg/simctl/generate"
+)
+
+func TestLoadFilesAndBind(t *testing.T) {
+	dir := t.TempDir()
+	nodeFile, podFile := filepath.Join(dir, "nodes.yaml"), filepath.Join(dir, "pods.yaml")
+	capacity := generate.BuildResources(map[string]string{"cpu": "4", "memory": "8Gi", "pods": "2", "nvidia.com/gpu": "1"})
+	nodes := []interface{}{
+		generate.BuildFakeNode("n1", false, capacity, capacity, nil, nil),
+		generate.BuildFakeNode("n2", false, capacity, nil, nil, nil),
+	}
+	gpuPod := generate.BuildFakePod("gpu", "ns", "", "", nil, corev1.PodPending,
+		generate.BuildResources(map[string]string{"cpu": "1", "nvidia.com/gpu": "1"}))
+	boundPod := generate.BuildFakePod("bound", "ns", "", "", nil, corev1.PodRunning,
+		generate.BuildResources(map[string]string{"cpu": "3500m"}))
+	boundPod.Spec.NodeName = "n1"
+	pods := []interface{}{gpuPod, boundPod, generate.BuildFakePodGroup("job", "ns", "", 1, nil)}
+	if err := generate.WriteYamlFile(nodeFile, nodes); err != nil {
+		t.Fatal(err)
+	}
+	if err := generate.WriteYamlFile(podFile, pods); err != nil {
+		t.Fatal(err)
+	}
+
+	// pods are loaded after the nodes they are bound to, whatever the order of files
+	c, err := LoadFiles(podFile, nodeFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Nodes()) != 2 || len(c.Pods()) != 2 || len(c.Objects) != 1 {
+		t.Fatalf("loaded %d nodes, %d pods and %d objects", len(c.Nodes()), len(c.Pods()), len(c.Objects))
+	}
+	n1 := c.Node("n1")
+	if len(n1.Pods) != 1 {
+		t.Fatalf("bound pod is not on its node")
+	}
+
+	gpu := c.PendingPods()[0]
+	if reasons := n1.Fits(gpu); !reflect.DeepEqual(reasons, []string{"Insufficient cpu"}) {
+		t.Errorf("unexpected reasons for n1: %v", reasons)
+	}
+	// a node without allocatable resources offers its capacity
+	if reasons := c.Node("n2").Fits(gpu); len(reasons) != 0 {
+		t.Errorf("gpu pod should fit on n2: %v", reasons)
+	}
+	if err := c.Bind(PodKey(gpu), "n2"); err != nil {
+		t.Fatal(err)
+	}
+	if free := c.Node("n2").Free()["nvidia.com/gpu"]; !free.IsZero() {
+		t.Errorf("gpu of n2 should be allocated, %s free", free.String())
+	}
+	if err := c.Bind(PodKey(gpu), "n1"); err == nil {
+		t.Errorf("binding a bound pod should fail")
+	}
+	if utilization := c.Utilization()[corev1.ResourceCPU]; utilization != 0.5625 {
+		t.Errorf("cpu utilization = %v, want 0.5625", utilization)
+	}
+
+	if err := c.Unbind(PodKey(gpu)); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.PendingPods()) != 1 || len(c.Node("n2").Pods) != 0 {
+		t.Errorf("unbound pod should be pending again")
+	}
+
+	if err := c.Evict(PodKey(boundPod), "preempted"); err != nil {
+		t.Fatal(err)
+	}
+	if boundPod := c.Pod(PodKey(boundPod)); boundPod.Status.Phase != corev1.PodFailed || len(n1.Pods) != 0 {
+		t.Errorf("evicted pod should have failed and left its node")
+	}
+	if requested := n1.Requested[corev1.ResourceCPU]; !requested.IsZero() {
+		t.Errorf("evicted pod still requests %s cpu", requested.String())
+	}
+}
@@ -0,0 +1,394 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulator
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+// schedulerConfigGroup is the api group of KubeSchedulerConfiguration.
+const schedulerConfigGroup = "kubescheduler.config.k8s.io"
+
+// defaultPreemption is the postFilter plugin of kube-scheduler, simulated by Scheduler.Preemption.
+const defaultPreemption = "DefaultPreemption"
+
+// SchedulerConfiguration is the part of a KubeSchedulerConfiguration which the simulated
+// scheduler honors, its other fields are ignored.
+type SchedulerConfiguration struct {
+	metav1.TypeMeta `json:",inline"`
+	Profiles        []SchedulerProfile `json:"profiles,omitempty"`
+}
+
+// SchedulerProfile is a scheduling profile, which schedules the pods of its SchedulerName.
+type SchedulerProfile struct {
+	SchedulerName string         `json:"schedulerName,omitempty"`
+	Plugins       *PluginsConfig `json:"plugins,omitempty"`
+	PluginConfig  []PluginConfig `json:"pluginConfig,omitempty"`
+}
+
+// PluginsConfig enables and disables plugins at the extension points of a profile. Only the
+// filter, postFilter and score extension points are simulated, a plugin enabled at multiPoint is
+// enabled at all of them which it implements. DefaultPreemption is the only postFilter plugin.
+type PluginsConfig struct {
+	MultiPoint PluginSet `json:"multiPoint,omitempty"`
+	Filter     PluginSet `json:"filter,omitempty"`
+	PostFilter PluginSet `json:"postFilter,omitempty"`
+	Score      PluginSet `json:"score,omitempty"`
+}
+
+// PluginSet are the plugins enabled in addition to the default plugins, and the default plugins
+// which are disabled, all of them if "*" is.
+type PluginSet struct {
+	Enabled  []PluginRef `json:"enabled,omitempty"`
+	Disabled []PluginRef `json:"disabled,omitempty"`
+}
+
+// PluginRef is a plugin, Weight only applies to score plugins.
+type PluginRef struct {
+	Name   string `json:"name"`
+	Weight *int32 `json:"weight,omitempty"`
+}
+
+// PluginConfig are the args of a plugin.
+type PluginConfig struct {
+	Name string                 `json:"name"`
+	Args map[string]interface{} `json:"args,omitempty"`
+}
+
+// Profiles schedules every pod with the profile of its schedulerName, like kube-scheduler with
+// several profiles. The pods of other schedulers are left pending.
+type Profiles struct {
+	Schedulers map[string]*Scheduler
+	// Ignored are the plugins of the configuration which are not simulated.
+	Ignored []string
+}
+
+// defaultPlugin is a plugin which is enabled by default, at the filter extension point if filter
+// and at the score extension point with its weight if it has one.
+type defaultPlugin struct {
+	name   string
+	filter bool
+	weight int64
+}
+
+// defaultPlugins returns the default plugins of the api version of a configuration which are
+// simulated, in the order of kube-scheduler.
+func defaultPlugins(version string) []defaultPlugin {
+	switch version {
+	case "v1beta1":
+		return []defaultPlugin{
+			{"NodeUnschedulable", true, 0}, {"NodeResourcesFit", true, 0}, {"NodeAffinity", true, 1},
+			{"TaintToleration", true, 1}, {"PodTopologySpread", true, 2}, {"InterPodAffinity", true, 1},
+			{"NodeResourcesLeastAllocated", false, 1},
+		}
+	case "v1beta2":
+		return []defaultPlugin{
+			{"NodeUnschedulable", true, 0}, {"NodeResourcesFit", true, 1}, {"NodeAffinity", true, 1},
+			{"TaintToleration", true, 1}, {"PodTopologySpread", true, 2}, {"InterPodAffinity", true, 1},
+		}
+	}
+	return []defaultPlugin{
+		{"NodeUnschedulable", true, 0}, {"NodeResourcesFit", true, 1}, {"NodeAffinity", true, 2},
+		{"TaintToleration", true, 3}, {"PodTopologySpread", true, 2}, {"InterPodAffinity", true, 2},
+	}
+}
+
+// nodeResourcesFitArgs are the args of NodeResourcesFit, and of the resource scoring plugins
+// which it replaced in kube-scheduler 1.23.
+type nodeResourcesFitArgs struct {
+	ScoringStrategy *struct {
+		Type                     ScoringStrategyType `json:"type"`
+		Resources                []ResourceWeight    `json:"resources"`
+		RequestedToCapacityRatio *struct {
+			Shape []UtilizationShapePoint `json:"shape"`
+		} `json:"requestedToCapacityRatio"`
+	} `json:"scoringStrategy"`
+	Resources []ResourceWeight        `json:"resources"`
+	Shape     []UtilizationShapePoint `json:"shape"`
+}
+
+// pluginFactories build the simulated plugins from their args.
+var pluginFactories = map[string]func(args map[string]interface{}) (Plugin, error){
+	"NodeUnschedulable": func(map[string]interface{}) (Plugin, error) { return &NodeUnschedulable{}, nil },
+	"NodeAffinity":      func(map[string]interface{}) (Plugin, error) { return &NodeAffinity{}, nil },
+	"TaintToleration":   func(map[string]interface{}) (Plugin, error) { return &TaintToleration{}, nil },
+	"PodTopologySpread": func(map[string]interface{}) (Plugin, error) { return &PodTopologySpread{}, nil },
+	"InterPodAffinity":  func(map[string]interface{}) (Plugin, error) { return &InterPodAffinity{}, nil },
+	"NodeResourcesFit":  resourcesFitFactory(""),
+
+	"NodeResourcesLeastAllocated": resourcesFitFactory(LeastAllocated),
+	"NodeResourcesMostAllocated":  resourcesFitFactory(MostAllocated),
+	"RequestedToCapacityRatio":    resourcesFitFactory(RequestedToCapacityRatio),
+}
+
+// resourcesFitFactory builds NodeResourcesFit from its args, or from the args of the
+// plugin of strategy in the api versions before it had a scoring strategy.
+func resourcesFitFactory(strategy ScoringStrategyType) func(map[string]interface{}) (Plugin, error) {
+	return func(raw map[string]interface{}) (Plugin, error) {
+		var args nodeResourcesFitArgs
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw, &args); err != nil {
+			return nil, err
+		}
+		s := ScoringStrategy{Type: strategy, Resources: args.Resources, Shape: args.Shape}
+		if strategy == "" && args.ScoringStrategy != nil {
+			s = ScoringStrategy{Type: args.ScoringStrategy.Type, Resources: args.ScoringStrategy.Resources}
+			if args.ScoringStrategy.RequestedToCapacityRatio != nil {
+				s.Shape = args.ScoringStrategy.RequestedToCapacityRatio.Shape
+			}
+		}
+		switch s.Type {
+		case "", LeastAllocated, MostAllocated, RequestedToCapacityRatio:
+		default:
+			return nil, fmt.Errorf("unknown scoring strategy %q", s.Type)
+		}
+		for _, point := range s.Shape {
+			if point.Utilization < 0 || point.Utilization > 100 || point.Score < 0 || point.Score > maxShapeScore {
+				return nil, fmt.Errorf("invalid shape point %+v", point)
+			}
+		}
+		sort.SliceStable(s.Shape, func(i, j int) bool { return s.Shape[i].Utilization < s.Shape[j].Utilization })
+		return NewNodeResourcesFit(s), nil
+	}
+}
+
+// LoadSchedulerConfiguration reads a KubeSchedulerConfiguration from filename.
+func LoadSchedulerConfiguration(filename string) (*Profiles, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	profiles, err := ParseSchedulerConfiguration(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return profiles, nil
+}
+
+// ParseSchedulerConfiguration builds the schedulers of the profiles of a KubeSchedulerConfiguration,
+// a configuration without profiles has the default profile.
+func ParseSchedulerConfiguration(data []byte) (*Profiles, error) {
+	conf := &SchedulerConfiguration{}
+	if err := yaml.Unmarshal(data, conf); err != nil {
+		return nil, err
+	}
+	gv, err := schema.ParseGroupVersion(conf.APIVersion)
+	if err != nil {
+		return nil, err
+	}
+	if gv.Group != schedulerConfigGroup || conf.Kind != "KubeSchedulerConfiguration" {
+		return nil, fmt.Errorf("%s %s is not a KubeSchedulerConfiguration", conf.APIVersion, conf.Kind)
+	}
+	switch gv.Version {
+	case "v1beta1", "v1beta2", "v1beta3", "v1":
+	default:
+		return nil, fmt.Errorf("unsupported version %s of KubeSchedulerConfiguration", gv.Version)
+	}
+
+	if len(conf.Profiles) == 0 {
+		conf.Profiles = []SchedulerProfile{{}}
+	}
+	profiles := &Profiles{Schedulers: map[string]*Scheduler{}}
+	ignored := map[string]bool{}
+	for _, profile := range conf.Profiles {
+		if profile.SchedulerName == "" {
+			profile.SchedulerName = corev1.DefaultSchedulerName
+		}
+		if _, ok := profiles.Schedulers[profile.SchedulerName]; ok {
+			return nil, fmt.Errorf("duplicate profile of scheduler %s", profile.SchedulerName)
+		}
+		scheduler, err := newProfileScheduler(gv.Version, &profile, ignored)
+		if err != nil {
+			return nil, fmt.Errorf("profile %s: %v", profile.SchedulerName, err)
+		}
+		profiles.Schedulers[profile.SchedulerName] = scheduler
+	}
+	for name := range ignored {
+		profiles.Ignored = append(profiles.Ignored, name)
+	}
+	sort.Strings(profiles.Ignored)
+	return profiles, nil
+}
+
+// newProfileScheduler builds the scheduler of a profile from the default plugins of version,
+// records the enabled plugins which are not simulated in ignored.
+func newProfileScheduler(version string, profile *SchedulerProfile, ignored map[string]bool) (*Scheduler, error) {
+	args := map[string]map[string]interface{}{}
+	for _, config := range profile.PluginConfig {
+		args[config.Name] = config.Args
+	}
+	instances := map[string]Plugin{}
+	instance := func(name string) (Plugin, error) {
+		if plugin, ok := instances[name]; ok {
+			return plugin, nil
+		}
+		factory, ok := pluginFactories[name]
+		if !ok {
+			return nil, nil
+		}
+		plugin, err := factory(args[name])
+		if err != nil {
+			return nil, fmt.Errorf("plugin %s: %v", name, err)
+		}
+		instances[name] = plugin
+		return plugin, nil
+	}
+
+	type enabledPlugin struct {
+		name   string
+		weight int64
+	}
+	var filters, scores []enabledPlugin
+	weights := map[string]int64{}
+	for _, plugin := range defaultPlugins(version) {
+		if plugin.filter {
+			filters = append(filters, enabledPlugin{name: plugin.name})
+		}
+		if plugin.weight > 0 {
+			weights[plugin.name] = plugin.weight
+			scores = append(scores, enabledPlugin{name: plugin.name, weight: plugin.weight})
+		}
+	}
+
+	apply := func(set PluginSet, enabled []enabledPlugin, point func(Plugin) bool) ([]enabledPlugin, error) {
+		for _, ref := range set.Disabled {
+			kept := enabled[:0]
+			for _, plugin := range enabled {
+				if ref.Name != "*" && plugin.name != ref.Name {
+					kept = append(kept, plugin)
+				}
+			}
+			enabled = kept
+		}
+		for _, ref := range set.Enabled {
+			if ref.Name == defaultPreemption {
+				continue
+			}
+			plugin, err := instance(ref.Name)
+			if err != nil {
+				return nil, err
+			}
+			if plugin == nil {
+				ignored[ref.Name] = true
+				continue
+			}
+			if !point(plugin) {
+				continue
+			}
+			weight := weights[ref.Name]
+			if ref.Weight != nil {
+				weight = int64(*ref.Weight)
+			}
+			if weight <= 0 {
+				weight = 1
+			}
+			found := false
+			for i := range enabled {
+				if enabled[i].name == ref.Name {
+					enabled[i].weight, found = weight, true
+				}
+			}
+			if !found {
+				enabled = append(enabled, enabledPlugin{name: ref.Name, weight: weight})
+			}
+		}
+		return enabled, nil
+	}
+	isFilter := func(p Plugin) bool { _, ok := p.(FilterPlugin); return ok }
+	isScore := func(p Plugin) bool { _, ok := p.(ScorePlugin); return ok }
+
+	if plugins := profile.Plugins; plugins != nil {
+		var err error
+		if filters, err = apply(plugins.MultiPoint, filters, isFilter); err != nil {
+			return nil, err
+		}
+		if scores, err = apply(plugins.MultiPoint, scores, isScore); err != nil {
+			return nil, err
+		}
+		if filters, err = apply(plugins.Filter, filters, isFilter); err != nil {
+			return nil, err
+		}
+		if scores, err = apply(plugins.Score, scores, isScore); err != nil {
+			return nil, err
+		}
+	}
+
+	s := &Scheduler{Preemption: preemptionEnabled(profile.Plugins)}
+	for _, enabled := range filters {
+		plugin, err := instance(enabled.name)
+		if err != nil {
+			return nil, err
+		}
+		s.Filters = append(s.Filters, plugin.(FilterPlugin))
+	}
+	for _, enabled := range scores {
+		plugin, err := instance(enabled.name)
+		if err != nil {
+			return nil, err
+		}
+		s.Scores = append(s.Scores, WeightedScorePlugin{Plugin: plugin.(ScorePlugin), Weight: enabled.weight})
+	}
+	return s, nil
+}
+
+// preemptionEnabled reports whether DefaultPreemption is enabled, it is a default plugin.
+func preemptionEnabled(plugins *PluginsConfig) bool {
+	if plugins == nil {
+		return true
+	}
+	enabled := true
+	for _, set := range []PluginSet{plugins.MultiPoint, plugins.PostFilter} {
+		for _, ref := range set.Disabled {
+			if ref.Name == "*" || ref.Name == defaultPreemption {
+				enabled = false
+			}
+		}
+		for _, ref := range set.Enabled {
+			if ref.Name == defaultPreemption {
+				enabled = true
+			}
+		}
+	}
+	return enabled
+}
+
+// Run schedules the pending pods of the cluster with the profiles of their schedulerNames,
+// the pods with a higher priority first.
+func (p *Profiles) Run(c *Cluster) (*Result, error) {
+	result := &Result{}
+	for _, pod := range SortByPriority(c.PendingPods()) {
+		name := pod.Spec.SchedulerName
+		if name == "" {
+			name = corev1.DefaultSchedulerName
+		}
+		s, ok := p.Schedulers[name]
+		if !ok {
+			result.Unschedulable = append(result.Unschedulable,
+				Placement{Pod: PodKey(pod), Reason: fmt.Sprintf("no profile of scheduler %s", name)})
+			continue
+		}
+		if err := s.scheduleOne(c, pod, result); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,122 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulator
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testSchedulerConfiguration = `
+apiVersion: kubescheduler.config.k8s.io/v1
+kind: KubeSchedulerConfiguration
+leaderElection:
+  leaderElect: false
+profiles:
+- schedulerName: default-scheduler
+- schedulerName: bin-packing
+  plugins:
+    multiPoint:
+      enabled:
+      - name: ImageLocality
+    filter:
+      disabled:
+      - name: TaintToleration
+    postFilter:
+      disabled:
+      - name: DefaultPreemption
+    score:
+      disabled:
+      - name: "*"
+      enabled:
+      - name: NodeResourcesFit
+        weight: 5
+  pluginConfig:
+  - name: NodeResourcesFit
+    args:
+      scoringStrategy:
+        type: MostAllocated
+        resources:
+        - name: cpu
+          weight: 1
+`
+
+func TestParseSchedulerConfiguration(t *testing.T) {
+	profiles, err := ParseSchedulerConfiguration([]byte(testSchedulerConfiguration))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(profiles.Ignored, []string{"ImageLocality"}) {
+		t.Errorf("got ignored plugins %v", profiles.Ignored)
+	}
+
+	want := map[string]int64{"NodeResourcesFit": 1, "NodeAffinity": 2, "TaintToleration": 3, "PodTopologySpread": 2, "InterPodAffinity": 2}
+	// the scheduler without a configuration has the same default weights
+	for _, scheduler := range []*Scheduler{profiles.Schedulers["default-scheduler"], NewDefaultScheduler()} {
+		weights := map[string]int64{}
+		for _, score := range scheduler.Scores {
+			weights[score.Plugin.Name()] = score.Weight
+		}
+		if !reflect.DeepEqual(weights, want) {
+			t.Errorf("got default weights %v, want %v", weights, want)
+		}
+	}
+
+	binPacking := profiles.Schedulers["bin-packing"]
+	if len(binPacking.Filters) != 5 || len(binPacking.Scores) != 1 || binPacking.Scores[0].Weight != 5 {
+		t.Fatalf("got %d filters and scores %+v", len(binPacking.Filters), binPacking.Scores)
+	}
+	if !profiles.Schedulers["default-scheduler"].Preemption || binPacking.Preemption {
+		t.Errorf("DefaultPreemption is not only enabled in the default profile")
+	}
+	if strategy := binPacking.Scores[0].Plugin.(*NodeResourcesFit).Strategy; strategy.Type != MostAllocated || len(strategy.Resources) != 1 {
+		t.Errorf("got scoring strategy %+v", strategy)
+	}
+
+	if _, err := ParseSchedulerConfiguration([]byte("apiVersion: v1\nkind: ConfigMap\n")); err == nil {
+		t.Errorf("a ConfigMap was accepted as scheduler configuration")
+	}
+}
+
+func TestProfilesBySchedulerName(t *testing.T) {
+	profiles, err := ParseSchedulerConfiguration([]byte(testSchedulerConfiguration))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := newTestCluster(t, "z1", "z1")
+	for _, name := range []string{"p1", "p2"} {
+		pod := newTestPod(name, "1", nil)
+		pod.Spec.SchedulerName = "bin-packing"
+		if err := c.AddPod(pod); err != nil {
+			t.Fatal(err)
+		}
+	}
+	other := newTestPod("other", "1", nil)
+	other.Spec.SchedulerName = "volcano"
+	if err := c.AddPod(other); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := profiles.Run(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Scheduled) != 2 || result.Scheduled[0].Node != result.Scheduled[1].Node {
+		t.Errorf("bin-packing pods were not packed on one node: %+v", result.Scheduled)
+	}
+	if len(result.Unschedulable) != 1 || result.Unschedulable[0].Reason != "no profile of scheduler volcano" {
+		t.Errorf("got unschedulable %+v", result.Unschedulable)
+	}
+}
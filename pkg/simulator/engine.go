@@ -0,0 +1,350 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulator
+
+import (
+	"container/heap"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/D0m021ng/scheduler-simulator/pkg/simctl/generate"
+)
+
+// Algorithm schedules the pending pods of a cluster, such as a Scheduler.
+type Algorithm interface {
+	Run(c *Cluster) (*Result, error)
+}
+
+type event struct {
+	time time.Duration
+	// cycle events run after all other events of the same time, so that
+	// a scheduling cycle sees all pods which arrived at that time.
+	cycle bool
+	seq   int64
+	run   func() error
+}
+
+type eventQueue []*event
+
+func (q eventQueue) Len() int { return len(q) }
+
+func (q eventQueue) Less(i, j int) bool {
+	if q[i].time != q[j].time {
+		return q[i].time < q[j].time
+	}
+	if q[i].cycle != q[j].cycle {
+		return !q[i].cycle
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q eventQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *eventQueue) Push(x interface{}) { *q = append(*q, x.(*event)) }
+
+func (q *eventQueue) Pop() interface{} {
+	old := *q
+	e := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return e
+}
+
+// Engine is a discrete-event simulation of a cluster on a virtual clock. Pods arrive at the time
+// of their arrival-time annotation and complete after the runtime of their runtime annotation
+// once they are bound, nodes join at the time of their arrival-time annotation. A scheduling
+// cycle of the Algorithm runs whenever pods arrived or resources were released, so the clock
+// jumps from event to event and a week of workload simulates in seconds.
+type Engine struct {
+	Cluster   *Cluster
+	Algorithm Algorithm
+	// Period aligns the scheduling cycles to multiples of it, like the schedule period of
+	// a batch scheduler. Zero runs a cycle at the time of every change.
+	Period time.Duration
+	// Until stops the simulation at this time, zero runs it until no events are left.
+	Until time.Duration
+	// Autoscaler adds nodes for unschedulable pods and removes unneeded nodes, none if nil.
+	Autoscaler *Autoscaler
+
+	now            time.Duration
+	seq            int64
+	events         eventQueue
+	cycleScheduled bool
+	// starts counts the binds of every pod, so that the completion of a pod which
+	// was unbound before its runtime elapsed is dropped.
+	starts  map[types.NamespacedName]int
+	reasons map[types.NamespacedName]string
+	report  *Report
+}
+
+// NewEngine returns an engine which simulates c with algorithm.
+func NewEngine(c *Cluster, algorithm Algorithm) *Engine {
+	return &Engine{
+		Cluster:   c,
+		Algorithm: algorithm,
+		starts:    map[types.NamespacedName]int{},
+		reasons:   map[types.NamespacedName]string{},
+		report:    newReport(),
+	}
+}
+
+// Now returns the time of the virtual clock, as the offset from the start of the simulation.
+func (e *Engine) Now() time.Duration {
+	return e.now
+}
+
+// At runs fn at time t of the virtual clock, or at the current time if t has passed.
+func (e *Engine) At(t time.Duration, fn func() error) {
+	if t < e.now {
+		t = e.now
+	}
+	e.seq++
+	heap.Push(&e.events, &event{time: t, seq: e.seq, run: fn})
+}
+
+// AddPodAt adds pod to the cluster at time t.
+func (e *Engine) AddPodAt(t time.Duration, pod *corev1.Pod) {
+	e.At(t, func() error {
+		if err := e.Cluster.AddPod(pod); err != nil {
+			return err
+		}
+		e.report.record(PodKey(pod)).Arrival = e.now
+		e.RequestCycle()
+		return nil
+	})
+}
+
+// AddNodeAt adds node to the cluster at time t.
+func (e *Engine) AddNodeAt(t time.Duration, node *corev1.Node) {
+	e.At(t, func() error {
+		if err := e.Cluster.AddNode(node); err != nil {
+			return err
+		}
+		e.RequestCycle()
+		return nil
+	})
+}
+
+// RemoveNodeAt removes a node from the cluster at time t, its pods become pending again.
+func (e *Engine) RemoveNodeAt(t time.Duration, name string) {
+	e.At(t, func() error {
+		pods, err := e.Cluster.RemoveNode(name)
+		if err != nil {
+			return err
+		}
+		if len(pods) > 0 {
+			e.RequestCycle()
+		}
+		return nil
+	})
+}
+
+// RequestCycle runs a scheduling cycle at the current time, or at the next multiple of Period.
+func (e *Engine) RequestCycle() {
+	if e.cycleScheduled {
+		return
+	}
+	e.cycleScheduled = true
+	t := e.now
+	if e.Period > 0 && t%e.Period != 0 {
+		t += e.Period - t%e.Period
+	}
+	e.seq++
+	heap.Push(&e.events, &event{time: t, cycle: true, seq: e.seq, run: e.cycle})
+}
+
+// Run simulates the cluster until no events are left, or until Until.
+func (e *Engine) Run() (*Report, error) {
+	if err := e.load(); err != nil {
+		return nil, err
+	}
+	if e.Autoscaler != nil {
+		if err := e.Autoscaler.start(e); err != nil {
+			return nil, err
+		}
+	}
+	e.RequestCycle()
+	for e.events.Len() > 0 {
+		ev := heap.Pop(&e.events).(*event)
+		if e.Until > 0 && ev.time > e.Until {
+			// the simulation covers the time until Until, the cluster keeps its state since the last event
+			e.sample()
+			e.now = e.Until
+			break
+		}
+		if ev.time > e.now {
+			e.sample()
+			e.now = ev.time
+		}
+		if err := ev.run(); err != nil {
+			return nil, fmt.Errorf("at %s: %v", e.now, err)
+		}
+	}
+	e.sample()
+	e.report.End = e.now
+	for _, pod := range e.Cluster.PendingPods() {
+		key := PodKey(pod)
+		e.report.Unschedulable = append(e.report.Unschedulable, Placement{Pod: key, Reason: e.reasons[key]})
+	}
+	return e.report, nil
+}
+
+// load moves the nodes and pods of the cluster which arrive later to events, and
+// starts the pods which are already bound.
+func (e *Engine) load() error {
+	for _, node := range e.Cluster.Nodes() {
+		arrival, err := arrivalTime(&node.Node.ObjectMeta)
+		if err != nil {
+			return fmt.Errorf("node %s: %v", node.Node.Name, err)
+		}
+		if arrival > 0 && len(node.Pods) == 0 {
+			n := node.Node
+			if _, err := e.Cluster.RemoveNode(n.Name); err != nil {
+				return err
+			}
+			e.AddNodeAt(arrival, n)
+		}
+	}
+	for _, pod := range e.Cluster.Pods() {
+		key := PodKey(pod)
+		if isTerminated(pod) {
+			continue
+		}
+		if len(pod.Spec.NodeName) > 0 {
+			e.report.record(key)
+			if err := e.started(key); err != nil {
+				return err
+			}
+			continue
+		}
+		arrival, err := arrivalTime(&pod.ObjectMeta)
+		if err != nil {
+			return fmt.Errorf("pod %s: %v", key, err)
+		}
+		if arrival > 0 {
+			if err := e.Cluster.RemovePod(key); err != nil {
+				return err
+			}
+			e.AddPodAt(arrival, pod)
+			continue
+		}
+		e.report.record(key)
+	}
+	return nil
+}
+
+func (e *Engine) cycle() error {
+	e.cycleScheduled = false
+	if e.Cluster.PendingCount() == 0 {
+		return nil
+	}
+	result, err := e.Algorithm.Run(e.Cluster)
+	if err != nil {
+		return err
+	}
+	for _, placement := range result.Scheduled {
+		delete(e.reasons, placement.Pod)
+		if err := e.started(placement.Pod); err != nil {
+			return err
+		}
+	}
+	for _, placement := range result.Unschedulable {
+		e.reasons[placement.Pod] = placement.Reason
+	}
+	for _, placement := range result.Evicted {
+		e.report.record(placement.Pod).Evictions++
+	}
+	for _, preemption := range result.Preemptions {
+		preemption.Time = e.now
+		e.report.Preemptions = append(e.report.Preemptions, preemption)
+	}
+	if len(result.Evicted) > 0 {
+		// the evicted pods are pending again, and may fit elsewhere
+		e.RequestCycle()
+	}
+	if e.Autoscaler != nil && len(result.Unschedulable) > 0 {
+		e.Autoscaler.scaleUp(result)
+	}
+	e.report.Cycles++
+	return nil
+}
+
+// started records the start of a bound pod, and completes it after its runtime.
+func (e *Engine) started(key types.NamespacedName) error {
+	pod := e.Cluster.Pod(key)
+	record := e.report.record(key)
+	record.Node, record.Start, record.Started = pod.Spec.NodeName, e.now, true
+
+	runtime, ok, err := runtimeOf(pod)
+	if err != nil || !ok {
+		return err
+	}
+	e.starts[key]++
+	start := e.starts[key]
+	e.At(e.now+runtime, func() error {
+		pod := e.Cluster.Pod(key)
+		if pod == nil || e.starts[key] != start || len(pod.Spec.NodeName) == 0 || isTerminated(pod) {
+			return nil
+		}
+		if err := e.Cluster.Complete(key); err != nil {
+			return err
+		}
+		record.End, record.Finished = e.now, true
+		e.RequestCycle()
+		return nil
+	})
+	return nil
+}
+
+func (e *Engine) sample() {
+	sample := Sample{
+		Time:        e.now,
+		Nodes:       len(e.Cluster.nodeNames),
+		Pending:     e.Cluster.PendingCount(),
+		Utilization: e.Cluster.Utilization(),
+	}
+	for _, node := range e.Cluster.nodes {
+		sample.Running += len(node.Pods)
+	}
+	e.report.addSample(sample)
+}
+
+func arrivalTime(meta *metav1.ObjectMeta) (time.Duration, error) {
+	value, ok := meta.Annotations[generate.ArrivalTimeAnnotationKey]
+	if !ok {
+		return 0, nil
+	}
+	arrival, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s annotation %q", generate.ArrivalTimeAnnotationKey, value)
+	}
+	return arrival, nil
+}
+
+// runtimeOf returns the runtime of pod, pods without a runtime annotation run forever.
+func runtimeOf(pod *corev1.Pod) (time.Duration, bool, error) {
+	value, ok := pod.Annotations[generate.RuntimeAnnotationKey]
+	if !ok {
+		return 0, false, nil
+	}
+	runtime, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, false, fmt.Errorf("pod %s: invalid %s annotation %q", PodKey(pod), generate.RuntimeAnnotationKey, value)
+	}
+	return runtime, true, nil
+}
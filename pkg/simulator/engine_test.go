@@ -0,0 +1,100 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulator
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/D0m021ng/scheduler-simulator/pkg/simctl/generate"
+)
+
+func TestEngineQueuesArrivals(t *testing.T) {
+	c := newTestCluster(t, "z1")
+	// the node fits two of the pods at a time
+	for i, arrival := range []string{"0s", "1m", "2m", "10m"} {
+		pod := newTestPod(string(rune('a'+i)), "2", nil)
+		pod.Annotations[generate.ArrivalTimeAnnotationKey] = arrival
+		pod.Annotations[generate.RuntimeAnnotationKey] = "5m"
+		if err := c.AddPod(pod); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	engine := NewEngine(c, NewDefaultScheduler())
+	report, err := engine.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if started, finished := report.Counts(); started != 4 || finished != 4 || len(report.Unschedulable) != 0 {
+		t.Fatalf("got %d started and %d finished pods, %d unschedulable", started, finished, len(report.Unschedulable))
+	}
+	// c waits for a to complete at 5m, d arrives after b and c completed
+	wantWaits := []time.Duration{0, 0, 3 * time.Minute, 0}
+	for i, record := range report.Pods {
+		if record.Wait() != wantWaits[i] {
+			t.Errorf("pod %s waited %s, want %s", record.Pod, record.Wait(), wantWaits[i])
+		}
+	}
+	if report.End != 15*time.Minute {
+		t.Errorf("simulation ended at %s", report.End)
+	}
+	// the node is fully requested from 1m to 6m, and half for the other 10m
+	if got := report.AverageUtilization()["cpu"]; got < 0.66 || got > 0.67 {
+		t.Errorf("got average cpu utilization %f, want 2/3", got)
+	}
+
+	var timeline bytes.Buffer
+	if err := report.WriteTimeline(&timeline); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(timeline.String()), "\n"); lines[0] != "seconds,nodes,pending,running,cpu,memory,pods" ||
+		len(lines) != len(report.Timeline)+1 {
+		t.Errorf("got timeline %s", timeline.String())
+	}
+}
+
+func TestEnginePeriodAndUntil(t *testing.T) {
+	c := newTestCluster(t, "z1")
+	pod := newTestPod("a", "1", nil)
+	pod.Annotations[generate.ArrivalTimeAnnotationKey] = "90s"
+	if err := c.AddPod(pod); err != nil {
+		t.Fatal(err)
+	}
+	late := newTestPod("late", "1", nil)
+	late.Annotations[generate.ArrivalTimeAnnotationKey] = "1h"
+	if err := c.AddPod(late); err != nil {
+		t.Fatal(err)
+	}
+
+	engine := NewEngine(c, NewDefaultScheduler())
+	engine.Period, engine.Until = time.Minute, 30*time.Minute
+	report, err := engine.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Pods) != 1 || report.Pods[0].Start != 2*time.Minute {
+		t.Fatalf("got records %+v", report.Pods)
+	}
+	if report.End != 30*time.Minute {
+		t.Errorf("simulation ended at %s, want at 30m", report.End)
+	}
+	// a requests a quarter of the cpu of the node from 2m until the end
+	if got, want := report.AverageUtilization()["cpu"], 0.25*28/30; got < want-1e-9 || got > want+1e-9 {
+		t.Errorf("got average cpu utilization %f, want %f", got, want)
+	}
+}
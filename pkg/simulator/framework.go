@@ -0,0 +1,100 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// MaxNodeScore is the highest score a score plugin gives a node after normalization.
+const MaxNodeScore int64 = 100
+
+// Plugin is a plugin of the scheduling pipeline, named like its kube-scheduler counterpart.
+type Plugin interface {
+	Name() string
+}
+
+// PreFilterPlugin computes the state of a plugin for a pod once per scheduling cycle,
+// before its nodes are filtered and scored. Pods are scheduled one at a time, so
+// plugins keep this state themselves.
+type PreFilterPlugin interface {
+	Plugin
+	PreFilter(c *Cluster, pod *corev1.Pod)
+}
+
+// FilterPlugin returns the reasons why pod can not run on node, or nothing if it can.
+type FilterPlugin interface {
+	Plugin
+	Filter(pod *corev1.Pod, node *NodeInfo) []string
+}
+
+// ScorePlugin scores a node which passed all filters for pod, higher is better.
+type ScorePlugin interface {
+	Plugin
+	Score(pod *corev1.Pod, node *NodeInfo) int64
+}
+
+// ScoreNormalizer is a ScorePlugin whose scores of all feasible nodes are scaled
+// to [0, MaxNodeScore] before they are weighted.
+type ScoreNormalizer interface {
+	NormalizeScores(scores []int64)
+}
+
+// FitError is the error of a pod which fits on no node, with the count of nodes per reason.
+type FitError struct {
+	Pod         *corev1.Pod
+	NumAllNodes int
+	Reasons     map[string]int
+}
+
+// Error returns the message of the FailedScheduling event kube-scheduler records for the pod.
+func (f *FitError) Error() string {
+	if f.NumAllNodes == 0 {
+		return "no nodes available to schedule pods"
+	}
+	var reasons []string
+	for reason, count := range f.Reasons {
+		reasons = append(reasons, fmt.Sprintf("%d %s", count, reason))
+	}
+	sort.Strings(reasons)
+	return fmt.Sprintf("0/%d nodes are available: %s.", f.NumAllNodes, strings.Join(reasons, ", "))
+}
+
+// defaultNormalizeScore scales scores by the highest of them to [0, MaxNodeScore],
+// reversed if lower raw scores are better.
+func defaultNormalizeScore(scores []int64, reverse bool) {
+	var max int64
+	for _, score := range scores {
+		if score > max {
+			max = score
+		}
+	}
+	for i := range scores {
+		switch {
+		case max == 0 && reverse:
+			scores[i] = MaxNodeScore
+		case max == 0:
+			scores[i] = 0
+		case reverse:
+			scores[i] = MaxNodeScore - MaxNodeScore*scores[i]/max
+		default:
+			scores[i] = MaxNodeScore * scores[i] / max
+		}
+	}
+}
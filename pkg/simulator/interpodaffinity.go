@@ -0,0 +1,249 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulator
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// hardPodAffinityWeight is the score weight of the required affinity terms of the
+// existing pods which match the pod, as defaulted by kube-scheduler.
+const hardPodAffinityWeight int64 = 1
+
+// affinityTerm is a pod affinity term of a pod, with the namespaces it applies to.
+type affinityTerm struct {
+	namespaces  map[string]bool
+	selector    labels.Selector
+	topologyKey string
+	weight      int64
+}
+
+func newAffinityTerm(pod *corev1.Pod, term *corev1.PodAffinityTerm, weight int64) *affinityTerm {
+	namespaces := map[string]bool{}
+	for _, ns := range term.Namespaces {
+		namespaces[ns] = true
+	}
+	if len(namespaces) == 0 {
+		namespaces[pod.Namespace] = true
+	}
+	selector, err := metav1.LabelSelectorAsSelector(term.LabelSelector)
+	if err != nil {
+		selector = labels.Nothing()
+	}
+	return &affinityTerm{namespaces: namespaces, selector: selector, topologyKey: term.TopologyKey, weight: weight}
+}
+
+func (t *affinityTerm) matches(pod *corev1.Pod) bool {
+	return t.namespaces[pod.Namespace] && t.selector.Matches(labels.Set(pod.Labels))
+}
+
+// InterPodAffinity filters out the nodes which break the required pod affinity and anti-affinity
+// of the pod, or the required anti-affinity of the pods already running, and scores the nodes by
+// the preferred terms of the pod and of the running pods.
+type InterPodAffinity struct {
+	affinity     []*affinityTerm
+	antiAffinity []*affinityTerm
+	// affinityDomains are the topology values with pods matching each affinity term.
+	affinityDomains []map[string]bool
+	// antiAffinityDomains are the topology values with pods matching each anti-affinity term.
+	antiAffinityDomains []map[string]bool
+	// existingAntiAffinity are the topology domains whose running pods do not accept the pod.
+	existingAntiAffinity map[topologyPair]bool
+	// scores are the preferred scores of the pod per topology key and value.
+	scores map[string]map[string]int64
+}
+
+func (p *InterPodAffinity) Name() string {
+	return "InterPodAffinity"
+}
+
+func (p *InterPodAffinity) PreFilter(c *Cluster, pod *corev1.Pod) {
+	p.affinity, p.antiAffinity = nil, nil
+	p.existingAntiAffinity = map[topologyPair]bool{}
+	p.scores = map[string]map[string]int64{}
+
+	var preferred []*affinityTerm
+	if affinity := pod.Spec.Affinity; affinity != nil {
+		if affinity.PodAffinity != nil {
+			for i := range affinity.PodAffinity.RequiredDuringSchedulingIgnoredDuringExecution {
+				term := &affinity.PodAffinity.RequiredDuringSchedulingIgnoredDuringExecution[i]
+				p.affinity = append(p.affinity, newAffinityTerm(pod, term, 0))
+			}
+			for _, term := range affinity.PodAffinity.PreferredDuringSchedulingIgnoredDuringExecution {
+				preferred = append(preferred, newAffinityTerm(pod, &term.PodAffinityTerm, int64(term.Weight)))
+			}
+		}
+		if affinity.PodAntiAffinity != nil {
+			for i := range affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution {
+				term := &affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution[i]
+				p.antiAffinity = append(p.antiAffinity, newAffinityTerm(pod, term, 0))
+			}
+			for _, term := range affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution {
+				preferred = append(preferred, newAffinityTerm(pod, &term.PodAffinityTerm, -int64(term.Weight)))
+			}
+		}
+	}
+	p.affinityDomains = make([]map[string]bool, len(p.affinity))
+	for i := range p.affinity {
+		p.affinityDomains[i] = map[string]bool{}
+	}
+	p.antiAffinityDomains = make([]map[string]bool, len(p.antiAffinity))
+	for i := range p.antiAffinity {
+		p.antiAffinityDomains[i] = map[string]bool{}
+	}
+
+	// without terms of its own, only the running pods with terms can affect the pod
+	hasTerms := len(p.affinity) > 0 || len(p.antiAffinity) > 0 || len(preferred) > 0
+	for _, node := range c.Nodes() {
+		pods := node.podsWithAffinity
+		if hasTerms {
+			pods = node.Pods
+		}
+		for _, existing := range pods {
+			p.addExistingPod(pod, existing, node.Node, preferred)
+		}
+	}
+}
+
+// addExistingPod adds the domains and scores of a pod running on node.
+func (p *InterPodAffinity) addExistingPod(pod, existing *corev1.Pod, node *corev1.Node, preferred []*affinityTerm) {
+	for i, term := range p.affinity {
+		if value, ok := node.Labels[term.topologyKey]; ok && term.matches(existing) {
+			p.affinityDomains[i][value] = true
+		}
+	}
+	for i, term := range p.antiAffinity {
+		if value, ok := node.Labels[term.topologyKey]; ok && term.matches(existing) {
+			p.antiAffinityDomains[i][value] = true
+		}
+	}
+	for _, term := range preferred {
+		if term.matches(existing) {
+			p.addScore(node, term.topologyKey, term.weight)
+		}
+	}
+
+	affinity := existing.Spec.Affinity
+	if affinity == nil {
+		return
+	}
+	if affinity.PodAntiAffinity != nil {
+		for i := range affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution {
+			term := newAffinityTerm(existing, &affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution[i], 0)
+			if value, ok := node.Labels[term.topologyKey]; ok && term.matches(pod) {
+				p.existingAntiAffinity[topologyPair{key: term.topologyKey, value: value}] = true
+			}
+		}
+		for _, weighted := range affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution {
+			if term := newAffinityTerm(existing, &weighted.PodAffinityTerm, 0); term.matches(pod) {
+				p.addScore(node, term.topologyKey, -int64(weighted.Weight))
+			}
+		}
+	}
+	if affinity.PodAffinity != nil {
+		for i := range affinity.PodAffinity.RequiredDuringSchedulingIgnoredDuringExecution {
+			term := newAffinityTerm(existing, &affinity.PodAffinity.RequiredDuringSchedulingIgnoredDuringExecution[i], 0)
+			if term.matches(pod) {
+				p.addScore(node, term.topologyKey, hardPodAffinityWeight)
+			}
+		}
+		for _, weighted := range affinity.PodAffinity.PreferredDuringSchedulingIgnoredDuringExecution {
+			if term := newAffinityTerm(existing, &weighted.PodAffinityTerm, 0); term.matches(pod) {
+				p.addScore(node, term.topologyKey, int64(weighted.Weight))
+			}
+		}
+	}
+}
+
+func (p *InterPodAffinity) addScore(node *corev1.Node, topologyKey string, weight int64) {
+	value, ok := node.Labels[topologyKey]
+	if !ok || weight == 0 {
+		return
+	}
+	if p.scores[topologyKey] == nil {
+		p.scores[topologyKey] = map[string]int64{}
+	}
+	p.scores[topologyKey][value] += weight
+}
+
+func (p *InterPodAffinity) Filter(pod *corev1.Pod, node *NodeInfo) []string {
+	for pair := range p.existingAntiAffinity {
+		if value, ok := node.Node.Labels[pair.key]; ok && value == pair.value {
+			return []string{"node(s) didn't satisfy existing pods anti-affinity rules"}
+		}
+	}
+	if !p.satisfiesAffinity(pod, node.Node) {
+		return []string{"node(s) didn't match pod affinity rules"}
+	}
+	for i, term := range p.antiAffinity {
+		if value, ok := node.Node.Labels[term.topologyKey]; ok && p.antiAffinityDomains[i][value] {
+			return []string{"node(s) didn't match pod anti-affinity rules"}
+		}
+	}
+	return nil
+}
+
+// satisfiesAffinity returns whether node is in a domain with matching pods for every affinity
+// term. The first pod of a group which matches its own terms can go to any node, otherwise
+// such a group could never be scheduled.
+func (p *InterPodAffinity) satisfiesAffinity(pod *corev1.Pod, node *corev1.Node) bool {
+	matched, selfMatch := true, true
+	for i, term := range p.affinity {
+		value, ok := node.Labels[term.topologyKey]
+		if !ok || !p.affinityDomains[i][value] {
+			matched = false
+		}
+		if len(p.affinityDomains[i]) > 0 || !term.matches(pod) {
+			selfMatch = false
+		}
+	}
+	return matched || selfMatch
+}
+
+// Score returns the sum of the preferred scores of the domains of node.
+func (p *InterPodAffinity) Score(pod *corev1.Pod, node *NodeInfo) int64 {
+	var score int64
+	for key, values := range p.scores {
+		if value, ok := node.Node.Labels[key]; ok {
+			score += values[value]
+		}
+	}
+	return score
+}
+
+// NormalizeScores scales the scores, which can be negative, between their lowest and highest.
+func (p *InterPodAffinity) NormalizeScores(scores []int64) {
+	if len(scores) == 0 {
+		return
+	}
+	min, max := scores[0], scores[0]
+	for _, score := range scores {
+		if score < min {
+			min = score
+		}
+		if score > max {
+			max = score
+		}
+	}
+	for i, score := range scores {
+		if max == min {
+			scores[i] = 0
+			continue
+		}
+		scores[i] = MaxNodeScore * (score - min) / (max - min)
+	}
+}
@@ -0,0 +1,121 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulator
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/D0m021ng/scheduler-simulator/pkg/simctl/generate"
+)
+
+// LoadFiles loads the objects of files, as written by simctl generate, import or snapshot,
+// into a new cluster. The nodes of all files are added before the pods, so that the pods
+// which are bound to nodes can be in any file.
+func LoadFiles(filenames ...string) (*Cluster, error) {
+	var objs []*unstructured.Unstructured
+	for _, filename := range filenames {
+		fileObjs, err := generate.ReadYamlFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		objs = append(objs, flattenLists(fileObjs)...)
+	}
+
+	c := NewCluster()
+	var pods []*unstructured.Unstructured
+	for _, obj := range objs {
+		switch obj.GroupVersionKind().GroupKind() {
+		case corev1.SchemeGroupVersion.WithKind("Node").GroupKind():
+			node := &corev1.Node{}
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, node); err != nil {
+				return nil, fmt.Errorf("node %s: %v", obj.GetName(), err)
+			}
+			if err := c.AddNode(node); err != nil {
+				return nil, err
+			}
+		case corev1.SchemeGroupVersion.WithKind("Pod").GroupKind():
+			pods = append(pods, obj)
+		default:
+			c.Objects = append(c.Objects, obj)
+		}
+	}
+	classes, defaultClass := priorityClasses(c.Objects)
+	for _, obj := range pods {
+		pod := &corev1.Pod{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, pod); err != nil {
+			return nil, fmt.Errorf("pod %s: %v", obj.GetName(), err)
+		}
+		// resolve the priority and preemption policy of pods which were not admitted by an api server, as it would
+		class, ok := classes[pod.Spec.PriorityClassName]
+		if !ok && len(pod.Spec.PriorityClassName) == 0 {
+			class, ok = defaultClass, defaultClass != nil
+		}
+		if ok {
+			if pod.Spec.Priority == nil {
+				pod.Spec.Priority = &class.Value
+			}
+			if pod.Spec.PreemptionPolicy == nil {
+				pod.Spec.PreemptionPolicy = class.PreemptionPolicy
+			}
+		}
+		if err := c.AddPod(pod); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// priorityClasses returns the priority classes in objs by name, and the global default one.
+func priorityClasses(objs []*unstructured.Unstructured) (map[string]*schedulingv1.PriorityClass, *schedulingv1.PriorityClass) {
+	classes := map[string]*schedulingv1.PriorityClass{}
+	var defaultClass *schedulingv1.PriorityClass
+	for _, obj := range objs {
+		if obj.GroupVersionKind().GroupKind() != schedulingv1.SchemeGroupVersion.WithKind("PriorityClass").GroupKind() {
+			continue
+		}
+		pc := &schedulingv1.PriorityClass{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, pc); err != nil {
+			continue
+		}
+		classes[pc.Name] = pc
+		if pc.GlobalDefault {
+			defaultClass = pc
+		}
+	}
+	return classes, defaultClass
+}
+
+// flattenLists replaces the lists in objs with their items.
+func flattenLists(objs []*unstructured.Unstructured) []*unstructured.Unstructured {
+	var flat []*unstructured.Unstructured
+	for _, obj := range objs {
+		if !obj.IsList() {
+			flat = append(flat, obj)
+			continue
+		}
+		_ = obj.EachListItem(func(item runtime.Object) error {
+			if u, ok := item.(*unstructured.Unstructured); ok {
+				flat = append(flat, u)
+			}
+			return nil
+		})
+	}
+	return flat
+}
@@ -0,0 +1,75 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulator
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/component-helpers/scheduling/corev1/nodeaffinity"
+)
+
+// NodeAffinity filters out the nodes which do not match the node selector and the required node
+// affinity of the pod, and prefers the nodes which match more of its preferred terms.
+type NodeAffinity struct {
+	preferred *nodeaffinity.PreferredSchedulingTerms
+}
+
+func (p *NodeAffinity) Name() string {
+	return "NodeAffinity"
+}
+
+func (p *NodeAffinity) PreFilter(c *Cluster, pod *corev1.Pod) {
+	p.preferred = nil
+	if affinity := pod.Spec.Affinity; affinity != nil && affinity.NodeAffinity != nil {
+		terms := affinity.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution
+		// invalid terms match no node, as in kube-scheduler
+		p.preferred, _ = nodeaffinity.NewPreferredSchedulingTerms(terms)
+	}
+}
+
+func (p *NodeAffinity) Filter(pod *corev1.Pod, node *NodeInfo) []string {
+	if !matchesNodeSelector(pod, node.Node) {
+		return []string{"node(s) didn't match node selector"}
+	}
+	return nil
+}
+
+// Score returns the sum of the weights of the preferred terms of pod which node matches.
+func (p *NodeAffinity) Score(pod *corev1.Pod, node *NodeInfo) int64 {
+	if p.preferred == nil {
+		return 0
+	}
+	return p.preferred.Score(node.Node)
+}
+
+func (p *NodeAffinity) NormalizeScores(scores []int64) {
+	defaultNormalizeScore(scores, false)
+}
+
+// matchesNodeSelector returns whether node matches both the node selector and the required node affinity of pod.
+func matchesNodeSelector(pod *corev1.Pod, node *corev1.Node) bool {
+	if len(pod.Spec.NodeSelector) > 0 &&
+		!labels.SelectorFromSet(pod.Spec.NodeSelector).Matches(labels.Set(node.Labels)) {
+		return false
+	}
+	affinity := pod.Spec.Affinity
+	if affinity == nil || affinity.NodeAffinity == nil ||
+		affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return true
+	}
+	matches, _ := nodeaffinity.NewLazyErrorNodeSelector(
+		affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution).Match(node)
+	return matches
+}
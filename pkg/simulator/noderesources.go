@@ -0,0 +1,157 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulator
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ScoringStrategyType is how NodeResourcesFit scores the resources of nodes.
+type ScoringStrategyType string
+
+const (
+	// LeastAllocated prefers the nodes with the most free resources, which spreads pods.
+	LeastAllocated ScoringStrategyType = "LeastAllocated"
+	// MostAllocated prefers the nodes with the least free resources, which packs pods.
+	MostAllocated ScoringStrategyType = "MostAllocated"
+	// RequestedToCapacityRatio scores the utilization of nodes by a piecewise linear shape.
+	RequestedToCapacityRatio ScoringStrategyType = "RequestedToCapacityRatio"
+)
+
+// maxShapeScore is the highest score of a point of a RequestedToCapacityRatio shape.
+const maxShapeScore int64 = 10
+
+// ResourceWeight is a resource scored by NodeResourcesFit, with its weight in the score of a node.
+type ResourceWeight struct {
+	Name   corev1.ResourceName `json:"name"`
+	Weight int64               `json:"weight"`
+}
+
+// UtilizationShapePoint maps the utilization of a resource, in percent, to a score in [0, 10].
+type UtilizationShapePoint struct {
+	Utilization int64 `json:"utilization"`
+	Score       int64 `json:"score"`
+}
+
+// ScoringStrategy configures the scores of NodeResourcesFit, like the scoringStrategy of its
+// kube-scheduler plugin args. Resources default to cpu and memory with a weight of 1.
+type ScoringStrategy struct {
+	Type      ScoringStrategyType
+	Resources []ResourceWeight
+	// Shape is the utilization shape of RequestedToCapacityRatio, in increasing utilization.
+	Shape []UtilizationShapePoint
+}
+
+// NodeResourcesFit filters out the nodes without enough free resources for the pod, and
+// scores the resources the nodes would have requested with the pod by its strategy.
+type NodeResourcesFit struct {
+	Strategy ScoringStrategy
+
+	podRequests corev1.ResourceList
+}
+
+// NewNodeResourcesFit returns a NodeResourcesFit scoring resources with strategy.
+func NewNodeResourcesFit(strategy ScoringStrategy) *NodeResourcesFit {
+	if strategy.Type == "" {
+		strategy.Type = LeastAllocated
+	}
+	if len(strategy.Resources) == 0 {
+		strategy.Resources = []ResourceWeight{
+			{Name: corev1.ResourceCPU, Weight: 1},
+			{Name: corev1.ResourceMemory, Weight: 1},
+		}
+	}
+	if strategy.Type == RequestedToCapacityRatio && len(strategy.Shape) == 0 {
+		// the default shape of kube-scheduler, which prefers the least utilized nodes
+		strategy.Shape = []UtilizationShapePoint{{Utilization: 0, Score: maxShapeScore}, {Utilization: 100, Score: 0}}
+	}
+	return &NodeResourcesFit{Strategy: strategy}
+}
+
+func (p *NodeResourcesFit) Name() string {
+	return "NodeResourcesFit"
+}
+
+func (p *NodeResourcesFit) PreFilter(c *Cluster, pod *corev1.Pod) {
+	p.podRequests = PodRequests(pod)
+	for name, quantity := range nonZeroRequests(pod) {
+		if name == corev1.ResourceCPU || name == corev1.ResourceMemory {
+			p.podRequests[name] = quantity
+		}
+	}
+}
+
+func (p *NodeResourcesFit) Filter(pod *corev1.Pod, node *NodeInfo) []string {
+	return node.Fits(pod)
+}
+
+// Score returns the weighted average of the scores of the resources of node.
+func (p *NodeResourcesFit) Score(pod *corev1.Pod, node *NodeInfo) int64 {
+	allocatable := node.Allocatable()
+	var score, weights int64
+	for _, resource := range p.Strategy.Resources {
+		capacity := quantityValue(resource.Name, allocatable[resource.Name])
+		requested := node.Requested[resource.Name]
+		if resource.Name == corev1.ResourceCPU || resource.Name == corev1.ResourceMemory {
+			requested = node.nonZeroRequested[resource.Name]
+		}
+		requested = requested.DeepCopy()
+		requested.Add(p.podRequests[resource.Name])
+
+		if capacity == 0 && p.Strategy.Type == RequestedToCapacityRatio {
+			continue
+		}
+		score += p.resourceScore(quantityValue(resource.Name, requested), capacity) * resource.Weight
+		weights += resource.Weight
+	}
+	if weights == 0 {
+		return 0
+	}
+	return score / weights
+}
+
+func (p *NodeResourcesFit) resourceScore(requested, capacity int64) int64 {
+	switch p.Strategy.Type {
+	case MostAllocated:
+		if capacity == 0 {
+			return 0
+		}
+		if requested > capacity {
+			requested = capacity
+		}
+		return requested * MaxNodeScore / capacity
+	case RequestedToCapacityRatio:
+		return shapeScore(p.Strategy.Shape, requested*100/capacity) * MaxNodeScore / maxShapeScore
+	default:
+		if capacity == 0 || requested > capacity {
+			return 0
+		}
+		return (capacity - requested) * MaxNodeScore / capacity
+	}
+}
+
+// shapeScore interpolates the score of utilization between the points of shape.
+func shapeScore(shape []UtilizationShapePoint, utilization int64) int64 {
+	if utilization <= shape[0].Utilization {
+		return shape[0].Score
+	}
+	for i := 1; i < len(shape); i++ {
+		if utilization <= shape[i].Utilization {
+			prev, next := shape[i-1], shape[i]
+			return prev.Score + (next.Score-prev.Score)*(utilization-prev.Utilization)/(next.Utilization-prev.Utilization)
+		}
+	}
+	return shape[len(shape)-1].Score
+}
@@ -0,0 +1,98 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulator
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// DisruptionBudgets returns the PodDisruptionBudgets of the cluster of any version of the policy group,
+// with the status which the disruption controller would compute from its pods. An empty selector
+// selects every pod of the namespace in policy/v1, but none in policy/v1beta1, whose selector is
+// cleared so that it selects nothing whatever the version.
+func DisruptionBudgets(c *Cluster) ([]*policyv1beta1.PodDisruptionBudget, error) {
+	var pdbs []*policyv1beta1.PodDisruptionBudget
+	for _, obj := range c.Objects {
+		if obj.GetKind() != "PodDisruptionBudget" || obj.GroupVersionKind().Group != policyv1beta1.GroupName {
+			continue
+		}
+		pdb := &policyv1beta1.PodDisruptionBudget{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, pdb); err != nil {
+			return nil, fmt.Errorf("PodDisruptionBudget %s: %v", obj.GetName(), err)
+		}
+		if pdb.Namespace == "" {
+			pdb.Namespace = metav1.NamespaceDefault
+		}
+		if selector := pdb.Spec.Selector; obj.GroupVersionKind().Version == "v1beta1" && selector != nil &&
+			len(selector.MatchLabels) == 0 && len(selector.MatchExpressions) == 0 {
+			pdb.Spec.Selector = nil
+		}
+		if err := disruptionStatus(pdb, c.Pods()); err != nil {
+			return nil, fmt.Errorf("PodDisruptionBudget %s: %v", obj.GetName(), err)
+		}
+		pdbs = append(pdbs, pdb)
+	}
+	return pdbs, nil
+}
+
+// disruptionStatus sets the status of pdb from the pods which it selects, bound pods are healthy.
+func disruptionStatus(pdb *policyv1beta1.PodDisruptionBudget, pods []*corev1.Pod) error {
+	selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+	if err != nil {
+		return err
+	}
+	var expected, healthy int
+	for _, pod := range pods {
+		if pod.Namespace != pdb.Namespace || !selector.Matches(labels.Set(pod.Labels)) || isTerminated(pod) {
+			continue
+		}
+		expected++
+		if pod.Spec.NodeName != "" {
+			healthy++
+		}
+	}
+
+	desired := 0
+	switch {
+	case pdb.Spec.MinAvailable != nil:
+		if desired, err = intstr.GetScaledValueFromIntOrPercent(pdb.Spec.MinAvailable, expected, true); err != nil {
+			return err
+		}
+	case pdb.Spec.MaxUnavailable != nil:
+		unavailable, err := intstr.GetScaledValueFromIntOrPercent(pdb.Spec.MaxUnavailable, expected, true)
+		if err != nil {
+			return err
+		}
+		desired = expected - unavailable
+	}
+	allowed := healthy - desired
+	if allowed < 0 {
+		allowed = 0
+	}
+	pdb.Status = policyv1beta1.PodDisruptionBudgetStatus{
+		ExpectedPods:       int32(expected),
+		CurrentHealthy:     int32(healthy),
+		DesiredHealthy:     int32(desired),
+		DisruptionsAllowed: int32(allowed),
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulator
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+type topologyPair struct {
+	key   string
+	value string
+}
+
+type spreadConstraint struct {
+	maxSkew     int32
+	topologyKey string
+	selector    labels.Selector
+	// selfMatch is whether the selector matches the pod which is scheduled.
+	selfMatch bool
+}
+
+// PodTopologySpread filters out the nodes which would skew the pods matching the DoNotSchedule
+// spread constraints of the pod by more than their maxSkew, and prefers the topology domains
+// with fewer pods matching its ScheduleAnyway constraints.
+type PodTopologySpread struct {
+	required  []spreadConstraint
+	preferred []spreadConstraint
+	// counts are the pods matching each constraint per domain, by the index of the constraint.
+	requiredCounts  []map[string]int32
+	requiredMins    []int32
+	preferredCounts []map[string]int64
+}
+
+func (p *PodTopologySpread) Name() string {
+	return "PodTopologySpread"
+}
+
+func (p *PodTopologySpread) PreFilter(c *Cluster, pod *corev1.Pod) {
+	p.required, p.preferred = nil, nil
+	for _, constraint := range pod.Spec.TopologySpreadConstraints {
+		selector, err := metav1.LabelSelectorAsSelector(constraint.LabelSelector)
+		if err != nil {
+			selector = labels.Nothing()
+		}
+		sc := spreadConstraint{
+			maxSkew:     constraint.MaxSkew,
+			topologyKey: constraint.TopologyKey,
+			selector:    selector,
+			selfMatch:   selector.Matches(labels.Set(pod.Labels)),
+		}
+		if constraint.WhenUnsatisfiable == corev1.DoNotSchedule {
+			p.required = append(p.required, sc)
+		} else {
+			p.preferred = append(p.preferred, sc)
+		}
+	}
+	p.requiredCounts = make([]map[string]int32, len(p.required))
+	p.requiredMins = make([]int32, len(p.required))
+	for i := range p.required {
+		p.requiredCounts[i] = map[string]int32{}
+	}
+	p.preferredCounts = make([]map[string]int64, len(p.preferred))
+	for i := range p.preferred {
+		p.preferredCounts[i] = map[string]int64{}
+	}
+	if len(p.required) == 0 && len(p.preferred) == 0 {
+		return
+	}
+
+	// only the nodes the pod could be scheduled to by its node affinity are domains
+	for _, node := range c.Nodes() {
+		if !matchesNodeSelector(pod, node.Node) {
+			continue
+		}
+		if hasTopologyKeys(node.Node, p.required) {
+			for i, constraint := range p.required {
+				value := node.Node.Labels[constraint.topologyKey]
+				p.requiredCounts[i][value] += int32(countMatchingPods(pod.Namespace, constraint.selector, node))
+			}
+		}
+		if hasTopologyKeys(node.Node, p.preferred) {
+			for i, constraint := range p.preferred {
+				value := node.Node.Labels[constraint.topologyKey]
+				p.preferredCounts[i][value] += int64(countMatchingPods(pod.Namespace, constraint.selector, node))
+			}
+		}
+	}
+	for i, counts := range p.requiredCounts {
+		first := true
+		for _, count := range counts {
+			if first || count < p.requiredMins[i] {
+				p.requiredMins[i], first = count, false
+			}
+		}
+	}
+}
+
+func (p *PodTopologySpread) Filter(pod *corev1.Pod, node *NodeInfo) []string {
+	for i, constraint := range p.required {
+		value, ok := node.Node.Labels[constraint.topologyKey]
+		if !ok {
+			return []string{"node(s) didn't match pod topology spread constraints (missing required label)"}
+		}
+		var self int32
+		if constraint.selfMatch {
+			self = 1
+		}
+		if p.requiredCounts[i][value]+self-p.requiredMins[i] > constraint.maxSkew {
+			return []string{"node(s) didn't match pod topology spread constraints"}
+		}
+	}
+	return nil
+}
+
+// Score returns the count of pods matching the preferred constraints in the domains of node,
+// or -1 if node lacks one of their topology keys.
+func (p *PodTopologySpread) Score(pod *corev1.Pod, node *NodeInfo) int64 {
+	if !hasTopologyKeys(node.Node, p.preferred) {
+		return -1
+	}
+	var score int64
+	for i, constraint := range p.preferred {
+		score += p.preferredCounts[i][node.Node.Labels[constraint.topologyKey]]
+	}
+	return score
+}
+
+// NormalizeScores gives the nodes in the least crowded domains the highest score, and
+// the nodes which lack a topology key none.
+func (p *PodTopologySpread) NormalizeScores(scores []int64) {
+	min, max := int64(-1), int64(-1)
+	for _, score := range scores {
+		if score < 0 {
+			continue
+		}
+		if min < 0 || score < min {
+			min = score
+		}
+		if score > max {
+			max = score
+		}
+	}
+	for i, score := range scores {
+		switch {
+		case score < 0:
+			scores[i] = 0
+		case max == min:
+			scores[i] = MaxNodeScore
+		default:
+			scores[i] = MaxNodeScore * (max - score) / (max - min)
+		}
+	}
+}
+
+func hasTopologyKeys(node *corev1.Node, constraints []spreadConstraint) bool {
+	for _, constraint := range constraints {
+		if _, ok := node.Labels[constraint.topologyKey]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func countMatchingPods(namespace string, selector labels.Selector, node *NodeInfo) int {
+	count := 0
+	for _, pod := range node.Pods {
+		if pod.Namespace == namespace && selector.Matches(labels.Set(pod.Labels)) {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,219 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulator
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	corev1helpers "k8s.io/component-helpers/scheduling/corev1"
+)
+
+// Preemption is a pod which was bound to Node after the Victims of a lower priority were evicted from it.
+type Preemption struct {
+	// Time is the time of the simulation the preemption happened at, set by the Engine.
+	Time      time.Duration
+	Preemptor types.NamespacedName
+	Node      string
+	Victims   []types.NamespacedName
+}
+
+// candidate is a node where a pod fits once victims are evicted, violations are the
+// victims whose eviction violates a PodDisruptionBudget.
+type candidate struct {
+	node       *NodeInfo
+	victims    []*corev1.Pod
+	violations int
+}
+
+// preempt evicts the pods of the node where pod fits by evicting the least important pods, like
+// the DefaultPreemption plugin of kube-scheduler, and returns the preemption. It returns nil if
+// pod may not preempt or there is no such node.
+func (s *Scheduler) preempt(c *Cluster, pod *corev1.Pod, result *Result) (*Preemption, error) {
+	if pod.Spec.PreemptionPolicy != nil && *pod.Spec.PreemptionPolicy == corev1.PreemptNever {
+		return nil, nil
+	}
+	pdbs, err := DisruptionBudgets(c)
+	if err != nil {
+		return nil, err
+	}
+	var best *candidate
+	for _, node := range c.Nodes() {
+		candidate, err := s.selectVictims(c, pod, node, pdbs)
+		if err != nil {
+			return nil, err
+		}
+		if candidate != nil && (best == nil || candidate.better(best)) {
+			best = candidate
+		}
+	}
+	if best == nil {
+		return nil, nil
+	}
+
+	preemption := &Preemption{Preemptor: PodKey(pod), Node: best.node.Node.Name}
+	reason := fmt.Sprintf("preempted by %s", preemption.Preemptor)
+	for _, victim := range best.victims {
+		key := PodKey(victim)
+		if err := c.Unbind(key); err != nil {
+			return nil, err
+		}
+		SetPodScheduled(victim, corev1.ConditionFalse, corev1.PodReasonUnschedulable, reason)
+		preemption.Victims = append(preemption.Victims, key)
+		result.Evicted = append(result.Evicted, Placement{Pod: key, Node: preemption.Node, Reason: reason})
+	}
+	return preemption, nil
+}
+
+// selectVictims returns the pods of node with a lower priority than pod which must be evicted for pod
+// to fit, as few and as unimportant as possible, or nil if pod does not fit even without all of them.
+// The victims are unbound to check whether pod fits, the cluster is unchanged when it returns.
+func (s *Scheduler) selectVictims(c *Cluster, pod *corev1.Pod, node *NodeInfo, pdbs []*policyv1beta1.PodDisruptionBudget) (*candidate, error) {
+	priority := corev1helpers.PodPriority(pod)
+	var potential []*corev1.Pod
+	for _, p := range node.Pods {
+		if corev1helpers.PodPriority(p) < priority {
+			potential = append(potential, p)
+		}
+	}
+	if len(potential) == 0 {
+		return nil, nil
+	}
+	for _, victim := range potential {
+		if err := c.Unbind(PodKey(victim)); err != nil {
+			return nil, err
+		}
+	}
+	var victims []*corev1.Pod
+	restore := func() error {
+		for _, victim := range victims {
+			if err := c.Bind(PodKey(victim), node.Node.Name); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if !s.fits(c, pod, node) {
+		victims = potential
+		return nil, restore()
+	}
+
+	// reprieve the most important pods first, and those which are protected by a budget before the others
+	sort.SliceStable(potential, func(i, j int) bool {
+		return corev1helpers.PodPriority(potential[i]) > corev1helpers.PodPriority(potential[j])
+	})
+	violating, nonViolating := splitByDisruptionBudgets(potential, pdbs)
+	result := &candidate{node: node}
+	for i, group := range [][]*corev1.Pod{violating, nonViolating} {
+		for _, p := range group {
+			if err := c.Bind(PodKey(p), node.Node.Name); err != nil {
+				return nil, err
+			}
+			if s.fits(c, pod, node) {
+				continue
+			}
+			if err := c.Unbind(PodKey(p)); err != nil {
+				return nil, err
+			}
+			victims = append(victims, p)
+			if i == 0 {
+				result.violations++
+			}
+		}
+	}
+	if len(victims) == 0 {
+		return nil, nil
+	}
+	result.victims = victims
+	return result, restore()
+}
+
+// fits reports whether pod passes all filters on node.
+func (s *Scheduler) fits(c *Cluster, pod *corev1.Pod, node *NodeInfo) bool {
+	for _, plugin := range s.plugins() {
+		if p, ok := plugin.(PreFilterPlugin); ok {
+			p.PreFilter(c, pod)
+		}
+	}
+	return len(s.filter(pod, node)) == 0
+}
+
+// splitByDisruptionBudgets splits pods into those whose eviction would violate a PodDisruptionBudget,
+// counting the disruptions of the pods before them, and the others.
+func splitByDisruptionBudgets(pods []*corev1.Pod, pdbs []*policyv1beta1.PodDisruptionBudget) (violating, nonViolating []*corev1.Pod) {
+	allowed := make([]int32, len(pdbs))
+	selectors := make([]labels.Selector, len(pdbs))
+	for i, pdb := range pdbs {
+		allowed[i] = pdb.Status.DisruptionsAllowed
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil {
+			selector = labels.Nothing()
+		}
+		selectors[i] = selector
+	}
+	for _, pod := range pods {
+		violated := false
+		for i, pdb := range pdbs {
+			if pdb.Namespace != pod.Namespace || !selectors[i].Matches(labels.Set(pod.Labels)) {
+				continue
+			}
+			allowed[i]--
+			if allowed[i] < 0 {
+				violated = true
+			}
+		}
+		if violated {
+			violating = append(violating, pod)
+		} else {
+			nonViolating = append(nonViolating, pod)
+		}
+	}
+	return violating, nonViolating
+}
+
+// better reports whether the victims of c disrupt less than those of other: the fewest violated
+// budgets, then the lowest highest priority, the lowest sum of priorities and the fewest victims.
+func (c *candidate) better(other *candidate) bool {
+	if c.violations != other.violations {
+		return c.violations < other.violations
+	}
+	highest, sum := c.priorities()
+	otherHighest, otherSum := other.priorities()
+	if highest != otherHighest {
+		return highest < otherHighest
+	}
+	if sum != otherSum {
+		return sum < otherSum
+	}
+	return len(c.victims) < len(other.victims)
+}
+
+// priorities returns the highest and the sum of the priorities of the victims.
+func (c *candidate) priorities() (highest int32, sum int64) {
+	for i, victim := range c.victims {
+		priority := corev1helpers.PodPriority(victim)
+		if i == 0 || priority > highest {
+			highest = priority
+		}
+		sum += int64(priority)
+	}
+	return highest, sum
+}
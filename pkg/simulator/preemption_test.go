@@ -0,0 +1,139 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulator
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// newPreemptionCluster returns a full cluster, node a runs two pods of priority 0 with the label
+// app=db, node b runs a pod of priority 10 and a small one of priority 0.
+func newPreemptionCluster(t *testing.T) *Cluster {
+	c := newTestCluster(t, "z1", "z1")
+	bound := []struct {
+		name, app, cpu, node string
+		priority             int32
+	}{
+		{"db-1", "db", "2", "a", 0},
+		{"db-2", "db", "2", "a", 0},
+		{"mid", "web", "3", "b", 10},
+		{"small", "web", "1", "b", 0},
+	}
+	for _, p := range bound {
+		pod := newTestPod(p.name, p.cpu, map[string]string{"app": p.app})
+		priority := p.priority
+		pod.Spec.Priority = &priority
+		if err := c.AddPod(pod); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Bind(PodKey(pod), p.node); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return c
+}
+
+func TestPreemption(t *testing.T) {
+	minAvailable := intstr.FromInt(2)
+	pdb := &policyv1beta1.PodDisruptionBudget{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "policy/v1beta1", Kind: "PodDisruptionBudget"},
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "ns"},
+		Spec: policyv1beta1.PodDisruptionBudgetSpec{
+			MinAvailable: &minAvailable,
+			Selector:     &metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}},
+		},
+	}
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(pdb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a budget of all 4 pods of the namespace in policy/v1, but of none in policy/v1beta1
+	emptySelector := func(version string) map[string]interface{} {
+		return map[string]interface{}{
+			"apiVersion": "policy/" + version,
+			"kind":       "PodDisruptionBudget",
+			"metadata":   map[string]interface{}{"name": "all", "namespace": "ns"},
+			"spec":       map[string]interface{}{"minAvailable": int64(4), "selector": map[string]interface{}{}},
+		}
+	}
+	never := corev1.PreemptNever
+
+	tests := []struct {
+		name    string
+		pdb     map[string]interface{}
+		policy  *corev1.PreemptionPolicy
+		node    string
+		victims []string
+	}{
+		{name: "lowest priority victims", node: "a", victims: []string{"db-1", "db-2"}},
+		{name: "respects disruption budgets", pdb: obj, node: "b", victims: []string{"mid"}},
+		{name: "empty selector of policy/v1 selects all pods", pdb: emptySelector("v1"), node: "b", victims: []string{"mid"}},
+		{name: "empty selector of policy/v1beta1 selects no pods", pdb: emptySelector("v1beta1"), node: "a", victims: []string{"db-1", "db-2"}},
+		{name: "preemption policy never", policy: &never},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := newPreemptionCluster(t)
+			if test.pdb != nil {
+				c.Objects = append(c.Objects, &unstructured.Unstructured{Object: test.pdb})
+			}
+			pod := newTestPod("high", "3", nil)
+			priority := int32(100)
+			pod.Spec.Priority, pod.Spec.PreemptionPolicy = &priority, test.policy
+			if err := c.AddPod(pod); err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := NewDefaultScheduler().Run(c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.node == "" {
+				if len(result.Preemptions) != 0 || len(result.Unschedulable) != 1 {
+					t.Fatalf("got %+v, want the pod unschedulable without preemption", result)
+				}
+				return
+			}
+			if len(result.Preemptions) != 1 {
+				t.Fatalf("got preemptions %+v, want one", result.Preemptions)
+			}
+			preemption := result.Preemptions[0]
+			var victims []types.NamespacedName
+			for _, name := range test.victims {
+				victims = append(victims, types.NamespacedName{Namespace: "ns", Name: name})
+			}
+			if preemption.Node != test.node || !reflect.DeepEqual(preemption.Victims, victims) {
+				t.Fatalf("got preemption %+v, want victims %v on node %s", preemption, victims, test.node)
+			}
+			if node := c.Pod(preemption.Preemptor).Spec.NodeName; node != test.node || len(result.Evicted) != len(victims) {
+				t.Fatalf("preemptor bound to %q with evicted %+v", node, result.Evicted)
+			}
+			for _, victim := range victims {
+				if c.Pod(victim).Spec.NodeName != "" {
+					t.Errorf("victim %s is still bound", victim)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,243 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulator
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// PodRecord is the history of a pod in a simulation, as offsets from its start.
+type PodRecord struct {
+	Pod types.NamespacedName
+	// Node is the node the pod was last bound to.
+	Node    string
+	Arrival time.Duration
+	// Start is the time the pod was last bound, if it Started.
+	Start   time.Duration
+	Started bool
+	// End is the time the pod completed, if it Finished.
+	End      time.Duration
+	Finished bool
+	// Evictions counts how often the pod was evicted to make room for other pods.
+	Evictions int
+}
+
+// Wait returns how long a started pod waited to be scheduled since its arrival.
+func (r *PodRecord) Wait() time.Duration {
+	return r.Start - r.Arrival
+}
+
+// Sample is the state of the cluster at a time of the simulation, kept until the next sample.
+type Sample struct {
+	Time    time.Duration
+	Nodes   int
+	Pending int
+	Running int
+	// Utilization is the ratio of the requested to the allocatable resources of all nodes.
+	Utilization map[corev1.ResourceName]float64
+}
+
+// Report is the outcome of a simulation.
+type Report struct {
+	// Pods are the records of all pods, in the order they arrived.
+	Pods []*PodRecord
+	// Timeline has a sample at every time the state of the cluster changed.
+	Timeline []Sample
+	// End is the time of the last event.
+	End    time.Duration
+	Cycles int
+	// Unschedulable are the pods still pending at the end, with the last reason they did not fit.
+	Unschedulable []Placement
+	// Preemptions are the preemptions of all scheduling cycles, in the order they happened.
+	Preemptions []Preemption
+	// NodesAdded and NodesRemoved count the nodes which the Autoscaler added and removed.
+	NodesAdded   int
+	NodesRemoved int
+
+	records map[types.NamespacedName]*PodRecord
+}
+
+func newReport() *Report {
+	return &Report{records: map[types.NamespacedName]*PodRecord{}}
+}
+
+// record returns the record of pod, which is added if it does not exist yet.
+func (r *Report) record(key types.NamespacedName) *PodRecord {
+	record, ok := r.records[key]
+	if !ok {
+		record = &PodRecord{Pod: key}
+		r.records[key] = record
+		r.Pods = append(r.Pods, record)
+	}
+	return record
+}
+
+// addSample adds sample to the timeline, replacing the last sample if it is of the same time.
+func (r *Report) addSample(sample Sample) {
+	if n := len(r.Timeline); n > 0 && r.Timeline[n-1].Time == sample.Time {
+		r.Timeline[n-1] = sample
+		return
+	}
+	r.Timeline = append(r.Timeline, sample)
+}
+
+// WaitTimes returns the wait times of all started pods, in increasing order.
+func (r *Report) WaitTimes() []time.Duration {
+	var waits []time.Duration
+	for _, record := range r.Pods {
+		if record.Started {
+			waits = append(waits, record.Wait())
+		}
+	}
+	sort.Slice(waits, func(i, j int) bool { return waits[i] < waits[j] })
+	return waits
+}
+
+// Counts returns the count of pods which started and which finished.
+func (r *Report) Counts() (started, finished int) {
+	for _, record := range r.Pods {
+		if record.Started {
+			started++
+		}
+		if record.Finished {
+			finished++
+		}
+	}
+	return started, finished
+}
+
+// AverageUtilization returns the utilization per resource, averaged over the time of the simulation.
+// A simulation which ends at its start has the utilization of its last sample.
+func (r *Report) AverageUtilization() map[corev1.ResourceName]float64 {
+	average := map[corev1.ResourceName]float64{}
+	if len(r.Timeline) == 0 {
+		return average
+	}
+	if r.End == 0 {
+		for name, value := range r.Timeline[len(r.Timeline)-1].Utilization {
+			average[name] = value
+		}
+		return average
+	}
+	for i, sample := range r.Timeline {
+		end := r.End
+		if i+1 < len(r.Timeline) {
+			end = r.Timeline[i+1].Time
+		}
+		for name, value := range sample.Utilization {
+			average[name] += value * float64(end-sample.Time) / float64(r.End)
+		}
+	}
+	return average
+}
+
+// NodeHours returns the sum of the time every node was part of the cluster, in hours.
+func (r *Report) NodeHours() float64 {
+	var hours float64
+	for i, sample := range r.Timeline {
+		end := r.End
+		if i+1 < len(r.Timeline) {
+			end = r.Timeline[i+1].Time
+		}
+		hours += float64(sample.Nodes) * (end - sample.Time).Hours()
+	}
+	return hours
+}
+
+// WriteTimeline writes the timeline as csv, with a column per resource for its utilization.
+func (r *Report) WriteTimeline(w io.Writer) error {
+	var names []string
+	seen := map[corev1.ResourceName]bool{}
+	for _, sample := range r.Timeline {
+		for name := range sample.Utilization {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, string(name))
+			}
+		}
+	}
+	sort.Strings(names)
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(append([]string{"seconds", "nodes", "pending", "running"}, names...)); err != nil {
+		return err
+	}
+	for _, sample := range r.Timeline {
+		row := []string{
+			strconv.FormatFloat(sample.Time.Seconds(), 'f', -1, 64),
+			strconv.Itoa(sample.Nodes),
+			strconv.Itoa(sample.Pending),
+			strconv.Itoa(sample.Running),
+		}
+		for _, name := range names {
+			row = append(row, fmt.Sprintf("%.4f", sample.Utilization[corev1.ResourceName(name)]))
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WritePreemptions writes the preemptions as csv, with the victims of a preemption separated by spaces.
+func (r *Report) WritePreemptions(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"seconds", "preemptor", "node", "victims"}); err != nil {
+		return err
+	}
+	for _, preemption := range r.Preemptions {
+		victims := make([]string, 0, len(preemption.Victims))
+		for _, victim := range preemption.Victims {
+			victims = append(victims, victim.String())
+		}
+		row := []string{
+			strconv.FormatFloat(preemption.Time.Seconds(), 'f', -1, 64),
+			preemption.Preemptor.String(),
+			preemption.Node,
+			strings.Join(victims, " "),
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Percentile returns the nearest-rank p-th percentile of sorted durations, with p in [0, 100].
+func Percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
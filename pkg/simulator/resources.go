@@ -0,0 +1,126 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulator
+
+import (
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	defaultMilliCPURequest int64 = 100
+	defaultMemoryRequest   int64 = 200 * 1024 * 1024
+)
+
+// PodRequests returns the resources requested by pod, as the scheduler accounts them: the sum
+// of its containers, but at least the largest of its init containers, plus the pod overhead.
+func PodRequests(pod *corev1.Pod) corev1.ResourceList {
+	return podRequests(pod, containerRequests)
+}
+
+// nonZeroRequests returns the requests of pod like PodRequests, but with the default cpu and
+// memory requests for the containers which request none, as the scheduler scores nodes with them.
+func nonZeroRequests(pod *corev1.Pod) corev1.ResourceList {
+	return podRequests(pod, func(container *corev1.Container) corev1.ResourceList {
+		requests := containerRequests(container)
+		if _, ok := requests[corev1.ResourceCPU]; !ok {
+			requests[corev1.ResourceCPU] = *resource.NewMilliQuantity(defaultMilliCPURequest, resource.DecimalSI)
+		}
+		if _, ok := requests[corev1.ResourceMemory]; !ok {
+			requests[corev1.ResourceMemory] = *resource.NewQuantity(defaultMemoryRequest, resource.BinarySI)
+		}
+		return requests
+	})
+}
+
+func podRequests(pod *corev1.Pod, requestsOf func(*corev1.Container) corev1.ResourceList) corev1.ResourceList {
+	requests := corev1.ResourceList{}
+	for i := range pod.Spec.Containers {
+		addResources(requests, requestsOf(&pod.Spec.Containers[i]))
+	}
+	for i := range pod.Spec.InitContainers {
+		maxResources(requests, requestsOf(&pod.Spec.InitContainers[i]))
+	}
+	addResources(requests, pod.Spec.Overhead)
+	return requests
+}
+
+// containerRequests returns the requests of container, a resource with only
+// a limit requests the limit, as defaulted by the api server.
+func containerRequests(container *corev1.Container) corev1.ResourceList {
+	requests := container.Resources.Requests.DeepCopy()
+	if requests == nil {
+		requests = corev1.ResourceList{}
+	}
+	for name, limit := range container.Resources.Limits {
+		if _, ok := requests[name]; !ok {
+			requests[name] = limit.DeepCopy()
+		}
+	}
+	return requests
+}
+
+func addResources(dst, src corev1.ResourceList) {
+	for name, quantity := range src {
+		sum := dst[name]
+		sum.Add(quantity)
+		dst[name] = sum
+	}
+}
+
+func subtractResources(dst, src corev1.ResourceList) {
+	for name, quantity := range src {
+		diff := dst[name]
+		diff.Sub(quantity)
+		dst[name] = diff
+	}
+}
+
+func maxResources(dst, src corev1.ResourceList) {
+	for name, quantity := range src {
+		if current, ok := dst[name]; !ok || quantity.Cmp(current) > 0 {
+			dst[name] = quantity.DeepCopy()
+		}
+	}
+}
+
+// insufficientResources returns the resources of requests which exceed the free
+// resources of allocatable minus requested, in the order of their names.
+func insufficientResources(requests, requested, allocatable corev1.ResourceList) []corev1.ResourceName {
+	var insufficient []corev1.ResourceName
+	for name, quantity := range requests {
+		if quantity.IsZero() {
+			continue
+		}
+		free := allocatable[name].DeepCopy()
+		free.Sub(requested[name])
+		if quantity.Cmp(free) > 0 {
+			insufficient = append(insufficient, name)
+		}
+	}
+	sort.Slice(insufficient, func(i, j int) bool { return insufficient[i] < insufficient[j] })
+	return insufficient
+}
+
+// quantityValue returns the value of q in the unit the scheduler compares it with,
+// millicores for cpu and the plain value for all other resources.
+func quantityValue(name corev1.ResourceName, q resource.Quantity) int64 {
+	if name == corev1.ResourceCPU {
+		return q.MilliValue()
+	}
+	return q.Value()
+}
@@ -0,0 +1,231 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulator
+
+import (
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	corev1helpers "k8s.io/component-helpers/scheduling/corev1"
+)
+
+// WeightedScorePlugin is a score plugin with the weight of its scores in the total score of a node.
+type WeightedScorePlugin struct {
+	Plugin ScorePlugin
+	Weight int64
+}
+
+// Scheduler schedules pods one at a time through a filter and score pipeline like the one of
+// kube-scheduler: the nodes which pass all Filters are scored by all Scores, and the pod is
+// bound to the node with the highest total score. Ties go to the node which was added first,
+// so that simulations are reproducible.
+type Scheduler struct {
+	Filters []FilterPlugin
+	Scores  []WeightedScorePlugin
+	// Preemption lets a pod which fits on no node evict pods of a lower priority, like the
+	// DefaultPreemption plugin of kube-scheduler. The pod is bound right away, its victims are
+	// unbound and pending again, to be rescheduled by the next scheduling cycle.
+	Preemption bool
+}
+
+// DefaultSchedulerConfiguration is the configuration of NewDefaultScheduler, the defaults of the
+// v1 KubeSchedulerConfiguration.
+const DefaultSchedulerConfiguration = `
+apiVersion: kubescheduler.config.k8s.io/v1
+kind: KubeSchedulerConfiguration
+`
+
+// NewDefaultScheduler returns a scheduler with the default plugins and weights of kube-scheduler
+// which apply to the simulated cluster, those of DefaultSchedulerConfiguration.
+func NewDefaultScheduler() *Scheduler {
+	profiles, err := ParseSchedulerConfiguration([]byte(DefaultSchedulerConfiguration))
+	if err != nil {
+		panic(fmt.Sprintf("invalid default scheduler configuration: %v", err))
+	}
+	return profiles.Schedulers[corev1.DefaultSchedulerName]
+}
+
+// Placement is the outcome of scheduling a pod.
+type Placement struct {
+	Pod types.NamespacedName
+	// Node is the node the pod was bound to, empty if it is unschedulable.
+	Node string
+	// Reason is why the pod is unschedulable, or why it was evicted.
+	Reason string
+}
+
+// Result is the outcome of scheduling the pending pods of a cluster, in scheduling order.
+type Result struct {
+	Scheduled     []Placement
+	Unschedulable []Placement
+	// Evicted are the pods which were unbound from their Node to make room for other pods.
+	Evicted []Placement
+	// Preemptions are the pods which were scheduled by evicting pods of a lower priority.
+	Preemptions []Preemption
+}
+
+// Schedule returns the node which pod should be bound to, or a *FitError if it fits on no node.
+func (s *Scheduler) Schedule(c *Cluster, pod *corev1.Pod) (string, error) {
+	feasible, fitErr := s.FeasibleNodes(c, pod)
+	switch len(feasible) {
+	case 0:
+		return "", fitErr
+	case 1:
+		return feasible[0].Node.Name, nil
+	}
+
+	totals := make([]int64, len(feasible))
+	for _, score := range s.Scores {
+		scores := make([]int64, len(feasible))
+		for i, node := range feasible {
+			scores[i] = score.Plugin.Score(pod, node)
+		}
+		if normalizer, ok := score.Plugin.(ScoreNormalizer); ok {
+			normalizer.NormalizeScores(scores)
+		}
+		for i := range scores {
+			totals[i] += scores[i] * score.Weight
+		}
+	}
+	best := 0
+	for i := range totals {
+		if totals[i] > totals[best] {
+			best = i
+		}
+	}
+	return feasible[best].Node.Name, nil
+}
+
+// FeasibleNodes returns the nodes which pass all filters for pod, in the order they were added, and
+// the reasons of the other nodes. The error is the *FitError of pod if no node is feasible.
+func (s *Scheduler) FeasibleNodes(c *Cluster, pod *corev1.Pod) ([]*NodeInfo, *FitError) {
+	nodes := c.Nodes()
+	fitErr := &FitError{Pod: pod, NumAllNodes: len(nodes), Reasons: map[string]int{}}
+	for _, plugin := range s.plugins() {
+		if p, ok := plugin.(PreFilterPlugin); ok {
+			p.PreFilter(c, pod)
+		}
+	}
+	var feasible []*NodeInfo
+	for _, node := range nodes {
+		if reasons := s.filter(pod, node); len(reasons) > 0 {
+			for _, reason := range reasons {
+				fitErr.Reasons[reason]++
+			}
+			continue
+		}
+		feasible = append(feasible, node)
+	}
+	return feasible, fitErr
+}
+
+// Run schedules all pending pods of the cluster, the pods with a higher priority first and
+// otherwise in the order they were added. Every pod is bound to its node before the next
+// one is scheduled, unschedulable pods are kept pending with a PodScheduled condition.
+func (s *Scheduler) Run(c *Cluster) (*Result, error) {
+	result := &Result{}
+	for _, pod := range SortByPriority(c.PendingPods()) {
+		if err := s.scheduleOne(c, pod, result); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// scheduleOne schedules pod and binds it to its node, preempting pods of a lower priority if it
+// fits on no node, and adds the outcome to result.
+func (s *Scheduler) scheduleOne(c *Cluster, pod *corev1.Pod, result *Result) error {
+	key := PodKey(pod)
+	nodeName, err := s.Schedule(c, pod)
+	if _, ok := err.(*FitError); ok && s.Preemption {
+		preemption, preemptErr := s.preempt(c, pod, result)
+		if preemptErr != nil {
+			return preemptErr
+		}
+		if preemption != nil {
+			result.Preemptions = append(result.Preemptions, *preemption)
+			nodeName, err = preemption.Node, nil
+		}
+	}
+	if err != nil {
+		SetPodScheduled(pod, corev1.ConditionFalse, corev1.PodReasonUnschedulable, err.Error())
+		result.Unschedulable = append(result.Unschedulable, Placement{Pod: key, Reason: err.Error()})
+		return nil
+	}
+	if err := c.Bind(key, nodeName); err != nil {
+		return err
+	}
+	SetPodScheduled(pod, corev1.ConditionTrue, "", "")
+	result.Scheduled = append(result.Scheduled, Placement{Pod: key, Node: nodeName})
+	return nil
+}
+
+// SortByPriority sorts pods by their priority, the highest first, and keeps the order of pods with the same priority.
+func SortByPriority(pods []*corev1.Pod) []*corev1.Pod {
+	sort.SliceStable(pods, func(i, j int) bool {
+		return corev1helpers.PodPriority(pods[i]) > corev1helpers.PodPriority(pods[j])
+	})
+	return pods
+}
+
+// filter runs the filters on node until one of them rejects pod.
+func (s *Scheduler) filter(pod *corev1.Pod, node *NodeInfo) []string {
+	for _, plugin := range s.Filters {
+		if reasons := plugin.Filter(pod, node); len(reasons) > 0 {
+			return reasons
+		}
+	}
+	return nil
+}
+
+// plugins returns the distinct plugins of the scheduler, a plugin may both filter and score.
+func (s *Scheduler) plugins() []Plugin {
+	var plugins []Plugin
+	seen := map[Plugin]bool{}
+	for _, plugin := range s.Filters {
+		if !seen[plugin] {
+			seen[plugin] = true
+			plugins = append(plugins, plugin)
+		}
+	}
+	for _, score := range s.Scores {
+		if !seen[score.Plugin] {
+			seen[score.Plugin] = true
+			plugins = append(plugins, score.Plugin)
+		}
+	}
+	return plugins
+}
+
+// SetPodScheduled sets the PodScheduled condition of pod, as a scheduler reports a scheduling attempt.
+func SetPodScheduled(pod *corev1.Pod, status corev1.ConditionStatus, reason, message string) {
+	condition := corev1.PodCondition{
+		Type:               corev1.PodScheduled,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		LastTransitionTime: metav1.Now(),
+	}
+	for i := range pod.Status.Conditions {
+		if pod.Status.Conditions[i].Type == corev1.PodScheduled {
+			pod.Status.Conditions[i] = condition
+			return
+		}
+	}
+	pod.Status.Conditions = append(pod.Status.Conditions, condition)
+}
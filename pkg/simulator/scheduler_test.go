@@ -0,0 +1,151 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulator
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/D0m021ng/scheduler-simulator/pkg/simctl/generate"
+)
+
+func newTestCluster(t *testing.T, zones ...string) *Cluster {
+	c := NewCluster()
+	capacity := generate.BuildResources(map[string]string{"cpu": "4", "memory": "8Gi", "pods": "110"})
+	for i, zone := range zones {
+		node := generate.BuildFakeNode(string(rune('a'+i)), false, capacity, capacity, nil,
+			map[string]string{corev1.LabelTopologyZone: zone})
+		if err := c.AddNode(node); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return c
+}
+
+func newTestPod(name, cpu string, labels map[string]string) *corev1.Pod {
+	return generate.BuildFakePod(name, "ns", "", "", labels, corev1.PodPending,
+		generate.BuildResources(map[string]string{"cpu": cpu}))
+}
+
+func TestScheduleFilters(t *testing.T) {
+	c := newTestCluster(t, "z1", "z2")
+	c.Node("a").Node.Spec.Taints = []corev1.Taint{{Key: "dedicated", Value: "gpu", Effect: corev1.TaintEffectNoSchedule}}
+	c.Node("b").Node.Spec.Unschedulable = true
+
+	pod := newTestPod("p", "1", nil)
+	_, err := NewDefaultScheduler().Schedule(c, pod)
+	want := "0/2 nodes are available: 1 node(s) had taint {dedicated: gpu}, that the pod didn't tolerate, 1 node(s) were unschedulable."
+	if err == nil || err.Error() != want {
+		t.Fatalf("got error %v, want %s", err, want)
+	}
+
+	pod.Spec.Tolerations = []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpExists}}
+	if node, err := NewDefaultScheduler().Schedule(c, pod); err != nil || node != "a" {
+		t.Fatalf("tolerating pod got node %q, error %v", node, err)
+	}
+	pod.Spec.NodeSelector = map[string]string{corev1.LabelTopologyZone: "z2"}
+	if _, err := NewDefaultScheduler().Schedule(c, pod); err == nil {
+		t.Fatalf("pod was scheduled to a node which does not match its node selector")
+	}
+}
+
+func TestRunSpreadsAndOrdersByPriority(t *testing.T) {
+	c := newTestCluster(t, "z1", "z1", "z2")
+	app := map[string]string{"app": "web"}
+	spread := []corev1.TopologySpreadConstraint{{
+		MaxSkew:           1,
+		TopologyKey:       corev1.LabelTopologyZone,
+		WhenUnsatisfiable: corev1.DoNotSchedule,
+		LabelSelector:     &metav1.LabelSelector{MatchLabels: app},
+	}}
+	for _, name := range []string{"w1", "w2", "w3", "w4"} {
+		pod := newTestPod(name, "500m", app)
+		pod.Spec.TopologySpreadConstraints = spread
+		if err := c.AddPod(pod); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the large pod is added last, but scheduled first for its priority
+	priority := int32(1000)
+	large := newTestPod("large", "4", nil)
+	large.Spec.Priority = &priority
+	if err := c.AddPod(large); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := NewDefaultScheduler().Run(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Unschedulable) != 0 || result.Scheduled[0].Pod.Name != "large" {
+		t.Fatalf("got result %+v", result)
+	}
+	zones := map[string]int{}
+	for _, placement := range result.Scheduled[1:] {
+		zones[c.Node(placement.Node).Node.Labels[corev1.LabelTopologyZone]]++
+	}
+	if zones["z1"] != 2 || zones["z2"] != 2 {
+		t.Fatalf("pods are not spread over zones: %v", zones)
+	}
+}
+
+func TestInterPodAntiAffinity(t *testing.T) {
+	c := newTestCluster(t, "z1", "z2")
+	app := map[string]string{"app": "db"}
+	for _, name := range []string{"db1", "db2", "db3"} {
+		pod := newTestPod(name, "100m", app)
+		pod.Spec.Affinity = &corev1.Affinity{PodAntiAffinity: &corev1.PodAntiAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{{
+				LabelSelector: &metav1.LabelSelector{MatchLabels: app},
+				TopologyKey:   corev1.LabelTopologyZone,
+			}},
+		}}
+		if err := c.AddPod(pod); err != nil {
+			t.Fatal(err)
+		}
+	}
+	result, err := NewDefaultScheduler().Run(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Scheduled) != 2 || len(result.Unschedulable) != 1 {
+		t.Fatalf("got result %+v", result)
+	}
+	if result.Scheduled[0].Node == result.Scheduled[1].Node {
+		t.Fatalf("pods with anti-affinity share node %s", result.Scheduled[0].Node)
+	}
+}
+
+func TestResourceScoringStrategies(t *testing.T) {
+	c := newTestCluster(t, "z1", "z1")
+	busy := newTestPod("busy", "2", nil)
+	busy.Spec.NodeName = "b"
+	if err := c.AddPod(busy); err != nil {
+		t.Fatal(err)
+	}
+	for strategy, want := range map[ScoringStrategyType]string{LeastAllocated: "a", MostAllocated: "b"} {
+		s := &Scheduler{
+			Filters: []FilterPlugin{NewNodeResourcesFit(ScoringStrategy{})},
+			Scores: []WeightedScorePlugin{
+				{Plugin: NewNodeResourcesFit(ScoringStrategy{Type: strategy}), Weight: 1},
+			},
+		}
+		if node, err := s.Schedule(c, newTestPod("p", "1", nil)); err != nil || node != want {
+			t.Errorf("%s got node %q, error %v, want %s", strategy, node, err, want)
+		}
+	}
+}
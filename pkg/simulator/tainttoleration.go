@@ -0,0 +1,91 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulator
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// NodeUnschedulable filters out the cordoned nodes, unless the pod tolerates the unschedulable taint.
+type NodeUnschedulable struct{}
+
+func (p *NodeUnschedulable) Name() string {
+	return "NodeUnschedulable"
+}
+
+func (p *NodeUnschedulable) Filter(pod *corev1.Pod, node *NodeInfo) []string {
+	if !node.Node.Spec.Unschedulable {
+		return nil
+	}
+	taint := &corev1.Taint{Key: corev1.TaintNodeUnschedulable, Effect: corev1.TaintEffectNoSchedule}
+	if toleratesTaint(pod.Spec.Tolerations, taint) {
+		return nil
+	}
+	return []string{"node(s) were unschedulable"}
+}
+
+// TaintToleration filters out the nodes with NoSchedule and NoExecute taints which the pod
+// does not tolerate, and prefers the nodes with fewer untolerated PreferNoSchedule taints.
+type TaintToleration struct{}
+
+func (p *TaintToleration) Name() string {
+	return "TaintToleration"
+}
+
+func (p *TaintToleration) Filter(pod *corev1.Pod, node *NodeInfo) []string {
+	for i := range node.Node.Spec.Taints {
+		taint := &node.Node.Spec.Taints[i]
+		if taint.Effect != corev1.TaintEffectNoSchedule && taint.Effect != corev1.TaintEffectNoExecute {
+			continue
+		}
+		if !toleratesTaint(pod.Spec.Tolerations, taint) {
+			return []string{fmt.Sprintf("node(s) had taint {%s: %s}, that the pod didn't tolerate", taint.Key, taint.Value)}
+		}
+	}
+	return nil
+}
+
+// Score returns the count of PreferNoSchedule taints of node which pod does not tolerate.
+func (p *TaintToleration) Score(pod *corev1.Pod, node *NodeInfo) int64 {
+	var tolerations []corev1.Toleration
+	for _, toleration := range pod.Spec.Tolerations {
+		if toleration.Effect == "" || toleration.Effect == corev1.TaintEffectPreferNoSchedule {
+			tolerations = append(tolerations, toleration)
+		}
+	}
+	var count int64
+	for i := range node.Node.Spec.Taints {
+		taint := &node.Node.Spec.Taints[i]
+		if taint.Effect == corev1.TaintEffectPreferNoSchedule && !toleratesTaint(tolerations, taint) {
+			count++
+		}
+	}
+	return count
+}
+
+func (p *TaintToleration) NormalizeScores(scores []int64) {
+	defaultNormalizeScore(scores, true)
+}
+
+func toleratesTaint(tolerations []corev1.Toleration, taint *corev1.Taint) bool {
+	for i := range tolerations {
+		if tolerations[i].ToleratesTaint(taint) {
+			return true
+		}
+	}
+	return false
+}
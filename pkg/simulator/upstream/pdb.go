@@ -0,0 +1,50 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package upstream
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/D0m021ng/scheduler-simulator/pkg/simulator"
+)
+
+// syncDisruptionBudgets mirrors the PodDisruptionBudgets of c into the clientset, with the status
+// which the disruption controller would compute, so that preemption respects them.
+func (s *Scheduler) syncDisruptionBudgets(c *simulator.Cluster) error {
+	pdbs, err := simulator.DisruptionBudgets(c)
+	if err != nil {
+		return err
+	}
+	for _, pdb := range pdbs {
+		key := types.NamespacedName{Namespace: pdb.Namespace, Name: pdb.Name}
+		allowed, ok := s.pdbs[key]
+		client := s.client.PolicyV1beta1().PodDisruptionBudgets(pdb.Namespace)
+		var err error
+		switch {
+		case !ok:
+			_, err = client.Create(context.TODO(), pdb, metav1.CreateOptions{})
+		case allowed != pdb.Status.DisruptionsAllowed:
+			_, err = client.UpdateStatus(context.TODO(), pdb, metav1.UpdateOptions{})
+		}
+		if err != nil {
+			return err
+		}
+		s.pdbs[key] = pdb.Status.DisruptionsAllowed
+	}
+	return nil
+}
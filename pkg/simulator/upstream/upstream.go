@@ -0,0 +1,502 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package upstream runs the kube-scheduler of k8s.io/kubernetes in-process as a simulated
+// scheduler. The scheduler watches a fake clientset which mirrors the simulated cluster before
+// every scheduling cycle, and its bindings, failures and preemptions are played back into the cluster.
+package upstream
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/events"
+	"k8s.io/kubernetes/pkg/scheduler"
+	schedulerconfig "k8s.io/kubernetes/pkg/scheduler/apis/config"
+	configscheme "k8s.io/kubernetes/pkg/scheduler/apis/config/scheme"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"github.com/D0m021ng/scheduler-simulator/pkg/simulator"
+)
+
+// defaultConfiguration is the configuration of kube-scheduler if none is given, with the defaults of its version.
+const defaultConfiguration = `
+apiVersion: kubescheduler.config.k8s.io/v1beta1
+kind: KubeSchedulerConfiguration
+`
+
+var (
+	podsResource = corev1.SchemeGroupVersion.WithResource("pods")
+	podsKind     = corev1.SchemeGroupVersion.WithKind("Pod")
+)
+
+// watchBatch is the count of pods which are created or deleted before the informers must catch up,
+// below the buffer of the watches of the fake clientset.
+const watchBatch = 50
+
+// cycleTimeout is how long a scheduling cycle waits for kube-scheduler in real time.
+const cycleTimeout = time.Minute
+
+// outcome is how kube-scheduler handled a pod in a scheduling cycle.
+type outcome struct {
+	node   string
+	reason string
+}
+
+// Scheduler is the kube-scheduler of k8s.io/kubernetes, it implements simulator.Algorithm.
+// Its scheduling decisions are those of upstream, including ties broken at random and
+// preemption by the DefaultPreemption plugin.
+type Scheduler struct {
+	client    *fake.Clientset
+	informers informers.SharedInformerFactory
+	scheduler *scheduler.Scheduler
+	profiles  map[string]bool
+	cancel    context.CancelFunc
+
+	// nodes and pods are the nodes and the nodes of the pods in the clientset,
+	// pdbs the disruptions allowed by the PodDisruptionBudgets.
+	nodes map[string]bool
+	pods  map[types.NamespacedName]string
+	pdbs  map[types.NamespacedName]int32
+	// changes counts the pods created and deleted in the clientset.
+	changes int
+
+	mu     sync.Mutex
+	notify chan struct{}
+	// deleting are the pods which are deleted to mirror the cluster, not by kube-scheduler.
+	deleting map[types.NamespacedName]bool
+	outcomes map[types.NamespacedName]outcome
+	// preempted are the pods which kube-scheduler deleted to make room for others.
+	preempted []types.NamespacedName
+}
+
+// LoadConfiguration reads a KubeSchedulerConfiguration of the version of the upstream scheduler
+// from filename, or returns the default configuration if it is empty.
+func LoadConfiguration(filename string) (*schedulerconfig.KubeSchedulerConfiguration, error) {
+	data := []byte(defaultConfiguration)
+	if filename != "" {
+		var err error
+		if data, err = os.ReadFile(filename); err != nil {
+			return nil, err
+		}
+	}
+	obj, _, err := configscheme.Codecs.UniversalDecoder().Decode(data, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid KubeSchedulerConfiguration: %v", err)
+	}
+	conf, ok := obj.(*schedulerconfig.KubeSchedulerConfiguration)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a KubeSchedulerConfiguration", obj.GetObjectKind().GroupVersionKind())
+	}
+	return conf, nil
+}
+
+// New starts kube-scheduler with the profiles of conf on an empty fake clientset.
+func New(conf *schedulerconfig.KubeSchedulerConfiguration) (*Scheduler, error) {
+	s := &Scheduler{
+		client:   fake.NewSimpleClientset(),
+		profiles: map[string]bool{},
+		nodes:    map[string]bool{},
+		pods:     map[types.NamespacedName]string{},
+		pdbs:     map[types.NamespacedName]int32{},
+		notify:   make(chan struct{}, 1),
+		deleting: map[types.NamespacedName]bool{},
+		outcomes: map[types.NamespacedName]outcome{},
+	}
+	s.client.PrependReactor("create", "pods", s.bindReactor)
+	s.client.PrependReactor("delete", "pods", s.deleteReactor)
+	s.informers = informers.NewSharedInformerFactory(s.client, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sched, err := scheduler.New(s.client, s.informers,
+		func(string) events.EventRecorder { return &events.FakeRecorder{} },
+		ctx.Done(),
+		scheduler.WithProfiles(conf.Profiles...),
+		scheduler.WithPercentageOfNodesToScore(conf.PercentageOfNodesToScore),
+		scheduler.WithExtenders(conf.Extenders...),
+		// pods are retried at the next scheduling cycle of the simulated clock, not after a backoff in real time
+		scheduler.WithPodInitialBackoffSeconds(0),
+		scheduler.WithPodMaxBackoffSeconds(0))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	for name := range sched.Profiles {
+		s.profiles[name] = true
+	}
+	handleError := sched.Error
+	sched.Error = func(podInfo *framework.QueuedPodInfo, err error) {
+		if s.current(podInfo.Pod) {
+			s.record(simulator.PodKey(podInfo.Pod), outcome{reason: err.Error()})
+		}
+		handleError(podInfo, err)
+	}
+	s.scheduler, s.cancel = sched, cancel
+
+	s.informers.Start(ctx.Done())
+	s.informers.WaitForCacheSync(ctx.Done())
+	go sched.Run(ctx)
+	return s, nil
+}
+
+// Close stops kube-scheduler.
+func (s *Scheduler) Close() {
+	s.cancel()
+}
+
+// Run mirrors c into the clientset, waits until kube-scheduler handled every pending pod and
+// applies its decisions to c. Pods which kube-scheduler preempted are evicted.
+func (s *Scheduler) Run(c *simulator.Cluster) (*simulator.Result, error) {
+	result := &simulator.Result{}
+	s.mu.Lock()
+	for key, o := range s.outcomes {
+		// bindings are kept until they are applied, even those of pods retried between cycles
+		if o.node == "" {
+			delete(s.outcomes, key)
+		}
+	}
+	s.mu.Unlock()
+	if err := s.evictPreempted(c, result); err != nil {
+		return nil, err
+	}
+	pending, err := s.sync(c, result)
+	if err != nil {
+		return nil, err
+	}
+	s.scheduler.SchedulingQueue.MoveAllToActiveOrBackoffQueue("SimulatedSchedulingCycle")
+
+	if err := s.wait(pending); err != nil {
+		return nil, err
+	}
+	if err := s.evictPreempted(c, result); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	outcomes := s.outcomes
+	s.outcomes = map[types.NamespacedName]outcome{}
+	s.mu.Unlock()
+	for _, pod := range pending {
+		key := simulator.PodKey(pod)
+		o := outcomes[key]
+		if o.node == "" {
+			simulator.SetPodScheduled(pod, corev1.ConditionFalse, corev1.PodReasonUnschedulable, o.reason)
+			result.Unschedulable = append(result.Unschedulable, simulator.Placement{Pod: key, Reason: o.reason})
+			continue
+		}
+		if err := c.Bind(key, o.node); err != nil {
+			return nil, err
+		}
+		s.pods[key] = o.node
+		simulator.SetPodScheduled(pod, corev1.ConditionTrue, "", "")
+		result.Scheduled = append(result.Scheduled, simulator.Placement{Pod: key, Node: o.node})
+	}
+	return result, nil
+}
+
+// evictPreempted unbinds the pods which kube-scheduler deleted to make room for others.
+func (s *Scheduler) evictPreempted(c *simulator.Cluster, result *simulator.Result) error {
+	s.mu.Lock()
+	preempted := s.preempted
+	s.preempted = nil
+	s.mu.Unlock()
+	for _, key := range preempted {
+		delete(s.pods, key)
+		pod := c.Pod(key)
+		if pod == nil || pod.Spec.NodeName == "" {
+			continue
+		}
+		node := pod.Spec.NodeName
+		if err := c.Unbind(key); err != nil {
+			return err
+		}
+		result.Evicted = append(result.Evicted, simulator.Placement{Pod: key, Node: node, Reason: "preempted by kube-scheduler"})
+	}
+	return nil
+}
+
+// sync updates the clientset to the nodes and pods of c, and returns the pending pods which
+// kube-scheduler is responsible for. The pods of other schedulers are added to result as unschedulable.
+func (s *Scheduler) sync(c *simulator.Cluster, result *simulator.Result) ([]*corev1.Pod, error) {
+	ctx := context.TODO()
+	nodes := map[string]bool{}
+	for _, node := range c.Nodes() {
+		nodes[node.Node.Name] = true
+		if !s.nodes[node.Node.Name] {
+			if _, err := s.client.CoreV1().Nodes().Create(ctx, node.Node.DeepCopy(), metav1.CreateOptions{}); err != nil {
+				return nil, err
+			}
+			s.nodes[node.Node.Name] = true
+		}
+	}
+
+	pods := map[types.NamespacedName]*corev1.Pod{}
+	var pending []*corev1.Pod
+	for _, pod := range c.Pods() {
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		key := simulator.PodKey(pod)
+		pods[key] = pod
+		node, ok := s.pods[key]
+		if ok && node != pod.Spec.NodeName {
+			// the pod was unbound, which kube-scheduler only sees as a new pod
+			if err := s.deletePod(key); err != nil {
+				return nil, err
+			}
+			ok = false
+		}
+		if pod.Spec.NodeName == "" {
+			if s.profiles[schedulerName(pod)] {
+				pending = append(pending, pod)
+			} else {
+				result.Unschedulable = append(result.Unschedulable, simulator.Placement{Pod: key,
+					Reason: fmt.Sprintf("no profile of scheduler %s", schedulerName(pod))})
+			}
+			continue
+		}
+		if !ok {
+			if err := s.createPod(pod); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for key := range s.pods {
+		if pods[key] == nil {
+			if err := s.deletePod(key); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for name := range s.nodes {
+		if !nodes[name] {
+			if err := s.client.CoreV1().Nodes().Delete(ctx, name, metav1.DeleteOptions{}); err != nil {
+				return nil, err
+			}
+			delete(s.nodes, name)
+		}
+	}
+	if err := s.syncDisruptionBudgets(c); err != nil {
+		return nil, err
+	}
+
+	// the cache of kube-scheduler must know all bound pods before pending pods are scheduled
+	if err := s.waitForCache(); err != nil {
+		return nil, err
+	}
+	for _, pod := range pending {
+		if _, ok := s.pods[simulator.PodKey(pod)]; !ok {
+			if err := s.createPod(pod); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return pending, nil
+}
+
+// throttle waits until the informers received all pods every watchBatch changes, the
+// watches of the fake clientset only buffer a limited count of events.
+func (s *Scheduler) throttle() error {
+	s.changes++
+	if s.changes%watchBatch != 0 {
+		return nil
+	}
+	deadline := time.Now().Add(cycleTimeout)
+	for {
+		obj, err := s.client.Tracker().List(podsResource, podsKind, metav1.NamespaceAll)
+		if err != nil {
+			return err
+		}
+		cached, err := s.informers.Core().V1().Pods().Lister().List(labels.Everything())
+		if err != nil {
+			return err
+		}
+		if len(cached) == len(obj.(*corev1.PodList).Items) {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for the informers of kube-scheduler")
+		}
+		time.Sleep(100 * time.Microsecond)
+	}
+}
+
+func (s *Scheduler) createPod(pod *corev1.Pod) error {
+	if err := s.throttle(); err != nil {
+		return err
+	}
+	copied := pod.DeepCopy()
+	// the cache of kube-scheduler keys pods by uid, a pod which is created again is a new pod
+	copied.ResourceVersion, copied.UID = "", types.UID(uuid.NewString())
+	if _, err := s.client.CoreV1().Pods(pod.Namespace).Create(context.TODO(), copied, metav1.CreateOptions{}); err != nil {
+		return err
+	}
+	s.pods[simulator.PodKey(pod)] = pod.Spec.NodeName
+	return nil
+}
+
+func (s *Scheduler) deletePod(key types.NamespacedName) error {
+	if err := s.throttle(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.deleting[key] = true
+	s.mu.Unlock()
+	err := s.client.CoreV1().Pods(key.Namespace).Delete(context.TODO(), key.Name, metav1.DeleteOptions{})
+	s.mu.Lock()
+	delete(s.deleting, key)
+	s.mu.Unlock()
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	delete(s.pods, key)
+	return nil
+}
+
+// waitForCache waits until the cache of kube-scheduler has the nodes and bound pods of the clientset.
+// The clientset is listed again on every poll, kube-scheduler may still bind pods retried between cycles.
+func (s *Scheduler) waitForCache() error {
+	deadline := time.Now().Add(cycleTimeout)
+	for {
+		want := map[string]int{}
+		for name := range s.nodes {
+			want[name] = 0
+		}
+		obj, err := s.client.Tracker().List(podsResource, podsKind, metav1.NamespaceAll)
+		if err != nil {
+			return err
+		}
+		for _, pod := range obj.(*corev1.PodList).Items {
+			if pod.Spec.NodeName != "" {
+				want[pod.Spec.NodeName]++
+			}
+		}
+		dump := s.scheduler.SchedulerCache.Dump()
+		synced := true
+		for name, count := range want {
+			info, ok := dump.Nodes[name]
+			if !ok || len(info.Pods) != count || (s.nodes[name] != (info.Node() != nil)) {
+				synced = false
+				break
+			}
+		}
+		for name, info := range dump.Nodes {
+			if _, ok := want[name]; !ok && (info.Node() != nil || len(info.Pods) > 0) {
+				synced = false
+			}
+		}
+		if synced {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for the cache of kube-scheduler")
+		}
+		time.Sleep(100 * time.Microsecond)
+	}
+}
+
+// wait waits until kube-scheduler bound or failed to schedule every pending pod.
+func (s *Scheduler) wait(pending []*corev1.Pod) error {
+	timeout := time.After(cycleTimeout)
+	for {
+		s.mu.Lock()
+		done := 0
+		for _, pod := range pending {
+			if _, ok := s.outcomes[simulator.PodKey(pod)]; ok {
+				done++
+			}
+		}
+		s.mu.Unlock()
+		if done == len(pending) {
+			return nil
+		}
+		select {
+		case <-s.notify:
+		case <-timeout:
+			return fmt.Errorf("timed out waiting for kube-scheduler to schedule %d pod(s)", len(pending)-done)
+		}
+	}
+}
+
+func (s *Scheduler) record(key types.NamespacedName, o outcome) {
+	s.mu.Lock()
+	s.outcomes[key] = o
+	s.mu.Unlock()
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// bindReactor binds pods in the clientset, which kube-scheduler does through the binding subresource.
+func (s *Scheduler) bindReactor(action k8stesting.Action) (bool, runtime.Object, error) {
+	create, ok := action.(k8stesting.CreateAction)
+	if !ok || action.GetSubresource() != "binding" {
+		return false, nil, nil
+	}
+	binding := create.GetObject().(*corev1.Binding)
+	tracker := s.client.Tracker()
+	obj, err := tracker.Get(podsResource, binding.Namespace, binding.Name)
+	if err != nil {
+		return true, nil, err
+	}
+	pod := obj.(*corev1.Pod).DeepCopy()
+	if binding.UID != "" && binding.UID != pod.UID {
+		// the pod was deleted and created again while kube-scheduler was binding the old one
+		return true, nil, apierrors.NewConflict(podsResource.GroupResource(), binding.Name,
+			fmt.Errorf("binding of pod %s is stale", binding.UID))
+	}
+	pod.Spec.NodeName = binding.Target.Name
+	if err := tracker.Update(podsResource, pod, pod.Namespace); err != nil {
+		return true, nil, err
+	}
+	s.record(types.NamespacedName{Namespace: binding.Namespace, Name: binding.Name}, outcome{node: binding.Target.Name})
+	return true, binding, nil
+}
+
+// current reports whether pod is the one in the clientset rather than an earlier pod of the same name.
+func (s *Scheduler) current(pod *corev1.Pod) bool {
+	obj, err := s.client.Tracker().Get(podsResource, pod.Namespace, pod.Name)
+	return err == nil && obj.(*corev1.Pod).UID == pod.UID
+}
+
+// deleteReactor records the pods which kube-scheduler deletes, the victims of preemption.
+func (s *Scheduler) deleteReactor(action k8stesting.Action) (bool, runtime.Object, error) {
+	key := types.NamespacedName{Namespace: action.GetNamespace(), Name: action.(k8stesting.DeleteAction).GetName()}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.deleting[key] {
+		s.preempted = append(s.preempted, key)
+	}
+	return false, nil, nil
+}
+
+func schedulerName(pod *corev1.Pod) string {
+	if pod.Spec.SchedulerName == "" {
+		return corev1.DefaultSchedulerName
+	}
+	return pod.Spec.SchedulerName
+}
@@ -0,0 +1,84 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package upstream
+
+import (
+	"fmt"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/D0m021ng/scheduler-simulator/pkg/simctl/generate"
+	"github.com/D0m021ng/scheduler-simulator/pkg/simulator"
+)
+
+func newTestScheduler(t *testing.T) *Scheduler {
+	conf, err := LoadConfiguration("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Close)
+	return s
+}
+
+func addTestPod(t *testing.T, c *simulator.Cluster, name, cpu string, priority int32) {
+	pod := generate.BuildFakePod(name, "ns", "", "", nil, corev1.PodPending,
+		generate.BuildResources(map[string]string{"cpu": cpu}))
+	pod.Spec.Priority = &priority
+	if err := c.AddPod(pod); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpstreamSchedulesAndPreempts(t *testing.T) {
+	c := simulator.NewCluster()
+	capacity := generate.BuildResources(map[string]string{"cpu": "4", "memory": "8Gi", "pods": "110"})
+	for i := 0; i < 2; i++ {
+		if err := c.AddNode(generate.BuildFakeNode(fmt.Sprintf("n%d", i), false, capacity, capacity, nil, nil)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		addTestPod(t, c, fmt.Sprintf("low-%d", i), "3", 0)
+	}
+	s := newTestScheduler(t)
+
+	result, err := s.Run(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "0/2 nodes are available: 2 Insufficient cpu."
+	if len(result.Scheduled) != 2 || len(result.Unschedulable) != 1 || result.Unschedulable[0].Reason != want {
+		t.Fatalf("got %+v, want 2 pods scheduled and 1 unschedulable with %q", result, want)
+	}
+
+	addTestPod(t, c, "high", "3", 1000)
+	if result, err = s.Run(c); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Evicted) != 1 {
+		t.Fatalf("got evicted %+v, want 1 pod preempted by the high priority pod", result.Evicted)
+	}
+	if result, err = s.Run(c); err != nil {
+		t.Fatal(err)
+	}
+	if pod := c.Pod(simulator.PodKey(generate.BuildFakePod("high", "ns", "", "", nil, "", nil))); pod.Spec.NodeName == "" {
+		t.Fatalf("the high priority pod was not scheduled after preemption: %+v", result)
+	}
+}
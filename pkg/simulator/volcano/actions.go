@@ -0,0 +1,261 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package volcano
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/D0m021ng/scheduler-simulator/pkg/simulator"
+)
+
+// actions are the actions of volcano by their names in volcano-scheduler.conf.
+var actions = map[string]func(ssn *session) error{
+	"enqueue":  enqueue,
+	"allocate": allocate,
+	"backfill": backfill,
+	"reclaim":  reclaim,
+	"preempt":  preempt,
+}
+
+func (ssn *session) sortedQueues() []*queueInfo {
+	queues := append([]*queueInfo(nil), ssn.queues...)
+	sort.SliceStable(queues, func(i, j int) bool { return ssn.queueLess(queues[i], queues[j]) })
+	return queues
+}
+
+func (ssn *session) sortedJobs(queue *queueInfo) []*jobInfo {
+	jobs := append([]*jobInfo(nil), queue.jobs...)
+	sort.SliceStable(jobs, func(i, j int) bool { return ssn.jobLess(jobs[i], jobs[j]) })
+	return jobs
+}
+
+// enqueue admits the pending jobs while the plugins agree that their minResources can be
+// allocated, the tasks of jobs which were not admitted are not allocated.
+func enqueue(ssn *session) error {
+	for _, queue := range ssn.sortedQueues() {
+		for _, job := range ssn.sortedJobs(queue) {
+			if job.inqueue || len(job.pending) == 0 {
+				continue
+			}
+			if job.minResources.IsEmpty() || ssn.enqueueable(job) {
+				job.inqueue = true
+				queue.inqueue.Add(job.minResources)
+				continue
+			}
+			job.reason = fmt.Sprintf("queue %s has not enough resources for the minResources of job %s", queue.name, job.name)
+		}
+	}
+	return nil
+}
+
+// allocate allocates the pending tasks of the admitted jobs, always to a job of the queue
+// which is the most below its share. The tasks of a job are only bound once the job is ready,
+// and a ready job gives way to the other jobs before the rest of its tasks are allocated.
+func allocate(ssn *session) error {
+	done := map[*jobInfo]bool{}
+	candidate := func(job *jobInfo) bool {
+		if done[job] || !job.inqueue || len(job.pending) == 0 {
+			return false
+		}
+		if reason := ssn.jobValid(job); reason != "" {
+			job.reason, done[job] = reason, true
+			return false
+		}
+		return true
+	}
+	for {
+		var queue *queueInfo
+		var job *jobInfo
+		for _, q := range ssn.queues {
+			var best *jobInfo
+			for _, j := range q.jobs {
+				if candidate(j) && (best == nil || ssn.jobLess(j, best)) {
+					best = j
+				}
+			}
+			if best == nil {
+				continue
+			}
+			if ssn.overused(q) {
+				for _, j := range q.jobs {
+					if candidate(j) {
+						j.reason, done[j] = fmt.Sprintf("queue %s is overused", q.name), true
+					}
+				}
+				continue
+			}
+			if queue == nil || ssn.queueLess(q, queue) {
+				queue, job = q, best
+			}
+		}
+		if job == nil {
+			return nil
+		}
+
+		job.reason = ""
+		stmt := ssn.statement()
+		for _, task := range ssn.sortedTasks(job) {
+			if !ssn.allocatable(queue, task) {
+				job.reason = fmt.Sprintf("queue %s has not enough capability for task %s", queue.name, task.key)
+				break
+			}
+			node, err := ssn.scheduler.Schedule(ssn.cluster, task.pod)
+			if err != nil {
+				job.reason = err.Error()
+				break
+			}
+			if err := stmt.allocate(task, node); err != nil {
+				return err
+			}
+			if ssn.jobReady(job) && len(job.pending) > 0 {
+				break
+			}
+		}
+		if !ssn.jobReady(job) {
+			if err := stmt.discard(); err != nil {
+				return err
+			}
+			if job.minMember > 1 {
+				job.reason = fmt.Sprintf("%d/%d tasks in gang unschedulable: %s",
+					len(job.pending), len(job.pending)+len(job.running), job.reason)
+			}
+			done[job] = true
+			continue
+		}
+		// a ready job is allocated again after the others until a task does not fit
+		done[job] = len(stmt.operations) == 0 || job.reason != ""
+		stmt.commit("")
+	}
+}
+
+// backfill allocates the pending tasks of the admitted jobs which request no resources.
+func backfill(ssn *session) error {
+	for _, job := range ssn.jobs {
+		if !job.inqueue || ssn.jobValid(job) != "" {
+			continue
+		}
+		for _, task := range ssn.sortedTasks(job) {
+			if !task.resreq.IsEmpty() {
+				continue
+			}
+			node, err := ssn.scheduler.Schedule(ssn.cluster, task.pod)
+			if err != nil {
+				job.reason = err.Error()
+				continue
+			}
+			stmt := ssn.statement()
+			if err := stmt.allocate(task, node); err != nil {
+				return err
+			}
+			stmt.commit("")
+		}
+	}
+	return nil
+}
+
+// reclaim evicts tasks of other reclaimable queues which the plugins allow to reclaim, for the
+// pending tasks of queues which are not overused.
+func reclaim(ssn *session) error {
+	return ssn.evictFor("reclaimed", ssn.reclaimableFns, func(job *jobInfo, victim *taskInfo) bool {
+		return victim.job.queue != job.queue && victim.job.queue.reclaimable
+	})
+}
+
+// preempt evicts tasks of other jobs of the same queue which the plugins allow to preempt.
+func preempt(ssn *session) error {
+	return ssn.evictFor("preempted", ssn.preemptableFns, func(job *jobInfo, victim *taskInfo) bool {
+		return victim.job.queue == job.queue && victim.job != job
+	})
+}
+
+// evictFor allocates the pending tasks of the admitted jobs to nodes where they fit once the
+// victims which candidate selects and fns allow are evicted, the lowest priority victims first.
+// The evictions are undone unless the job is ready.
+func (ssn *session) evictFor(verb string, fns []func(*taskInfo, []*taskInfo) []*taskInfo, candidate func(*jobInfo, *taskInfo) bool) error {
+	for _, queue := range ssn.sortedQueues() {
+		if ssn.overused(queue) {
+			continue
+		}
+		for _, job := range ssn.sortedJobs(queue) {
+			if !job.inqueue || len(job.pending) == 0 || ssn.jobValid(job) != "" {
+				continue
+			}
+			stmt := ssn.statement()
+			for _, task := range ssn.sortedTasks(job) {
+				if !ssn.allocatable(queue, task) {
+					break
+				}
+				ok, err := ssn.evictOnNode(stmt, task, fns, candidate)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					break
+				}
+			}
+			if !ssn.jobReady(job) {
+				if err := stmt.discard(); err != nil {
+					return err
+				}
+				continue
+			}
+			if len(stmt.operations) > 0 {
+				job.reason = ""
+			}
+			stmt.commit(fmt.Sprintf("%s by job %s", verb, job.name))
+		}
+	}
+	return nil
+}
+
+// evictOnNode allocates task to the first feasible node where it fits after evicting victims.
+func (ssn *session) evictOnNode(stmt *statement, task *taskInfo, fns []func(*taskInfo, []*taskInfo) []*taskInfo,
+	candidate func(*jobInfo, *taskInfo) bool) (bool, error) {
+	feasible, _ := ssn.predicates.FeasibleNodes(ssn.cluster, task.pod)
+	for _, node := range feasible {
+		var candidates []*taskInfo
+		for _, pod := range node.Pods {
+			if t := ssn.tasks[simulator.PodKey(pod)]; t != nil && candidate(task.job, t) {
+				candidates = append(candidates, t)
+			}
+		}
+		sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].priority < candidates[j].priority })
+		idle := ssn.idle(node)
+		if !task.resreq.LessEqual(idle) {
+			candidates = victims(fns, task, candidates)
+			available := idle.Clone()
+			for _, victim := range candidates {
+				available.Add(victim.resreq)
+			}
+			if !task.resreq.LessEqual(available) {
+				continue
+			}
+			for _, victim := range candidates {
+				if task.resreq.LessEqual(ssn.idle(node)) {
+					break
+				}
+				if err := stmt.evict(victim); err != nil {
+					return false, err
+				}
+			}
+		}
+		if err := stmt.allocate(task, node.Node.Name); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+	return false, nil
+}
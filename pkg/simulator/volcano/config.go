@@ -0,0 +1,192 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package volcano
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/yaml"
+)
+
+// DefaultConfiguration is the default volcano-scheduler.conf of volcano.
+const DefaultConfiguration = `
+actions: "enqueue, allocate, backfill"
+tiers:
+- plugins:
+  - name: priority
+  - name: gang
+  - name: conformance
+- plugins:
+  - name: overcommit
+  - name: drf
+  - name: predicates
+  - name: proportion
+  - name: nodeorder
+  - name: binpack
+`
+
+// Configuration is the configuration of the simulated scheduler, in the layout of volcano-scheduler.conf.
+// The plugins of a tier take precedence over the plugins of the following tiers when they order
+// queues, jobs and tasks.
+type Configuration struct {
+	Actions string `json:"actions"`
+	Tiers   []Tier `json:"tiers"`
+
+	// Ignored are the keys of the configuration which are not simulated, such as the configurations
+	// of the actions, and the options of the plugins as <plugin>.<key>.
+	Ignored []string `json:"-"`
+}
+
+// Tier is a tier of plugins.
+type Tier struct {
+	Plugins []PluginOption `json:"plugins"`
+}
+
+// PluginOption enables a plugin and its extension points, which are all enabled if not set.
+type PluginOption struct {
+	Name               string                 `json:"name"`
+	EnabledJobOrder    *bool                  `json:"enableJobOrder,omitempty"`
+	EnabledJobReady    *bool                  `json:"enableJobReady,omitempty"`
+	EnabledTaskOrder   *bool                  `json:"enableTaskOrder,omitempty"`
+	EnabledPreemptable *bool                  `json:"enablePreemptable,omitempty"`
+	EnabledReclaimable *bool                  `json:"enableReclaimable,omitempty"`
+	EnabledQueueOrder  *bool                  `json:"enableQueueOrder,omitempty"`
+	EnabledPredicate   *bool                  `json:"enablePredicate,omitempty"`
+	EnabledNodeOrder   *bool                  `json:"enableNodeOrder,omitempty"`
+	EnabledOverused    *bool                  `json:"enableOverused,omitempty"`
+	EnabledAllocatable *bool                  `json:"enableAllocatable,omitempty"`
+	EnabledJobEnqueued *bool                  `json:"enableJobEnqueued,omitempty"`
+	Arguments          map[string]interface{} `json:"arguments,omitempty"`
+}
+
+func enabled(flag *bool) bool {
+	return flag == nil || *flag
+}
+
+// floatArgument returns the argument key of the plugin as a number, or def if it is not set.
+func (o *PluginOption) floatArgument(key string, def float64) (float64, error) {
+	switch value := o.Arguments[key].(type) {
+	case nil:
+		return def, nil
+	case float64:
+		return value, nil
+	case int64:
+		return float64(value), nil
+	case string:
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f, nil
+		}
+	}
+	return 0, fmt.Errorf("plugin %s: invalid argument %s %v", o.Name, key, o.Arguments[key])
+}
+
+// LoadConfiguration reads the configuration from filename, or returns the default configuration if it is empty.
+func LoadConfiguration(filename string) (*Configuration, error) {
+	data := []byte(DefaultConfiguration)
+	if filename != "" {
+		var err error
+		if data, err = os.ReadFile(filename); err != nil {
+			return nil, err
+		}
+	}
+	return ParseConfiguration(data)
+}
+
+// ParseConfiguration parses and validates a configuration.
+func ParseConfiguration(data []byte) (*Configuration, error) {
+	conf := &Configuration{}
+	if err := yaml.Unmarshal(data, conf); err != nil {
+		return nil, fmt.Errorf("invalid scheduler configuration: %v", err)
+	}
+	ignored, err := ignoredKeys(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid scheduler configuration: %v", err)
+	}
+	conf.Ignored = ignored
+	for _, action := range conf.actions() {
+		if _, ok := actions[action]; !ok {
+			return nil, fmt.Errorf("unknown action %q", action)
+		}
+	}
+	for _, tier := range conf.Tiers {
+		for _, plugin := range tier.Plugins {
+			if _, ok := pluginBuilders[plugin.Name]; !ok {
+				return nil, fmt.Errorf("unknown plugin %q", plugin.Name)
+			}
+		}
+	}
+	return conf, nil
+}
+
+// ignoredKeys returns the keys of the configuration data and of its plugins which Configuration
+// does not have, e.g. the configurations section or the enabledHierarchy option of proportion.
+func ignoredKeys(data []byte) ([]string, error) {
+	var fields map[string]interface{}
+	if err := yaml.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	var tiers struct {
+		Tiers []struct {
+			Plugins []map[string]interface{} `json:"plugins"`
+		} `json:"tiers"`
+	}
+	if err := yaml.Unmarshal(data, &tiers); err != nil {
+		return nil, err
+	}
+
+	confKeys, pluginKeys := jsonKeys(reflect.TypeOf(Configuration{})), jsonKeys(reflect.TypeOf(PluginOption{}))
+	ignored := sets.NewString()
+	for key := range fields {
+		if !confKeys.Has(key) {
+			ignored.Insert(key)
+		}
+	}
+	for _, tier := range tiers.Tiers {
+		for _, plugin := range tier.Plugins {
+			for key := range plugin {
+				if !pluginKeys.Has(key) {
+					ignored.Insert(fmt.Sprintf("%v.%s", plugin["name"], key))
+				}
+			}
+		}
+	}
+	return ignored.List(), nil
+}
+
+// jsonKeys returns the json keys of the fields of a struct type.
+func jsonKeys(t reflect.Type) sets.String {
+	keys := sets.NewString()
+	for i := 0; i < t.NumField(); i++ {
+		if name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]; name != "" && name != "-" {
+			keys.Insert(name)
+		}
+	}
+	return keys
+}
+
+func (c *Configuration) actions() []string {
+	var names []string
+	for _, name := range strings.Split(c.Actions, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,363 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package volcano
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/D0m021ng/scheduler-simulator/pkg/simulator"
+)
+
+// pluginBuilders open the plugins of volcano on a session, by their names in volcano-scheduler.conf.
+var pluginBuilders = map[string]func(ssn *session, opt *PluginOption) error{
+	"priority":    openPriority,
+	"gang":        openGang,
+	"conformance": openConformance,
+	"drf":         openDRF,
+	"predicates":  openPredicates,
+	"proportion":  openProportion,
+	"nodeorder":   openNodeOrder,
+	"binpack":     openBinpack,
+	"overcommit":  openOvercommit,
+}
+
+func compare(l, r float64) int {
+	switch {
+	case l < r:
+		return -1
+	case l > r:
+		return 1
+	}
+	return 0
+}
+
+// openPriority orders jobs and tasks by priority, the highest first, and lets tasks
+// preempt the tasks of jobs with a lower priority.
+func openPriority(ssn *session, opt *PluginOption) error {
+	if enabled(opt.EnabledJobOrder) {
+		ssn.jobOrderFns = append(ssn.jobOrderFns, func(l, r *jobInfo) int {
+			return compare(float64(r.priority), float64(l.priority))
+		})
+	}
+	if enabled(opt.EnabledTaskOrder) {
+		ssn.taskOrderFns = append(ssn.taskOrderFns, func(l, r *taskInfo) int {
+			return compare(float64(r.priority), float64(l.priority))
+		})
+	}
+	if enabled(opt.EnabledPreemptable) {
+		ssn.preemptableFns = append(ssn.preemptableFns, func(preemptor *taskInfo, candidates []*taskInfo) []*taskInfo {
+			var victims []*taskInfo
+			for _, candidate := range candidates {
+				if candidate.job == preemptor.job && candidate.priority < preemptor.priority ||
+					candidate.job != preemptor.job && candidate.job.priority < preemptor.job.priority {
+					victims = append(victims, candidate)
+				}
+			}
+			return victims
+		})
+	}
+	return nil
+}
+
+// openGang only lets a job run once minMember of its tasks can, schedules the jobs which are
+// not ready first, and only evicts tasks of jobs which keep minMember tasks running.
+func openGang(ssn *session, opt *PluginOption) error {
+	if enabled(opt.EnabledJobReady) {
+		ssn.jobReadyFns = append(ssn.jobReadyFns, func(job *jobInfo) bool {
+			return job.ready() >= job.minMember
+		})
+		ssn.jobValidFns = append(ssn.jobValidFns, func(job *jobInfo) string {
+			if job.valid() {
+				return ""
+			}
+			return fmt.Sprintf("pod group %s is not ready, %d pod(s) of %d minMember exist",
+				job.name, len(job.pending)+len(job.running), job.minMember)
+		})
+	}
+	if enabled(opt.EnabledJobOrder) {
+		ssn.jobOrderFns = append(ssn.jobOrderFns, func(l, r *jobInfo) int {
+			lReady, rReady := l.ready() >= l.minMember, r.ready() >= r.minMember
+			switch {
+			case !lReady && rReady:
+				return -1
+			case lReady && !rReady:
+				return 1
+			}
+			return 0
+		})
+	}
+	evictable := func(_ *taskInfo, candidates []*taskInfo) []*taskInfo {
+		occupied := map[*jobInfo]int{}
+		var victims []*taskInfo
+		for _, candidate := range candidates {
+			job := candidate.job
+			if _, ok := occupied[job]; !ok {
+				occupied[job] = job.ready()
+			}
+			if job.minMember <= occupied[job]-1 || job.minMember == 1 {
+				occupied[job]--
+				victims = append(victims, candidate)
+			}
+		}
+		return victims
+	}
+	if enabled(opt.EnabledPreemptable) {
+		ssn.preemptableFns = append(ssn.preemptableFns, evictable)
+	}
+	if enabled(opt.EnabledReclaimable) {
+		ssn.reclaimableFns = append(ssn.reclaimableFns, evictable)
+	}
+	return nil
+}
+
+// openConformance never evicts critical pods.
+func openConformance(ssn *session, opt *PluginOption) error {
+	evictable := func(_ *taskInfo, candidates []*taskInfo) []*taskInfo {
+		var victims []*taskInfo
+		for _, candidate := range candidates {
+			pod := candidate.pod
+			if pod.Namespace == metav1.NamespaceSystem ||
+				pod.Spec.PriorityClassName == "system-cluster-critical" ||
+				pod.Spec.PriorityClassName == "system-node-critical" {
+				continue
+			}
+			victims = append(victims, candidate)
+		}
+		return victims
+	}
+	if enabled(opt.EnabledPreemptable) {
+		ssn.preemptableFns = append(ssn.preemptableFns, evictable)
+	}
+	if enabled(opt.EnabledReclaimable) {
+		ssn.reclaimableFns = append(ssn.reclaimableFns, evictable)
+	}
+	return nil
+}
+
+// openDRF orders jobs by their dominant resource share of the cluster, the lowest first.
+func openDRF(ssn *session, opt *PluginOption) error {
+	if enabled(opt.EnabledJobOrder) {
+		ssn.jobOrderFns = append(ssn.jobOrderFns, func(l, r *jobInfo) int {
+			return compare(l.allocated.Share(ssn.total), r.allocated.Share(ssn.total))
+		})
+	}
+	return nil
+}
+
+// openPredicates filters nodes like kube-scheduler does, besides the resources which
+// every session checks.
+func openPredicates(ssn *session, opt *PluginOption) error {
+	if !enabled(opt.EnabledPredicate) {
+		return nil
+	}
+	for _, plugin := range []simulator.FilterPlugin{
+		&simulator.NodeUnschedulable{},
+		&simulator.NodeAffinity{},
+		&simulator.TaintToleration{},
+		&simulator.PodTopologySpread{},
+		&simulator.InterPodAffinity{},
+	} {
+		ssn.scheduler.Filters = append(ssn.scheduler.Filters, plugin)
+		ssn.predicates.Filters = append(ssn.predicates.Filters, plugin)
+	}
+	return nil
+}
+
+// openProportion divides the resources of the cluster between the queues by their weights, up to
+// what their jobs request and their capability. A queue is overused once it is allocated its
+// deserved share, and queues which are allocated more than their share are reclaimed from.
+func openProportion(ssn *session, opt *PluginOption) error {
+	remaining := ssn.total.Clone()
+	meet := map[*queueInfo]bool{}
+	for {
+		var totalWeight float64
+		for _, queue := range ssn.queues {
+			if !meet[queue] {
+				totalWeight += queue.weight
+			}
+		}
+		if totalWeight == 0 || remaining.IsEmpty() {
+			break
+		}
+		increased := Resource{}
+		for _, queue := range ssn.queues {
+			if meet[queue] {
+				continue
+			}
+			old := queue.deserved.Clone()
+			queue.deserved.Add(remaining.Clone().Multiply(queue.weight / totalWeight))
+			if queue.capability != nil {
+				queue.deserved = queue.deserved.Min(queue.capability)
+			}
+			queue.deserved = queue.deserved.Min(queue.request)
+			for name := range queue.deserved {
+				if _, ok := queue.request[name]; !ok {
+					delete(queue.deserved, name)
+				}
+			}
+			if queue.request.LessEqual(queue.deserved) || queue.deserved.LessEqual(old) {
+				meet[queue] = true
+			}
+			increased.Add(queue.deserved.Clone().Sub(old))
+		}
+		remaining.Sub(increased)
+		if increased.IsEmpty() {
+			break
+		}
+	}
+
+	realCapability := func(queue *queueInfo) Resource {
+		if queue.capability == nil {
+			return ssn.total
+		}
+		return ssn.total.Min(queue.capability)
+	}
+	if enabled(opt.EnabledQueueOrder) {
+		ssn.queueOrderFns = append(ssn.queueOrderFns, func(l, r *queueInfo) int {
+			return compare(l.allocated.Share(l.deserved), r.allocated.Share(r.deserved))
+		})
+	}
+	if enabled(opt.EnabledOverused) {
+		ssn.overusedFns = append(ssn.overusedFns, func(queue *queueInfo) bool {
+			return queue.deserved.LessEqual(queue.allocated)
+		})
+	}
+	if enabled(opt.EnabledAllocatable) {
+		ssn.allocatableFns = append(ssn.allocatableFns, func(queue *queueInfo, task *taskInfo) bool {
+			capability := realCapability(queue)
+			future := queue.allocated.Clone().Add(task.resreq)
+			for name := range task.resreq {
+				if _, ok := capability[name]; ok && future[name] > capability[name]+minResource {
+					return false
+				}
+			}
+			return true
+		})
+	}
+	if enabled(opt.EnabledJobEnqueued) {
+		ssn.enqueueableFns = append(ssn.enqueueableFns, func(job *jobInfo) bool {
+			queue := job.queue
+			return job.minResources.Clone().Add(queue.allocated).Add(queue.inqueue).LessEqual(realCapability(queue))
+		})
+	}
+	if enabled(opt.EnabledReclaimable) {
+		ssn.reclaimableFns = append(ssn.reclaimableFns, func(_ *taskInfo, candidates []*taskInfo) []*taskInfo {
+			allocations := map[*queueInfo]Resource{}
+			var victims []*taskInfo
+			for _, candidate := range candidates {
+				queue := candidate.job.queue
+				if _, ok := allocations[queue]; !ok {
+					allocations[queue] = queue.allocated.Clone()
+				}
+				if allocations[queue].LessEqual(queue.deserved) {
+					continue
+				}
+				allocations[queue].Sub(candidate.resreq)
+				victims = append(victims, candidate)
+			}
+			return victims
+		})
+	}
+	return nil
+}
+
+// openNodeOrder scores nodes like the default plugins of kube-scheduler, weighted by its arguments.
+func openNodeOrder(ssn *session, opt *PluginOption) error {
+	if !enabled(opt.EnabledNodeOrder) {
+		return nil
+	}
+	plugins := []struct {
+		arg    string
+		def    float64
+		plugin simulator.ScorePlugin
+	}{
+		{"leastrequested.weight", 1, simulator.NewNodeResourcesFit(simulator.ScoringStrategy{Type: simulator.LeastAllocated})},
+		{"mostrequested.weight", 0, simulator.NewNodeResourcesFit(simulator.ScoringStrategy{Type: simulator.MostAllocated})},
+		{"nodeaffinity.weight", 1, &simulator.NodeAffinity{}},
+		{"podaffinity.weight", 1, &simulator.InterPodAffinity{}},
+		{"tainttoleration.weight", 1, &simulator.TaintToleration{}},
+	}
+	for _, p := range plugins {
+		weight, err := opt.floatArgument(p.arg, p.def)
+		if err != nil {
+			return err
+		}
+		if weight > 0 {
+			ssn.scheduler.Scores = append(ssn.scheduler.Scores, simulator.WeightedScorePlugin{Plugin: p.plugin, Weight: int64(weight)})
+		}
+	}
+	return nil
+}
+
+// openBinpack prefers the nodes with the most requested resources, to keep whole nodes free.
+func openBinpack(ssn *session, opt *PluginOption) error {
+	if !enabled(opt.EnabledNodeOrder) {
+		return nil
+	}
+	weight, err := opt.floatArgument("binpack.weight", 1)
+	if err != nil || weight <= 0 {
+		return err
+	}
+	strategy := simulator.ScoringStrategy{Type: simulator.MostAllocated}
+	names := []string{string(corev1.ResourceCPU), string(corev1.ResourceMemory)}
+	if extra, ok := opt.Arguments["binpack.resources"].(string); ok {
+		for _, name := range strings.Split(extra, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	for _, name := range names {
+		key := "binpack." + name
+		if name != string(corev1.ResourceCPU) && name != string(corev1.ResourceMemory) {
+			key = "binpack.resources." + name
+		}
+		resourceWeight, err := opt.floatArgument(key, 1)
+		if err != nil {
+			return err
+		}
+		if resourceWeight > 0 {
+			strategy.Resources = append(strategy.Resources,
+				simulator.ResourceWeight{Name: corev1.ResourceName(name), Weight: int64(resourceWeight)})
+		}
+	}
+	ssn.scheduler.Scores = append(ssn.scheduler.Scores,
+		simulator.WeightedScorePlugin{Plugin: simulator.NewNodeResourcesFit(strategy), Weight: int64(weight)})
+	return nil
+}
+
+// openOvercommit admits jobs while their minResources fit into the idle resources
+// of the cluster, overcommitted by the overcommit-factor.
+func openOvercommit(ssn *session, opt *PluginOption) error {
+	if !enabled(opt.EnabledJobEnqueued) {
+		return nil
+	}
+	factor, err := opt.floatArgument("overcommit-factor", 1.2)
+	if err != nil {
+		return err
+	}
+	ssn.enqueueableFns = append(ssn.enqueueableFns, func(job *jobInfo) bool {
+		idle := ssn.total.Clone().Multiply(factor)
+		for _, queue := range ssn.queues {
+			idle.Sub(queue.allocated).Sub(queue.inqueue)
+		}
+		return job.minResources.LessEqual(idle)
+	})
+	return nil
+}
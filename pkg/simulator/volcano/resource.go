@@ -0,0 +1,121 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package volcano
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// minResource is the difference below which two amounts of a resource are equal.
+const minResource = 0.1
+
+// Resource is an amount of resources in millicores for cpu and in the plain value for all
+// other resources, so that shares can be computed. The pods resource is not tracked.
+type Resource map[corev1.ResourceName]float64
+
+// NewResource returns the amounts of list.
+func NewResource(list corev1.ResourceList) Resource {
+	r := Resource{}
+	for name, quantity := range list {
+		if name == corev1.ResourcePods {
+			continue
+		}
+		if name == corev1.ResourceCPU {
+			r[name] = float64(quantity.MilliValue())
+			continue
+		}
+		r[name] = float64(quantity.Value())
+	}
+	return r
+}
+
+func (r Resource) Clone() Resource {
+	c := Resource{}
+	for name, value := range r {
+		c[name] = value
+	}
+	return c
+}
+
+func (r Resource) Add(other Resource) Resource {
+	for name, value := range other {
+		r[name] += value
+	}
+	return r
+}
+
+func (r Resource) Sub(other Resource) Resource {
+	for name, value := range other {
+		r[name] -= value
+	}
+	return r
+}
+
+// Multiply scales every resource of r by ratio.
+func (r Resource) Multiply(ratio float64) Resource {
+	for name := range r {
+		r[name] *= ratio
+	}
+	return r
+}
+
+// Min returns the smaller amount of r and other per resource, resources missing in other are kept.
+func (r Resource) Min(other Resource) Resource {
+	m := r.Clone()
+	for name, value := range other {
+		if current, ok := m[name]; ok && value < current {
+			m[name] = value
+		}
+	}
+	return m
+}
+
+// LessEqual returns whether every resource of r is at most the amount of other, which is zero if missing.
+func (r Resource) LessEqual(other Resource) bool {
+	for name, value := range r {
+		if value > other[name]+minResource {
+			return false
+		}
+	}
+	return true
+}
+
+// IsEmpty returns whether r has no resources.
+func (r Resource) IsEmpty() bool {
+	for _, value := range r {
+		if value >= minResource {
+			return false
+		}
+	}
+	return true
+}
+
+// Share returns the largest ratio of r to total over all resources, the dominant share of r.
+func (r Resource) Share(total Resource) float64 {
+	var share float64
+	for name, value := range r {
+		var s float64
+		switch {
+		case total[name] >= minResource:
+			s = value / total[name]
+		case value >= minResource:
+			s = 1
+		}
+		if s > share {
+			share = s
+		}
+	}
+	return share
+}
@@ -0,0 +1,447 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package volcano
+
+import (
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	corev1helpers "k8s.io/component-helpers/scheduling/corev1"
+
+	"github.com/D0m021ng/scheduler-simulator/pkg/simctl/generate"
+	"github.com/D0m021ng/scheduler-simulator/pkg/simulator"
+)
+
+// taskInfo is a pod of a job.
+type taskInfo struct {
+	pod      *corev1.Pod
+	key      types.NamespacedName
+	job      *jobInfo
+	resreq   Resource
+	priority int32
+}
+
+// jobInfo is a PodGroup with its pods, or a pod which belongs to no PodGroup.
+type jobInfo struct {
+	name         string
+	queue        *queueInfo
+	minMember    int
+	minResources Resource
+	priority     int32
+	order        int
+	inqueue      bool
+
+	pending   []*taskInfo
+	running   []*taskInfo
+	allocated Resource
+	// reason is why the pending tasks of the job were not allocated in this session.
+	reason string
+}
+
+// ready returns the count of tasks which are running or allocated.
+func (j *jobInfo) ready() int {
+	return len(j.running)
+}
+
+// valid returns whether the job has enough pods to ever reach its minMember.
+func (j *jobInfo) valid() bool {
+	return len(j.pending)+len(j.running) >= j.minMember
+}
+
+// queueInfo is a Queue with the resources of its jobs in this session.
+type queueInfo struct {
+	name        string
+	weight      float64
+	capability  Resource
+	reclaimable bool
+	order       int
+	jobs        []*jobInfo
+
+	// request are the resources of all tasks of the jobs, allocated of their running
+	// tasks and inqueue the minResources of the admitted jobs which run no task yet.
+	request   Resource
+	allocated Resource
+	inqueue   Resource
+	// deserved is the fair share of the cluster of the queue, as computed by proportion.
+	deserved Resource
+}
+
+// session is a scheduling cycle over a snapshot of the cluster. Plugins register their
+// functions on it in the order of their tiers, and actions consult them.
+type session struct {
+	cluster *simulator.Cluster
+	total   Resource
+	queues  []*queueInfo
+	jobs    []*jobInfo
+	tasks   map[types.NamespacedName]*taskInfo
+
+	// scheduler filters nodes with the predicates and resources and scores them with the
+	// node order plugins, predicates only runs the predicates for evictions.
+	scheduler  *simulator.Scheduler
+	predicates *simulator.Scheduler
+
+	jobOrderFns    []func(l, r *jobInfo) int
+	queueOrderFns  []func(l, r *queueInfo) int
+	taskOrderFns   []func(l, r *taskInfo) int
+	jobReadyFns    []func(job *jobInfo) bool
+	jobValidFns    []func(job *jobInfo) string
+	overusedFns    []func(queue *queueInfo) bool
+	allocatableFns []func(queue *queueInfo, task *taskInfo) bool
+	enqueueableFns []func(job *jobInfo) bool
+	preemptableFns []func(preemptor *taskInfo, victims []*taskInfo) []*taskInfo
+	reclaimableFns []func(reclaimer *taskInfo, victims []*taskInfo) []*taskInfo
+
+	result *simulator.Result
+}
+
+// openSession takes a snapshot of the jobs and queues of c and opens the plugins of conf.
+func (s *Scheduler) openSession(c *simulator.Cluster) (*session, error) {
+	_, allocatable := c.Totals()
+	ssn := &session{
+		cluster:    c,
+		total:      NewResource(allocatable),
+		tasks:      map[types.NamespacedName]*taskInfo{},
+		scheduler:  &simulator.Scheduler{},
+		predicates: &simulator.Scheduler{},
+		result:     &simulator.Result{},
+	}
+	queues := map[string]*queueInfo{}
+	for _, spec := range s.queues {
+		queues[spec.name] = ssn.addQueue(spec)
+	}
+
+	jobs := map[string]*jobInfo{}
+	addTask := func(pod *corev1.Pod, running bool) {
+		name, spec := s.jobOf(pod)
+		job, ok := jobs[name]
+		if !ok {
+			queue, ok := queues[spec.queue]
+			if !ok {
+				queue = ssn.addQueue(&queueSpec{name: spec.queue, weight: 1, reclaimable: true})
+				queues[spec.queue] = queue
+			}
+			if _, ok := s.jobOrder[name]; !ok {
+				s.jobOrder[name] = len(s.jobOrder)
+			}
+			job = &jobInfo{
+				name:         name,
+				queue:        queue,
+				minMember:    spec.minMember,
+				minResources: spec.minResources,
+				order:        s.jobOrder[name],
+				allocated:    Resource{},
+				priority:     corev1helpers.PodPriority(pod),
+			}
+			jobs[name] = job
+			ssn.jobs = append(ssn.jobs, job)
+			queue.jobs = append(queue.jobs, job)
+		}
+		task := &taskInfo{
+			pod:      pod,
+			key:      simulator.PodKey(pod),
+			job:      job,
+			resreq:   NewResource(simulator.PodRequests(pod)),
+			priority: corev1helpers.PodPriority(pod),
+		}
+		if task.priority > job.priority {
+			job.priority = task.priority
+		}
+		ssn.tasks[task.key] = task
+		job.queue.request.Add(task.resreq)
+		if running {
+			job.running = append(job.running, task)
+			job.allocated.Add(task.resreq)
+			job.queue.allocated.Add(task.resreq)
+		} else {
+			job.pending = append(job.pending, task)
+		}
+	}
+	for _, node := range c.Nodes() {
+		for _, pod := range node.Pods {
+			addTask(pod, true)
+		}
+	}
+	for _, pod := range c.PendingPods() {
+		addTask(pod, false)
+	}
+	sort.SliceStable(ssn.jobs, func(i, j int) bool { return ssn.jobs[i].order < ssn.jobs[j].order })
+	for _, queue := range ssn.queues {
+		sort.SliceStable(queue.jobs, func(i, j int) bool { return queue.jobs[i].order < queue.jobs[j].order })
+	}
+	for _, job := range ssn.jobs {
+		job.inqueue = s.inqueue[job.name] || len(job.running) > 0 || !s.hasAction("enqueue")
+		if job.inqueue && len(job.running) == 0 {
+			job.queue.inqueue.Add(job.minResources)
+		}
+	}
+
+	ssn.scheduler.Filters = append(ssn.scheduler.Filters, simulator.NewNodeResourcesFit(simulator.ScoringStrategy{}))
+	for _, tier := range s.conf.Tiers {
+		for i := range tier.Plugins {
+			if err := pluginBuilders[tier.Plugins[i].Name](ssn, &tier.Plugins[i]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return ssn, nil
+}
+
+func (ssn *session) addQueue(spec *queueSpec) *queueInfo {
+	queue := &queueInfo{
+		name:        spec.name,
+		weight:      float64(spec.weight),
+		capability:  spec.capability,
+		reclaimable: spec.reclaimable,
+		order:       len(ssn.queues),
+		request:     Resource{},
+		allocated:   Resource{},
+		inqueue:     Resource{},
+		deserved:    Resource{},
+	}
+	ssn.queues = append(ssn.queues, queue)
+	return queue
+}
+
+func (ssn *session) jobLess(l, r *jobInfo) bool {
+	for _, fn := range ssn.jobOrderFns {
+		if v := fn(l, r); v != 0 {
+			return v < 0
+		}
+	}
+	return l.order < r.order
+}
+
+func (ssn *session) queueLess(l, r *queueInfo) bool {
+	for _, fn := range ssn.queueOrderFns {
+		if v := fn(l, r); v != 0 {
+			return v < 0
+		}
+	}
+	return l.order < r.order
+}
+
+func (ssn *session) taskLess(l, r *taskInfo) bool {
+	for _, fn := range ssn.taskOrderFns {
+		if v := fn(l, r); v != 0 {
+			return v < 0
+		}
+	}
+	return false
+}
+
+// jobReady returns whether the job may run with its allocated tasks, which all plugins must agree on.
+func (ssn *session) jobReady(job *jobInfo) bool {
+	for _, fn := range ssn.jobReadyFns {
+		if !fn(job) {
+			return false
+		}
+	}
+	return true
+}
+
+// jobValid returns why the job can never be scheduled, or an empty string.
+func (ssn *session) jobValid(job *jobInfo) string {
+	for _, fn := range ssn.jobValidFns {
+		if reason := fn(job); reason != "" {
+			return reason
+		}
+	}
+	return ""
+}
+
+func (ssn *session) overused(queue *queueInfo) bool {
+	for _, fn := range ssn.overusedFns {
+		if fn(queue) {
+			return true
+		}
+	}
+	return false
+}
+
+func (ssn *session) allocatable(queue *queueInfo, task *taskInfo) bool {
+	for _, fn := range ssn.allocatableFns {
+		if !fn(queue, task) {
+			return false
+		}
+	}
+	return true
+}
+
+func (ssn *session) enqueueable(job *jobInfo) bool {
+	for _, fn := range ssn.enqueueableFns {
+		if !fn(job) {
+			return false
+		}
+	}
+	return true
+}
+
+// victims returns the candidates which every plugin allows to evict, none if no plugin decides.
+func victims(fns []func(*taskInfo, []*taskInfo) []*taskInfo, task *taskInfo, candidates []*taskInfo) []*taskInfo {
+	if len(fns) == 0 {
+		return nil
+	}
+	for _, fn := range fns {
+		candidates = fn(task, candidates)
+	}
+	return candidates
+}
+
+// sortedTasks returns the pending tasks of job in task order.
+func (ssn *session) sortedTasks(job *jobInfo) []*taskInfo {
+	tasks := append([]*taskInfo(nil), job.pending...)
+	sort.SliceStable(tasks, func(i, j int) bool { return ssn.taskLess(tasks[i], tasks[j]) })
+	return tasks
+}
+
+// idle returns the resources of node which are not requested.
+func (ssn *session) idle(node *simulator.NodeInfo) Resource {
+	return NewResource(node.Free())
+}
+
+// statement allocates and evicts tasks, which is undone unless it is committed.
+type statement struct {
+	ssn        *session
+	operations []operation
+}
+
+type operation struct {
+	task  *taskInfo
+	node  string
+	evict bool
+}
+
+func (ssn *session) statement() *statement {
+	return &statement{ssn: ssn}
+}
+
+func (s *statement) allocate(task *taskInfo, node string) error {
+	if err := s.ssn.cluster.Bind(task.key, node); err != nil {
+		return err
+	}
+	job := task.job
+	job.pending = removeTask(job.pending, task)
+	job.running = append(job.running, task)
+	job.allocated.Add(task.resreq)
+	job.queue.allocated.Add(task.resreq)
+	s.operations = append(s.operations, operation{task: task, node: node})
+	return nil
+}
+
+func (s *statement) evict(task *taskInfo) error {
+	node := task.pod.Spec.NodeName
+	if err := s.ssn.cluster.Unbind(task.key); err != nil {
+		return err
+	}
+	job := task.job
+	job.running = removeTask(job.running, task)
+	job.pending = append(job.pending, task)
+	job.allocated.Sub(task.resreq)
+	job.queue.allocated.Sub(task.resreq)
+	s.operations = append(s.operations, operation{task: task, node: node, evict: true})
+	return nil
+}
+
+// discard undoes the operations in reverse order.
+func (s *statement) discard() error {
+	for i := len(s.operations) - 1; i >= 0; i-- {
+		op := s.operations[i]
+		job := op.task.job
+		if op.evict {
+			if err := s.ssn.cluster.Bind(op.task.key, op.node); err != nil {
+				return err
+			}
+			job.pending = removeTask(job.pending, op.task)
+			job.running = append(job.running, op.task)
+			job.allocated.Add(op.task.resreq)
+			job.queue.allocated.Add(op.task.resreq)
+			continue
+		}
+		if err := s.ssn.cluster.Unbind(op.task.key); err != nil {
+			return err
+		}
+		job.running = removeTask(job.running, op.task)
+		job.pending = append(job.pending, op.task)
+		job.allocated.Sub(op.task.resreq)
+		job.queue.allocated.Sub(op.task.resreq)
+	}
+	s.operations = nil
+	return nil
+}
+
+// commit adds the operations to the result of the session.
+func (s *statement) commit(reason string) {
+	for _, op := range s.operations {
+		placement := simulator.Placement{Pod: op.task.key, Node: op.node}
+		if op.evict {
+			placement.Reason = reason
+			s.ssn.result.Evicted = append(s.ssn.result.Evicted, placement)
+			continue
+		}
+		s.ssn.result.Scheduled = append(s.ssn.result.Scheduled, placement)
+	}
+	s.operations = nil
+}
+
+func removeTask(tasks []*taskInfo, task *taskInfo) []*taskInfo {
+	for i, t := range tasks {
+		if t == task {
+			return append(tasks[:i], tasks[i+1:]...)
+		}
+	}
+	return tasks
+}
+
+// closeSession records the pods of the session as scheduled or unschedulable.
+func (ssn *session) closeSession() *simulator.Result {
+	for _, placement := range ssn.result.Scheduled {
+		if task := ssn.tasks[placement.Pod]; task != nil {
+			simulator.SetPodScheduled(task.pod, corev1.ConditionTrue, "", "")
+		}
+	}
+	for _, job := range ssn.jobs {
+		reason := job.reason
+		if reason == "" {
+			reason = fmt.Sprintf("job %s was not scheduled in this cycle", job.name)
+		}
+		for _, task := range job.pending {
+			simulator.SetPodScheduled(task.pod, corev1.ConditionFalse, corev1.PodReasonUnschedulable, reason)
+			ssn.result.Unschedulable = append(ssn.result.Unschedulable, simulator.Placement{Pod: task.key, Reason: reason})
+		}
+	}
+	return ssn.result
+}
+
+// jobOf returns the name of the job of pod and its spec, a pod which belongs
+// to no PodGroup is a job of its own with a minMember of 1.
+func (s *Scheduler) jobOf(pod *corev1.Pod) (string, *podGroupSpec) {
+	if group, ok := pod.Annotations[generate.PodGroupAnnotationKey]; ok {
+		name := types.NamespacedName{Namespace: pod.Namespace, Name: group}.String()
+		if spec, ok := s.podGroups[name]; ok {
+			return name, spec
+		}
+		return name, &podGroupSpec{queue: podQueue(pod), minMember: 1}
+	}
+	return simulator.PodKey(pod).String(), &podGroupSpec{queue: podQueue(pod), minMember: 1}
+}
+
+func podQueue(pod *corev1.Pod) string {
+	if queue := pod.Annotations[generate.QueueAnnotationKey]; queue != "" {
+		return queue
+	}
+	return defaultQueue
+}
@@ -0,0 +1,167 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package volcano simulates the volcano batch scheduler: pods are grouped into jobs by their
+// PodGroup, jobs are admitted and allocated through queues which share the cluster by weight,
+// and the scheduling cycle runs the actions and tiers of plugins of volcano-scheduler.conf.
+package volcano
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/D0m021ng/scheduler-simulator/pkg/simulator"
+)
+
+const defaultQueue = "default"
+
+var (
+	queueKind    = schema.GroupKind{Group: "scheduling.volcano.sh", Kind: "Queue"}
+	podGroupKind = schema.GroupKind{Group: "scheduling.volcano.sh", Kind: "PodGroup"}
+)
+
+type queueSpec struct {
+	name        string
+	weight      int32
+	capability  Resource
+	reclaimable bool
+}
+
+type podGroupSpec struct {
+	queue        string
+	minMember    int
+	minResources Resource
+}
+
+// Scheduler is a simulated volcano scheduler, it implements simulator.Algorithm. It keeps
+// which jobs were admitted by the enqueue action from one scheduling cycle to the next.
+type Scheduler struct {
+	conf *Configuration
+
+	loaded    bool
+	queues    []*queueSpec
+	podGroups map[string]*podGroupSpec
+	inqueue   map[string]bool
+	jobOrder  map[string]int
+}
+
+// New returns a scheduler which runs the actions and plugins of conf.
+func New(conf *Configuration) *Scheduler {
+	return &Scheduler{
+		conf:      conf,
+		podGroups: map[string]*podGroupSpec{},
+		inqueue:   map[string]bool{},
+		jobOrder:  map[string]int{},
+	}
+}
+
+// Run runs a scheduling cycle, the actions of the configuration in order.
+func (s *Scheduler) Run(c *simulator.Cluster) (*simulator.Result, error) {
+	if !s.loaded {
+		if err := s.load(c.Objects); err != nil {
+			return nil, err
+		}
+		s.loaded = true
+	}
+	ssn, err := s.openSession(c)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range s.conf.actions() {
+		if err := actions[name](ssn); err != nil {
+			return nil, err
+		}
+	}
+	for _, job := range ssn.jobs {
+		if job.inqueue {
+			s.inqueue[job.name] = true
+		}
+	}
+	return ssn.closeSession(), nil
+}
+
+func (s *Scheduler) hasAction(name string) bool {
+	for _, action := range s.conf.actions() {
+		if action == name {
+			return true
+		}
+	}
+	return false
+}
+
+// load reads the queues and podgroups of objs, the default queue is added if it is missing.
+func (s *Scheduler) load(objs []*unstructured.Unstructured) error {
+	hasDefault := false
+	for _, obj := range objs {
+		var spec struct {
+			Weight       int32               `json:"weight"`
+			Capability   corev1.ResourceList `json:"capability"`
+			Reclaimable  *bool               `json:"reclaimable"`
+			MinMember    int32               `json:"minMember"`
+			Queue        string              `json:"queue"`
+			MinResources corev1.ResourceList `json:"minResources"`
+		}
+		gk := obj.GroupVersionKind().GroupKind()
+		if gk != queueKind && gk != podGroupKind {
+			continue
+		}
+		if m, ok := obj.Object["spec"].(map[string]interface{}); ok {
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(m, &spec); err != nil {
+				return fmt.Errorf("%s %s: %v", gk.Kind, obj.GetName(), err)
+			}
+		}
+
+		if gk == queueKind {
+			queue := &queueSpec{name: obj.GetName(), weight: spec.Weight, reclaimable: true}
+			if queue.weight <= 0 {
+				queue.weight = 1
+			}
+			if spec.Reclaimable != nil {
+				queue.reclaimable = *spec.Reclaimable
+			}
+			if len(spec.Capability) > 0 {
+				queue.capability = NewResource(spec.Capability)
+			}
+			hasDefault = hasDefault || queue.name == defaultQueue
+			s.queues = append(s.queues, queue)
+			continue
+		}
+		group := &podGroupSpec{
+			queue:        spec.Queue,
+			minMember:    int(spec.MinMember),
+			minResources: NewResource(spec.MinResources),
+		}
+		if group.queue == "" {
+			group.queue = defaultQueue
+		}
+		if group.minMember < 1 {
+			group.minMember = 1
+		}
+		namespace := obj.GetNamespace()
+		if namespace == "" {
+			namespace = metav1.NamespaceDefault
+		}
+		s.podGroups[types.NamespacedName{Namespace: namespace, Name: obj.GetName()}.String()] = group
+	}
+	if !hasDefault {
+		s.queues = append([]*queueSpec{{name: defaultQueue, weight: 1, reclaimable: true}}, s.queues...)
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package volcano
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/D0m021ng/scheduler-simulator/pkg/simctl/generate"
+	"github.com/D0m021ng/scheduler-simulator/pkg/simulator"
+)
+
+func newTestCluster(t *testing.T, cpu string, nodes int) *simulator.Cluster {
+	c := simulator.NewCluster()
+	capacity := generate.BuildResources(map[string]string{"cpu": cpu, "memory": "16Gi", "pods": "110"})
+	for i := 0; i < nodes; i++ {
+		if err := c.AddNode(generate.BuildFakeNode(fmt.Sprintf("n%d", i), false, capacity, capacity, nil, nil)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return c
+}
+
+func addTestPods(t *testing.T, c *simulator.Cluster, prefix, queue, group string, count int, cpu string) {
+	for i := 0; i < count; i++ {
+		pod := generate.BuildFakePod(fmt.Sprintf("%s-%d", prefix, i), "ns", "", queue, nil, corev1.PodPending,
+			generate.BuildResources(map[string]string{"cpu": cpu}))
+		if group != "" {
+			pod.Annotations[generate.PodGroupAnnotationKey] = group
+		}
+		if err := c.AddPod(pod); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func runDefault(t *testing.T, c *simulator.Cluster, conf string) *simulator.Result {
+	if conf == "" {
+		conf = DefaultConfiguration
+	}
+	parsed, err := ParseConfiguration([]byte(conf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := New(parsed).Run(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func countByPrefix(placements []simulator.Placement) map[string]int {
+	counts := map[string]int{}
+	for _, placement := range placements {
+		counts[strings.Split(placement.Pod.Name, "-")[0]]++
+	}
+	return counts
+}
+
+func TestGangScheduling(t *testing.T) {
+	c := newTestCluster(t, "4", 2)
+	c.Objects = append(c.Objects,
+		generate.BuildFakePodGroup("big", "ns", "", 3, nil),
+		generate.BuildFakePodGroup("small", "ns", "", 2, nil))
+	addTestPods(t, c, "big", "", "big", 3, "3")
+	addTestPods(t, c, "small", "", "small", 2, "3")
+
+	result := runDefault(t, c, "")
+	if got := countByPrefix(result.Scheduled); got["big"] != 0 || got["small"] != 2 {
+		t.Fatalf("scheduled %v, want only the 2 pods of the small gang", got)
+	}
+	if len(result.Unschedulable) != 3 || !strings.Contains(result.Unschedulable[0].Reason, "3/3 tasks in gang unschedulable") {
+		t.Fatalf("got unschedulable %v", result.Unschedulable)
+	}
+}
+
+func TestProportionByQueueWeight(t *testing.T) {
+	c := newTestCluster(t, "8", 1)
+	c.Objects = append(c.Objects,
+		generate.BuildFakeQueue("q1", 1, nil, true),
+		generate.BuildFakeQueue("q2", 3, nil, true))
+	addTestPods(t, c, "a", "q1", "", 8, "1")
+	addTestPods(t, c, "b", "q2", "", 8, "1")
+
+	result := runDefault(t, c, "")
+	if got := countByPrefix(result.Scheduled); got["a"] != 2 || got["b"] != 6 {
+		t.Fatalf("scheduled %v, want 2 pods of q1 and 6 pods of q2", got)
+	}
+}
+
+func TestReclaim(t *testing.T) {
+	c := newTestCluster(t, "8", 1)
+	c.Objects = append(c.Objects,
+		generate.BuildFakeQueue("q1", 1, nil, true),
+		generate.BuildFakeQueue("q2", 1, nil, true))
+	addTestPods(t, c, "a", "q1", "", 8, "1")
+	for _, pod := range c.PendingPods() {
+		if err := c.Bind(simulator.PodKey(pod), "n0"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	addTestPods(t, c, "b", "q2", "", 4, "1")
+
+	result := runDefault(t, c, `
+actions: "enqueue, allocate, reclaim"
+tiers:
+- plugins:
+  - name: priority
+  - name: gang
+  - name: conformance
+- plugins:
+  - name: drf
+  - name: predicates
+  - name: proportion
+  - name: nodeorder
+`)
+	if got := countByPrefix(result.Scheduled); got["b"] != 4 {
+		t.Fatalf("scheduled %v, want the 4 pods of q2", got)
+	}
+	if got := countByPrefix(result.Evicted); got["a"] != 4 {
+		t.Fatalf("evicted %v, want 4 pods of q1 down to its deserved share", got)
+	}
+}
+
+func TestParseConfiguration(t *testing.T) {
+	if _, err := ParseConfiguration([]byte(`actions: "enqueue, shuffle"`)); err == nil {
+		t.Errorf("an unknown action was accepted")
+	}
+	if _, err := ParseConfiguration([]byte("tiers:\n- plugins:\n  - name: sla\n")); err == nil {
+		t.Errorf("an unknown plugin was accepted")
+	}
+
+	// the options of volcano-scheduler.conf which are not simulated are ignored
+	conf, err := ParseConfiguration([]byte(`
+actions: "enqueue, allocate"
+tiers:
+- plugins:
+  - name: proportion
+    enabledHierarchy: true
+  - name: nodeorder
+    enableNodeOrder: true
+configurations:
+- name: enqueue
+  arguments:
+    overcommit-factor: 1.2
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"configurations", "proportion.enabledHierarchy"}; !reflect.DeepEqual(conf.Ignored, want) {
+		t.Errorf("got ignored keys %v, want %v", conf.Ignored, want)
+	}
+}